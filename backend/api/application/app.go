@@ -2,42 +2,183 @@ package application
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strconv"
-)
+	"sync"
+	"time"
 
-// Define port constant value
-const port int = 8080
+	"github.com/DanVerh/artschool-admin/backend/api/auth"
+	"github.com/DanVerh/artschool-admin/backend/api/config"
+	"github.com/DanVerh/artschool-admin/backend/api/db"
+	"github.com/DanVerh/artschool-admin/backend/api/handlers"
+	"github.com/DanVerh/artschool-admin/backend/api/notifications"
+	"github.com/DanVerh/artschool-admin/backend/api/storage"
+)
 
 // Define App struct (class)
 type App struct {
 	router http.Handler
+	db     *db.Database
+	config *config.Config
+	// Nil when notifications are off
+	reminders *notifications.Reminders
+	expiry    *handler.SubscriptionExpiry
 }
 
 // Define constructor for creating object of App class
 // Pointer, because we need to modify object fields
-func New() *App {
+func New(cfg *config.Config) (*App, error) {
 	app := &App{
-		router: loadRoutes(),
+		config: cfg,
+	}
+
+	var store *storage.Store
+	if cfg.Storage == config.MemoryStorage {
+		// In-memory storage keeps nothing between restarts and is meant for local development
+		log.Println("Using in-memory storage, data is lost on restart")
+		store = storage.NewMemoryStore()
+	} else {
+		// One database client with its connection pool is shared by all repositories
+		database, err := db.DbConnect(cfg.DbUri, cfg.DbName, cfg.DbTimeout, cfg.QueryTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create database client: %w", err)
+		}
+		app.db = database
+		store = storage.NewMongoStore(database)
+	}
+
+	authManager, err := newAuthManager(cfg, store)
+	if err != nil {
+		return nil, err
 	}
 
-	return app
+	reminders, err := notifications.NewReminders(store, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notifications: %w", err)
+	}
+	app.reminders = reminders
+	app.expiry = &handler.SubscriptionExpiry{
+		Students:      store.Students,
+		Subscriptions: store.Subscriptions,
+		Transactions:  store.Transactions,
+		Now:           time.Now,
+	}
+
+	app.router = loadRoutes(store, authManager, cfg, reminders)
+
+	return app, nil
+}
+
+// Create the session manager and the first admin account
+func newAuthManager(cfg *config.Config, store *storage.Store) (*auth.Manager, error) {
+	secret := []byte(cfg.SessionSecret)
+	if len(secret) == 0 {
+		log.Println("SESSION_SECRET is not set, using a random one; sessions end on restart")
+		secret = make([]byte, 32)
+		_, err := rand.Read(secret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate session secret: %w", err)
+		}
+	}
+
+	authManager := auth.NewManager(store.Users, store.Sessions, secret, cfg.SessionTTL)
+
+	if cfg.AdminUsername != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.DbTimeout+cfg.QueryTimeout)
+		defer cancel()
+
+		// The server still starts without the database, the account is created on the next start
+		err := authManager.EnsureUser(ctx, cfg.AdminUsername, cfg.AdminPassword, auth.RoleOwner)
+		if err != nil {
+			log.Printf("Failed to create admin user %v: %v", cfg.AdminUsername, err)
+		}
+	}
+
+	return authManager, nil
 }
 
 // Method for starting the app server
+// The server runs until ctx is cancelled, then stops accepting connections
+// and waits up to the shutdown timeout for in-flight requests to finish
 func (app *App) Start(ctx context.Context) error {
 	server := &http.Server{
-		Addr:    ":" + strconv.Itoa(port), // convert port to ASCII
-		Handler: app.router,
+		Addr:         ":" + strconv.Itoa(app.config.Port), // convert port to ASCII
+		Handler:      app.router,
+		ReadTimeout:  app.config.ReadTimeout,
+		WriteTimeout: app.config.WriteTimeout,
 	}
-	
-	fmt.Printf("Application started on localhost:%d\n", port)
 
-	err := server.ListenAndServe()
-	if err != nil {
+	fmt.Printf("Application started on localhost:%d\n", app.config.Port)
+
+	// Background jobs stop together with the server, before the connection pool is closed
+	jobsCtx, stopJobs := context.WithCancel(ctx)
+	var jobs sync.WaitGroup
+	jobs.Add(1)
+	go func() {
+		defer jobs.Done()
+		app.expiry.Start(jobsCtx)
+	}()
+	if app.reminders != nil {
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			app.reminders.Start(jobsCtx)
+		}()
+	}
+	waitJobs := func() {
+		stopJobs()
+		jobs.Wait()
+	}
+
+	// Run the server in background so shutdown can be handled here
+	serverErr := make(chan error, 1)
+	go func() {
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+
+	select {
+	case err := <-serverErr:
+		waitJobs()
+		app.closeDb()
 		return fmt.Errorf("failed to start server: %w", err)
+	case <-ctx.Done():
+	}
+
+	log.Println("Shutting down, waiting for in-flight requests")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), app.config.ShutdownTimeout)
+	defer cancel()
+
+	err := server.Shutdown(shutdownCtx)
+	// Close the connection pool only after the handlers and the background jobs are done with it
+	waitJobs()
+	app.closeDb()
+	if err != nil {
+		return fmt.Errorf("failed to shut down server gracefully: %w", err)
 	}
 
+	log.Println("Server stopped")
 	return nil
 }
+
+// Close the connection pool once the server stops
+func (app *App) closeDb() {
+	if app.db == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), app.config.ShutdownTimeout)
+	defer cancel()
+
+	if err := app.db.DbDisconnect(ctx); err != nil {
+		log.Printf("Failed to disconnect MongoDB client: %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package application
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+// Mark attendance through the API and return the updated student
+func markAttendance(t *testing.T, router http.Handler, schedule models.Schedule, student models.Student, attendance interface{}) *models.Student {
+	t.Helper()
+
+	path := "/schedule/" + schedule.Id.Hex() + "/classes/" + student.Id.Hex() + "/attendance"
+	recorder := doRequest(t, router, http.MethodPost, path, map[string]interface{}{"attendance": attendance})
+	expectStatus(t, recorder, http.StatusOK)
+	response := struct {
+		Class   models.Class    `json:"class"`
+		Student *models.Student `json:"student"`
+	}{}
+	decodeResponse(t, recorder, &response)
+	return response.Student
+}
+
+// Check subscription and visit dates of the student; empty dates and zero subscription mean null
+func expectAccounting(t *testing.T, student *models.Student, subscription int, startDate string, lastDate string) {
+	t.Helper()
+
+	formatDate := func(date *time.Time) string {
+		if date == nil {
+			return ""
+		}
+		return date.UTC().Format(time.DateOnly)
+	}
+	gotSubscription := 0
+	if student.Subscription != nil {
+		gotSubscription = *student.Subscription
+	}
+	if gotSubscription != subscription || formatDate(student.StartDate) != startDate || formatDate(student.LastDate) != lastDate {
+		t.Fatalf("expected subscription %v, startDate %q, lastDate %q, got %v, %q, %q",
+			subscription, startDate, lastDate, gotSubscription, formatDate(student.StartDate), formatDate(student.LastDate))
+	}
+}
+
+func TestAttendanceAccounting(t *testing.T) {
+	router := newTestRouter(t)
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+	sellSubscription(t, router, alice, 2)
+	first := createSchedule(t, router, "2024-03-01T00:00:00Z", class(alice.Id.Hex(), "14:00", "both"))
+	second := createSchedule(t, router, "2024-03-05T00:00:00Z", class(alice.Id.Hex(), "14:00", "both"))
+	third := createSchedule(t, router, "2024-03-08T00:00:00Z", class(alice.Id.Hex(), "14:00", "both"))
+
+	// Visits use the subscription and move the dates
+	expectAccounting(t, markAttendance(t, router, first, alice, true), 1, "2024-03-01", "2024-03-01")
+	expectAccounting(t, markAttendance(t, router, first, alice, true), 1, "2024-03-01", "2024-03-01")
+	expectAccounting(t, markAttendance(t, router, second, alice, true), 0, "2024-03-01", "2024-03-05")
+
+	// The subscription is used up; nothing changes
+	recorder := doRequest(t, router, http.MethodPost, "/schedule/"+third.Id.Hex()+"/classes/"+alice.Id.Hex()+"/attendance", map[string]bool{"attendance": true})
+	expectError(t, recorder, errorHandling.NoClassesLeft)
+	if attendance := getSchedule(t, router, third.Id.Hex()).Classes[0].Attendence; attendance != nil {
+		t.Fatalf("expected attendance to stay null, got %v", *attendance)
+	}
+
+	// Absence does not use the subscription
+	expectAccounting(t, markAttendance(t, router, third, alice, false), 0, "2024-03-01", "2024-03-05")
+
+	// Un-marking reverses the visits
+	expectAccounting(t, markAttendance(t, router, second, alice, nil), 1, "2024-03-01", "2024-03-01")
+	expectAccounting(t, markAttendance(t, router, first, alice, false), 2, "", "")
+}
+
+// Mark one class through the API by its id and return the updated student
+func markClass(t *testing.T, router http.Handler, schedule models.Schedule, class models.Class, attendance interface{}) *models.Student {
+	t.Helper()
+
+	path := "/schedule/" + schedule.Id.Hex() + "/classes/" + class.Id.Hex() + "/attendance"
+	recorder := doRequest(t, router, http.MethodPost, path, map[string]interface{}{"attendance": attendance})
+	expectStatus(t, recorder, http.StatusOK)
+	response := struct {
+		Student *models.Student `json:"student"`
+	}{}
+	decodeResponse(t, recorder, &response)
+	return response.Student
+}
+
+func TestAttendanceDatesOutOfOrder(t *testing.T) {
+	router := newTestRouter(t)
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+	sellSubscription(t, router, alice, 8)
+	first := createSchedule(t, router, "2024-03-01T00:00:00Z", class(alice.Id.Hex(), "14:00", "both"))
+	second := createSchedule(t, router, "2024-03-05T00:00:00Z", class(alice.Id.Hex(), "10:00", "both"), class(alice.Id.Hex(), "14:00", "both"))
+
+	// Two visits on one day; un-marking one keeps the dates of the other
+	markClass(t, router, second, second.Classes[0], true)
+	expectAccounting(t, markClass(t, router, second, second.Classes[1], true), 6, "2024-03-05", "2024-03-05")
+	expectAccounting(t, markClass(t, router, second, second.Classes[0], nil), 7, "2024-03-05", "2024-03-05")
+
+	// A visit marked later for an earlier day moves the start back
+	expectAccounting(t, markAttendance(t, router, first, alice, true), 6, "2024-03-01", "2024-03-05")
+	expectAccounting(t, markClass(t, router, second, second.Classes[1], nil), 7, "2024-03-01", "2024-03-01")
+}
+
+func TestAttendanceErrors(t *testing.T) {
+	router := newTestRouter(t)
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+	bob := createStudent(t, router, "Bob Smith", "+123456789013")
+	schedule := createSchedule(t, router, "2024-03-01T00:00:00Z", class(alice.Id.Hex(), "14:00", "both"))
+	path := "/schedule/" + schedule.Id.Hex() + "/classes/"
+
+	tests := []struct {
+		name string
+		path string
+		body interface{}
+		code errorHandling.Code
+	}{
+		{"invalid JSON", path + alice.Id.Hex() + "/attendance", "{", errorHandling.InvalidJSON},
+		{"missing attendance", path + alice.Id.Hex() + "/attendance", map[string]string{}, errorHandling.ValidationFailed},
+		{"attendance not a boolean", path + alice.Id.Hex() + "/attendance", map[string]string{"attendance": "yes"}, errorHandling.ValidationFailed},
+		{"bad student id", path + "bad/attendance", map[string]bool{"attendance": true}, errorHandling.InvalidId},
+		{"student without class", path + bob.Id.Hex() + "/attendance", map[string]bool{"attendance": true}, errorHandling.NotFound},
+		{"missing schedule", "/schedule/000000000000000000000000/classes/" + alice.Id.Hex() + "/attendance", map[string]bool{"attendance": true}, errorHandling.NotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectError(t, doRequest(t, router, http.MethodPost, test.path, test.body), test.code)
+		})
+	}
+}
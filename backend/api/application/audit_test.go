@@ -0,0 +1,154 @@
+package application
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/DanVerh/artschool-admin/backend/api/auth"
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/handlers"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+func listAudit(t *testing.T, router http.Handler, query string) []models.AuditEntry {
+	t.Helper()
+
+	recorder := doRequest(t, router, http.MethodGet, "/audit"+query, nil)
+	expectStatus(t, recorder, http.StatusOK)
+	entries := []models.AuditEntry{}
+	decodeResponse(t, recorder, &entries)
+	return entries
+}
+
+func TestAuditOfStudentMutations(t *testing.T) {
+	router := newTestRouter(t)
+
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+	studentPath := "/students/" + alice.Id.Hex()
+	expectStatus(t, doRequest(t, router, http.MethodPut, studentPath, map[string]string{"comments": "Beginner"}), http.StatusOK)
+	expectStatus(t, doRequest(t, router, http.MethodPut, studentPath, map[string]string{"comments": "Prefers oil"}), http.StatusOK)
+	expectStatus(t, doRequest(t, router, http.MethodDelete, studentPath, nil), http.StatusOK)
+	expectStatus(t, doRequest(t, router, http.MethodPost, studentPath+"/purge", nil), http.StatusNoContent)
+
+	entries := listAudit(t, router, "?entityId="+alice.Id.Hex())
+	if len(entries) != 5 {
+		t.Fatalf("expected 5 audit entries, got %+v", entries)
+	}
+	// Newest first; deletion archives the student, purge deletes it
+	actions := []string{models.AuditDelete, models.AuditUpdate, models.AuditUpdate, models.AuditUpdate, models.AuditCreate}
+	for index, entry := range entries {
+		if entry.Action != actions[index] || entry.Entity != "student" || entry.Actor != "admin" {
+			t.Fatalf("unexpected audit entry %v: %+v", index, entry)
+		}
+	}
+
+	if change := entries[1].Changes; len(change) != 1 || change[0].Field != "deletedAt" || change[0].Before != nil {
+		t.Fatalf("expected deletedAt change, got %+v", change)
+	}
+	change := entries[2].Changes
+	if len(change) != 1 || change[0].Field != "comments" || change[0].Before != "Beginner" || change[0].After != "Prefers oil" {
+		t.Fatalf("expected comments change, got %+v", change)
+	}
+	if len(entries[4].Changes) != 2 || entries[4].Changes[0].Before != nil {
+		t.Fatalf("expected created fullname and phone, got %+v", entries[3].Changes)
+	}
+}
+
+func TestAuditOfScheduleMutations(t *testing.T) {
+	router := newTestRouter(t)
+
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+	schedule := createSchedule(t, router, "2024-03-01T00:00:00Z", class(alice.Id.Hex(), "14:00", "both"))
+	expectStatus(t, doRequest(t, router, http.MethodPut, "/schedule/"+schedule.Id.Hex(), class(alice.Id.Hex(), "15:00", "both")), http.StatusOK)
+
+	entries := listAudit(t, router, "?entity=schedule&limit=1")
+	if len(entries) != 1 || entries[0].Action != models.AuditUpdate || entries[0].EntityId != schedule.Id {
+		t.Fatalf("expected the schedule update, got %+v", entries)
+	}
+	if len(entries[0].Changes) != 1 || entries[0].Changes[0].Field != "classes" {
+		t.Fatalf("expected classes change, got %+v", entries[0].Changes)
+	}
+}
+
+func TestAuditFilters(t *testing.T) {
+	_, router := newTestServer(t)
+	admin := withToken(router, login(t, router, "admin", testPassword))
+	receptionist := newUserRouter(t, admin, router, "reception", auth.RoleReceptionist)
+
+	createStudent(t, admin, "Alice Johnson", "+123456789012")
+	createStudent(t, receptionist, "Bob Smith", "+123456789013")
+
+	entries := listAudit(t, admin, "?actor=reception")
+	if len(entries) != 1 || entries[0].Actor != "reception" {
+		t.Fatalf("expected one entry of reception, got %+v", entries)
+	}
+	if entries := listAudit(t, admin, "?from=2000-01-01&to=2000-01-02"); len(entries) != 0 {
+		t.Fatalf("expected no entries in 2000, got %+v", entries)
+	}
+
+	recorder := doRequest(t, admin, http.MethodGet, "/audit?entityId=1&from=yesterday&limit=0", nil)
+	response := expectError(t, recorder, errorHandling.ValidationFailed)
+	if len(response.Details) != 3 {
+		t.Fatalf("expected entityId, from and limit errors, got %+v", response.Details)
+	}
+
+	// Only the owner reads the audit log
+	expectForbidden(t, receptionist, http.MethodGet, "/audit", nil, auth.AuditRead)
+}
+
+// The audit schema is set by the last migration that creates or changes the collection;
+// the in-memory store has no validator, so an entity missing there only fails on MongoDB
+func TestAuditSchemaAllowsEveryEntity(t *testing.T) {
+	dir := filepath.Join("..", "..", "migration", "migrations")
+	files, err := filepath.Glob(filepath.Join(dir, "*.up.json"))
+	if err != nil {
+		t.Fatalf("failed to list migrations: %v", err)
+	}
+	version := func(path string) int {
+		number, _ := strconv.Atoi(strings.SplitN(filepath.Base(path), "_", 2)[0])
+		return number
+	}
+	sort.Slice(files, func(i, j int) bool { return version(files[i]) < version(files[j]) })
+
+	var entities []string
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("failed to read %v: %v", file, err)
+		}
+		var commands []struct {
+			Create    string `json:"create"`
+			CollMod   string `json:"collMod"`
+			Validator struct {
+				Schema struct {
+					Properties struct {
+						Entity struct {
+							Enum []string `json:"enum"`
+						} `json:"entity"`
+					} `json:"properties"`
+				} `json:"$jsonSchema"`
+			} `json:"validator"`
+		}
+		if err := json.Unmarshal(content, &commands); err != nil {
+			t.Fatalf("failed to parse %v: %v", file, err)
+		}
+		for _, command := range commands {
+			if command.Create == "audit" || command.CollMod == "audit" {
+				entities = command.Validator.Schema.Properties.Entity.Enum
+			}
+		}
+	}
+
+	for _, entity := range handler.AuditEntities {
+		if !slices.Contains(entities, entity) {
+			t.Fatalf("audit entity %q is missing from the audit schema %v, add it with a migration", entity, entities)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package application
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/DanVerh/artschool-admin/backend/api/auth"
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+)
+
+func TestProtectedRoutesRequireLogin(t *testing.T) {
+	_, router := newTestServer(t)
+
+	for _, path := range []string{"/students", "/schedule"} {
+		recorder := doRequest(t, router, http.MethodGet, path, nil)
+		expectError(t, recorder, errorHandling.Unauthorized)
+	}
+
+	// Health is open for load balancers
+	recorder := doRequest(t, router, http.MethodGet, "/health", nil)
+	expectStatus(t, recorder, http.StatusOK)
+}
+
+func TestLoginErrors(t *testing.T) {
+	_, router := newTestServer(t)
+
+	recorder := doRequest(t, router, http.MethodPost, "/auth/login", map[string]string{"username": "admin", "password": "wrong-password"})
+	expectError(t, recorder, errorHandling.InvalidCredentials)
+
+	recorder = doRequest(t, router, http.MethodPost, "/auth/login", map[string]string{"username": "nobody", "password": testPassword})
+	expectError(t, recorder, errorHandling.InvalidCredentials)
+
+	recorder = doRequest(t, router, http.MethodPost, "/auth/login", map[string]string{"username": "admin"})
+	expectError(t, recorder, errorHandling.ValidationFailed)
+}
+
+func TestLoginWithCookieAndLogout(t *testing.T) {
+	_, router := newTestServer(t)
+
+	recorder := doRequest(t, router, http.MethodPost, "/auth/login", map[string]string{"username": "admin", "password": testPassword})
+	expectStatus(t, recorder, http.StatusOK)
+	cookies := recorder.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != auth.CookieName || !cookies[0].HttpOnly {
+		t.Fatalf("expected http-only session cookie, got %+v", cookies)
+	}
+	session := cookies[0]
+
+	// Cookie authenticates requests
+	withCookie := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.AddCookie(session)
+		router.ServeHTTP(w, r)
+	})
+	recorder = doRequest(t, withCookie, http.MethodGet, "/students", nil)
+	expectStatus(t, recorder, http.StatusOK)
+
+	// Logout ends the session
+	recorder = doRequest(t, withCookie, http.MethodPost, "/auth/logout", nil)
+	expectStatus(t, recorder, http.StatusNoContent)
+	recorder = doRequest(t, withCookie, http.MethodGet, "/students", nil)
+	expectError(t, recorder, errorHandling.Unauthorized)
+}
+
+func TestForgedToken(t *testing.T) {
+	_, router := newTestServer(t)
+	token := login(t, router, "admin", testPassword)
+
+	// Same session id with another signature
+	forged := token + "A"
+	recorder := doRequest(t, withToken(router, forged), http.MethodGet, "/students", nil)
+	expectError(t, recorder, errorHandling.Unauthorized)
+}
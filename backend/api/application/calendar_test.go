@@ -0,0 +1,183 @@
+package application
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DanVerh/artschool-admin/backend/api/auth"
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/handlers"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+func getCalendar(t *testing.T, router http.Handler, path string) string {
+	t.Helper()
+
+	recorder := doRequest(t, router, http.MethodGet, path, nil)
+	expectStatus(t, recorder, http.StatusOK)
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/calendar") {
+		t.Fatalf("expected text/calendar, got %v", contentType)
+	}
+	return recorder.Body.String()
+}
+
+// Lines of the event with the UID, empty when the feed does not have it
+func calendarEvent(feed string, uid string) []string {
+	var event []string
+	for _, block := range strings.Split(feed, "BEGIN:VEVENT\r\n")[1:] {
+		if strings.Contains(block, "UID:"+uid+"@artschool-admin\r\n") {
+			event = append(event, strings.Split(block, "\r\n")...)
+		}
+	}
+	return event
+}
+
+func TestCalendarFeeds(t *testing.T) {
+	router := newTestRouter(t)
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+	bob := createStudent(t, router, "Bob Smith", "+123456789013")
+	anna := createTeacher(t, router, "Anna Brown", "+380501234567", "drawing", "painting")
+	room := createRoom(t, router, "Room A, first floor", 4)
+	day := time.Now().UTC().AddDate(0, 0, 3)
+	schedule := createSchedule(t, router, day.Format("2006-01-02")+"T00:00:00Z", roomClass(alice.Id.Hex(), "10:00", room, anna), class(bob.Id.Hex(), "11:00", "painting"))
+	aliceClass, bobClass := schedule.Classes[0], schedule.Classes[1]
+	old := createSchedule(t, router, "2024-03-01T00:00:00Z", class(alice.Id.Hex(), "10:00", "drawing"))
+
+	feed := getCalendar(t, router, "/schedule.ics")
+	if !strings.HasPrefix(feed, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n") || !strings.HasSuffix(feed, "END:VCALENDAR\r\n") {
+		t.Fatalf("unexpected calendar %q", feed)
+	}
+	event := calendarEvent(feed, aliceClass.Id.Hex())
+	for _, line := range []string{
+		"DTSTART:" + day.Format("20060102") + "T100000",
+		"DTEND:" + day.Format("20060102") + "T110000",
+		"SUMMARY:Drawing class: Alice Johnson",
+		`LOCATION:Room A\, first floor`,
+		"DESCRIPTION:Teacher: Anna Brown",
+		"STATUS:CONFIRMED",
+	} {
+		if !slices.Contains(event, line) {
+			t.Fatalf("expected %q in the event, got %q", line, event)
+		}
+	}
+	if calendarEvent(feed, old.Classes[0].Id.Hex()) != nil {
+		t.Fatalf("expected classes older than 30 days to be left out")
+	}
+	if calendarEvent(getCalendar(t, router, "/schedule.ics?from=2024-03-01"), old.Classes[0].Id.Hex()) == nil {
+		t.Fatalf("expected old class with from")
+	}
+
+	// Changed classes keep their UID, removed ones become cancelled events
+	expectStatus(t, doRequest(t, router, http.MethodPatch, "/schedule/"+schedule.Id.Hex()+"/classes/"+aliceClass.Id.Hex(), map[string]interface{}{"time": "12:00"}), http.StatusOK)
+	expectStatus(t, doRequest(t, router, http.MethodDelete, "/schedule/"+schedule.Id.Hex()+"/classes/"+bobClass.Id.Hex(), nil), http.StatusNoContent)
+	feed = getCalendar(t, router, "/schedule.ics")
+	if strings.Count(feed, "UID:"+aliceClass.Id.Hex()) != 1 || !slices.Contains(calendarEvent(feed, aliceClass.Id.Hex()), "DTSTART:"+day.Format("20060102")+"T120000") {
+		t.Fatalf("expected one moved event of Alice, got %q", feed)
+	}
+	if event := calendarEvent(feed, bobClass.Id.Hex()); !slices.Contains(event, "STATUS:CANCELLED") || !slices.Contains(event, "SEQUENCE:1") {
+		t.Fatalf("expected cancelled event of Bob, got %q", event)
+	}
+
+	// Teacher and student feeds
+	feed = getCalendar(t, router, "/teachers/"+anna.Id.Hex()+"/schedule.ics")
+	if strings.Count(feed, "BEGIN:VEVENT") != 1 || calendarEvent(feed, aliceClass.Id.Hex()) == nil || !strings.Contains(feed, "X-WR-CALNAME:Classes of Anna Brown") {
+		t.Fatalf("expected only the class of Anna, got %q", feed)
+	}
+	feed = getCalendar(t, router, "/students/"+bob.Id.Hex()+"/schedule.ics")
+	if strings.Count(feed, "BEGIN:VEVENT") != 1 || !slices.Contains(calendarEvent(feed, bobClass.Id.Hex()), "STATUS:CANCELLED") {
+		t.Fatalf("expected only the cancelled class of Bob, got %q", feed)
+	}
+
+	expectError(t, doRequest(t, router, http.MethodGet, "/schedule.ics?from=soon", nil), errorHandling.ValidationFailed)
+	expectError(t, doRequest(t, router, http.MethodGet, "/teachers/000000000000000000000000/schedule.ics", nil), errorHandling.NotFound)
+}
+
+func createFeed(t *testing.T, router http.Handler, body map[string]string) models.CalendarFeed {
+	t.Helper()
+
+	recorder := doRequest(t, router, http.MethodPost, "/calendar-feeds", body)
+	expectStatus(t, recorder, http.StatusCreated)
+	var feed models.CalendarFeed
+	decodeResponse(t, recorder, &feed)
+	return feed
+}
+
+func TestCalendarFeedLinks(t *testing.T) {
+	_, router := newTestServer(t)
+	admin := withToken(router, login(t, router, "admin", testPassword))
+	alice := createStudent(t, admin, "Alice Johnson", "+123456789012")
+	anna := createTeacher(t, admin, "Anna Brown", "+380501234567", "drawing")
+	ben := createTeacher(t, admin, "Ben Grey", "+380501234568", "drawing")
+	createSchedule(t, admin, time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)+"T00:00:00Z", teacherClass(alice.Id.Hex(), "10:00", "drawing", anna))
+
+	// Links open the feed without a session
+	school := createFeed(t, admin, map[string]string{"scope": "school"})
+	if school.Token == "" || school.Path != "/schedule.ics?token="+school.Token {
+		t.Fatalf("unexpected school feed %+v", school)
+	}
+	if feed := getCalendar(t, router, school.Path); strings.Count(feed, "BEGIN:VEVENT") != 1 {
+		t.Fatalf("expected the class in the school feed, got %q", feed)
+	}
+	annaFeed := createFeed(t, admin, map[string]string{"scope": "teacher", "entityId": anna.Id.Hex()})
+	getCalendar(t, router, annaFeed.Path)
+	studentFeed := createFeed(t, admin, map[string]string{"scope": "student", "entityId": alice.Id.Hex()})
+	getCalendar(t, router, studentFeed.Path)
+
+	// A link opens its own feed only
+	expectError(t, doRequest(t, router, http.MethodGet, "/schedule.ics?token="+annaFeed.Token, nil), errorHandling.Forbidden)
+	expectError(t, doRequest(t, router, http.MethodGet, "/teachers/"+ben.Id.Hex()+"/schedule.ics?token="+annaFeed.Token, nil), errorHandling.Forbidden)
+	expectError(t, doRequest(t, router, http.MethodGet, "/students?token="+school.Token, nil), errorHandling.Unauthorized)
+	expectError(t, doRequest(t, router, http.MethodGet, "/schedule.ics?token=guess", nil), errorHandling.Unauthorized)
+	expectError(t, doRequest(t, router, http.MethodGet, "/schedule.ics", nil), errorHandling.Unauthorized)
+
+	// Only the owner sees and revokes links
+	var feeds []models.CalendarFeed
+	recorder := doRequest(t, admin, http.MethodGet, "/calendar-feeds", nil)
+	expectStatus(t, recorder, http.StatusOK)
+	decodeResponse(t, recorder, &feeds)
+	if len(feeds) != 3 || feeds[0].Token != "" {
+		t.Fatalf("expected every feed without its token, got %+v", feeds)
+	}
+	expectStatus(t, doRequest(t, admin, http.MethodDelete, "/calendar-feeds/"+annaFeed.Id.Hex(), nil), http.StatusNoContent)
+	expectStatus(t, doRequest(t, admin, http.MethodDelete, "/calendar-feeds/"+studentFeed.Id.Hex(), nil), http.StatusNoContent)
+	expectError(t, doRequest(t, router, http.MethodGet, annaFeed.Path, nil), errorHandling.Unauthorized)
+	expectError(t, doRequest(t, router, http.MethodGet, studentFeed.Path, nil), errorHandling.Unauthorized)
+	getCalendar(t, router, school.Path)
+
+	expectError(t, doRequest(t, admin, http.MethodPost, "/calendar-feeds", map[string]string{"scope": "room"}), errorHandling.ValidationFailed)
+	expectError(t, doRequest(t, admin, http.MethodPost, "/calendar-feeds", map[string]string{"scope": "student", "entityId": "000000000000000000000000"}), errorHandling.ValidationFailed)
+	expectError(t, doRequest(t, admin, http.MethodPost, "/calendar-feeds", map[string]string{"scope": "school", "entityId": alice.Id.Hex()}), errorHandling.ValidationFailed)
+}
+
+// Teachers and receptionists read the feeds but can not create, list or revoke links
+func TestCalendarFeedPermissions(t *testing.T) {
+	_, router := newTestServer(t)
+	admin := withToken(router, login(t, router, "admin", testPassword))
+	school := createFeed(t, admin, map[string]string{"scope": "school"})
+
+	for _, role := range []string{auth.RoleTeacher, auth.RoleReceptionist} {
+		user := newUserRouter(t, admin, router, role, role)
+		expectStatus(t, doRequest(t, user, http.MethodGet, "/schedule.ics", nil), http.StatusOK)
+		expectForbidden(t, user, http.MethodPost, "/calendar-feeds", map[string]string{"scope": "school"}, auth.FeedsManage)
+		expectForbidden(t, user, http.MethodGet, "/calendar-feeds", nil, auth.FeedsManage)
+		expectForbidden(t, user, http.MethodDelete, "/calendar-feeds/"+school.Id.Hex(), nil, auth.FeedsManage)
+	}
+	getCalendar(t, router, school.Path)
+}
+
+// The request log sees the feed link without its token, the handlers with it
+func TestCalendarFeedTokenRedacted(t *testing.T) {
+	var requestURI, token string
+	redacted := handler.RedactFeedToken(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestURI, token = r.RequestURI, r.URL.Query().Get("token")
+	}))
+
+	redacted.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/schedule.ics?token=secret&from=2024-03-01", nil))
+	if strings.Contains(requestURI, "secret") || !strings.Contains(requestURI, "token=redacted") || token != "secret" {
+		t.Fatalf("expected the token redacted from %q only, handler got %q", requestURI, token)
+	}
+}
@@ -0,0 +1,165 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/config"
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+	"github.com/DanVerh/artschool-admin/backend/api/storage"
+)
+
+func addClass(t *testing.T, router http.Handler, schedule models.Schedule, body map[string]interface{}) models.Class {
+	t.Helper()
+
+	recorder := doRequest(t, router, http.MethodPost, "/schedule/"+schedule.Id.Hex()+"/classes", body)
+	expectStatus(t, recorder, http.StatusCreated)
+	var class models.Class
+	decodeResponse(t, recorder, &class)
+	return class
+}
+
+func TestScheduleClasses(t *testing.T) {
+	router := newTestRouter(t)
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+	sellSubscription(t, router, alice, 4)
+	anna := createTeacher(t, router, "Anna Brown", "+380501234567", "drawing", "painting")
+	schedule := createSchedule(t, router, "2024-03-01T00:00:00Z", class(alice.Id.Hex(), "10:00", "drawing"))
+	morning := schedule.Classes[0]
+	if morning.Id.IsZero() {
+		t.Fatalf("expected created class to have id")
+	}
+
+	// A second class of the same student on the same day
+	evening := addClass(t, router, schedule, teacherClass(alice.Id.Hex(), "18:00", "painting", anna))
+	if evening.Id.IsZero() || evening.Id == morning.Id || evening.TeacherId == nil {
+		t.Fatalf("unexpected added class %+v", evening)
+	}
+
+	recorder := doRequest(t, router, http.MethodPatch, "/schedule/"+schedule.Id.Hex()+"/classes/"+evening.Id.Hex(), map[string]interface{}{"time": "17:00", "teacherId": nil})
+	expectStatus(t, recorder, http.StatusOK)
+	var patched models.Class
+	decodeResponse(t, recorder, &patched)
+	if patched.Id != evening.Id || patched.Time != "17:00" || patched.Type != "painting" || patched.TeacherId != nil {
+		t.Fatalf("unexpected patched class %+v", patched)
+	}
+
+	// Attendance needs the class id now that Alice has two classes
+	recorder = doRequest(t, router, http.MethodPost, "/schedule/"+schedule.Id.Hex()+"/classes/"+alice.Id.Hex()+"/attendance", map[string]interface{}{"attendance": true})
+	expectError(t, recorder, errorHandling.ValidationFailed)
+	recorder = doRequest(t, router, http.MethodPost, "/schedule/"+schedule.Id.Hex()+"/classes/"+morning.Id.Hex()+"/attendance", map[string]interface{}{"attendance": true})
+	expectStatus(t, recorder, http.StatusOK)
+	recorder = doRequest(t, router, http.MethodDelete, "/schedule/"+schedule.Id.Hex()+"/classes/"+morning.Id.Hex(), nil)
+	expectError(t, recorder, errorHandling.ValidationFailed)
+
+	recorder = doRequest(t, router, http.MethodDelete, "/schedule/"+schedule.Id.Hex()+"/classes/"+evening.Id.Hex(), nil)
+	expectStatus(t, recorder, http.StatusNoContent)
+	found := getSchedule(t, router, schedule.Id.Hex())
+	if len(found.Classes) != 1 || found.Classes[0].Id != morning.Id || !isTrue(found.Classes[0].Attendence) {
+		t.Fatalf("expected only the attended morning class, got %+v", found.Classes)
+	}
+	if entries := listAudit(t, router, "?entity=schedule&entityId="+schedule.Id.Hex()); len(entries) != 5 {
+		t.Fatalf("expected 5 audit entries of the schedule, got %v", len(entries))
+	}
+}
+
+func isTrue(value *bool) bool {
+	return value != nil && *value
+}
+
+func TestScheduleClassesErrors(t *testing.T) {
+	router := newTestRouter(t)
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+	schedule := createSchedule(t, router, "2024-03-01T00:00:00Z", class(alice.Id.Hex(), "10:00", "drawing"))
+	classPath := "/schedule/" + schedule.Id.Hex() + "/classes/" + schedule.Classes[0].Id.Hex()
+	missingId := "000000000000000000000000"
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   interface{}
+		code   errorHandling.Code
+	}{
+		{"add to missing schedule", http.MethodPost, "/schedule/" + missingId + "/classes", class(alice.Id.Hex(), "12:00", "drawing"), errorHandling.NotFound},
+		{"add with attendance", http.MethodPost, "/schedule/" + schedule.Id.Hex() + "/classes", map[string]interface{}{"studentId": alice.Id.Hex(), "time": "12:00", "type": "drawing", "attendance": true}, errorHandling.ValidationFailed},
+		{"add at the same time", http.MethodPost, "/schedule/" + schedule.Id.Hex() + "/classes", class(alice.Id.Hex(), "10:00", "painting"), errorHandling.ScheduleConflict},
+		{"patch student", http.MethodPatch, classPath, map[string]string{"studentId": missingId}, errorHandling.ValidationFailed},
+		{"patch invalid time", http.MethodPatch, classPath, map[string]string{"time": "22:00"}, errorHandling.ValidationFailed},
+		{"patch empty body", http.MethodPatch, classPath, map[string]string{}, errorHandling.ValidationFailed},
+		{"patch missing class", http.MethodPatch, "/schedule/" + schedule.Id.Hex() + "/classes/" + missingId, map[string]string{"time": "12:00"}, errorHandling.NotFound},
+		{"delete with bad ObjectId", http.MethodDelete, "/schedule/" + schedule.Id.Hex() + "/classes/abc", nil, errorHandling.InvalidId},
+		{"delete missing class", http.MethodDelete, "/schedule/" + schedule.Id.Hex() + "/classes/" + missingId, nil, errorHandling.NotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectError(t, doRequest(t, router, test.method, test.path, test.body), test.code)
+		})
+	}
+}
+
+// A class read before its attendance was marked must not bring the old attendance back or lose the visit
+// Schedule repository running a write of another request right after a read, so the reader holds a stale copy
+type racingScheduleRepository struct {
+	storage.ScheduleRepository
+	afterGet func()
+}
+
+func (repo *racingScheduleRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Schedule, error) {
+	schedule, err := repo.ScheduleRepository.Get(ctx, id)
+	if afterGet := repo.afterGet; afterGet != nil {
+		repo.afterGet = nil
+		afterGet()
+	}
+	return schedule, err
+}
+
+// PUT saves only its class, attendance marked meanwhile stays
+func TestScheduleUpdateKeepsConcurrentAttendance(t *testing.T) {
+	store := storage.NewMemoryStore()
+	racing := &racingScheduleRepository{ScheduleRepository: store.Schedules}
+	store.Schedules = racing
+	_, server := newStoreServer(t, store, config.Default())
+	router := withToken(server, login(t, server, "admin", testPassword))
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+	bob := createStudent(t, router, "Bob Smith", "+123456789013")
+	sellSubscription(t, router, bob, 4)
+	schedule := createSchedule(t, router, "2024-03-01T00:00:00Z", class(alice.Id.Hex(), "10:00", "drawing"), class(bob.Id.Hex(), "11:00", "drawing"))
+
+	racing.afterGet = func() { markAttendance(t, router, schedule, bob, true) }
+	expectStatus(t, doRequest(t, router, http.MethodPut, "/schedule/"+schedule.Id.Hex(), class(alice.Id.Hex(), "12:00", "painting")), http.StatusOK)
+
+	found := getSchedule(t, router, schedule.Id.Hex())
+	if len(found.Classes) != 2 || found.Classes[0].Time != "12:00" || found.Classes[0].Type != "painting" || !isTrue(found.Classes[1].Attendence) {
+		t.Fatalf("expected the changed class and the attendance marked meanwhile, got %+v", found.Classes)
+	}
+}
+
+func TestScheduleClassChangesKeepAttendance(t *testing.T) {
+	store, server := newTestServer(t)
+	router := withToken(server, login(t, server, "admin", testPassword))
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+	sellSubscription(t, router, alice, 4)
+	schedule := createSchedule(t, router, "2024-03-01T00:00:00Z", class(alice.Id.Hex(), "10:00", "drawing"))
+	stale := schedule.Classes[0]
+	markAttendance(t, router, schedule, alice, true)
+
+	stale.Time = "11:00"
+	if err := store.Schedules.UpdateClass(context.Background(), schedule.Id, &stale, []string{"time"}); err != nil {
+		t.Fatalf("failed to update class: %v", err)
+	}
+	found := getSchedule(t, router, schedule.Id.Hex())
+	if found.Classes[0].Time != "11:00" || !isTrue(found.Classes[0].Attendence) || found.Classes[0].SubscriptionId == nil {
+		t.Fatalf("expected the moved class to stay attended, got %+v", found.Classes[0])
+	}
+
+	err := store.Schedules.RemoveClass(context.Background(), schedule.Id, stale.Id)
+	if !errors.Is(err, storage.ErrAttended) {
+		t.Fatalf("expected attended class to stay, got %v", err)
+	}
+}
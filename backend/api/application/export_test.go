@@ -0,0 +1,135 @@
+package application
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DanVerh/artschool-admin/backend/api/config"
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+	"github.com/DanVerh/artschool-admin/backend/api/storage"
+)
+
+// Download a CSV export and return its records without the byte order mark
+func getCSV(t *testing.T, router http.Handler, path string) [][]string {
+	t.Helper()
+
+	recorder := doRequest(t, router, http.MethodGet, path, nil)
+	expectStatus(t, recorder, http.StatusOK)
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "text/csv; charset=utf-8" {
+		t.Fatalf("expected CSV, got %v", contentType)
+	}
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(recorder.Body.String(), "\xef\xbb\xbf"))).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read CSV %q: %v", recorder.Body.String(), err)
+	}
+	return records
+}
+
+func TestExports(t *testing.T) {
+	router := newTestRouter(t)
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+	bob := createStudent(t, router, "=Bob Smith", "+123456789013")
+	expectStatus(t, doRequest(t, router, http.MethodPut, "/students/"+alice.Id.Hex(), map[string]string{"comments": "Beginner, mornings"}), http.StatusOK)
+	anna := createTeacher(t, router, "Anna Brown", "+380501234567", "drawing", "painting")
+	room := createRoom(t, router, "Room A", 4)
+	first := createSchedule(t, router, "2024-03-01T00:00:00Z", roomClass(alice.Id.Hex(), "10:00", room, anna), class(bob.Id.Hex(), "11:00", "painting"))
+	createSchedule(t, router, "2024-03-08T00:00:00Z", class(alice.Id.Hex(), "10:00", "drawing"))
+	sellSubscription(t, router, alice, 4)
+	markAttendance(t, router, first, alice, true)
+
+	// Students with the filters of the list; text that looks like a formula is escaped
+	records := getCSV(t, router, "/students/export?sort=-fullname")
+	if len(records) != 3 || !slices.Equal(records[0], []string{"id", "fullname", "phone", "subscription", "startDate", "lastDate", "comments", "deletedAt"}) {
+		t.Fatalf("unexpected students export %q", records)
+	}
+	if records[1][1] != "Alice Johnson" || records[1][3] != "3" || records[1][4] != "2024-03-01" || records[1][6] != "Beginner, mornings" || records[2][1] != "'=Bob Smith" || records[2][2] != "+123456789013" {
+		t.Fatalf("unexpected students %q", records[1:])
+	}
+	if records := getCSV(t, router, "/students/export?subscription=null"); len(records) != 2 || records[1][0] != bob.Id.Hex() {
+		t.Fatalf("expected only Bob, got %q", records)
+	}
+
+	// One row per class with the names resolved
+	records = getCSV(t, router, "/schedule/export?from=2024-03-01&to=2024-03-07")
+	expected := [][]string{
+		{"date", "time", "type", "studentId", "student", "teacher", "room", "attendance", "classId", "scheduleId"},
+		{"2024-03-01", "10:00", "drawing", alice.Id.Hex(), "Alice Johnson", "Anna Brown", "Room A", "present", first.Classes[0].Id.Hex(), first.Id.Hex()},
+		{"2024-03-01", "11:00", "painting", bob.Id.Hex(), "'=Bob Smith", "", "", "", first.Classes[1].Id.Hex(), first.Id.Hex()},
+	}
+	if !slices.EqualFunc(records, expected, slices.Equal) {
+		t.Fatalf("expected %q, got %q", expected, records)
+	}
+	if records := getCSV(t, router, "/schedule/export?teacherId="+anna.Id.Hex()); len(records) != 2 || records[1][3] != alice.Id.Hex() {
+		t.Fatalf("expected only the class of Anna, got %q", records)
+	}
+
+	// Only marked classes, with the pack used
+	records = getCSV(t, router, "/schedule/attendance/export")
+	if len(records) != 2 || records[1][6] != "present" || records[1][7] == "" {
+		t.Fatalf("unexpected attendance export %q", records)
+	}
+
+	// An XLSX export can be imported back without changes
+	recorder := doRequest(t, router, http.MethodGet, "/students/export?format=xlsx", nil)
+	expectStatus(t, recorder, http.StatusOK)
+	if disposition := recorder.Header().Get("Content-Disposition"); disposition != `attachment; filename="students.xlsx"` {
+		t.Fatalf("unexpected Content-Disposition %v", disposition)
+	}
+	report := importStudents(t, router, "?dryRun=true", recorder.Body.String())
+	if actions := importActions(report); !slices.Equal(actions, []string{models.ImportUnchanged, models.ImportUnchanged}) {
+		t.Fatalf("expected unchanged students, got %+v", report)
+	}
+
+	expectError(t, doRequest(t, router, http.MethodGet, "/students/export?format=pdf", nil), errorHandling.ValidationFailed)
+	expectError(t, doRequest(t, router, http.MethodGet, "/schedule/export?from=soon", nil), errorHandling.ValidationFailed)
+}
+
+// Student repository reading every student slowly, like a big collection
+type slowStudentRepository struct {
+	storage.StudentRepository
+}
+
+func (repo *slowStudentRepository) Each(ctx context.Context, query models.StudentQuery, fn func(student models.Student) error) error {
+	return repo.StudentRepository.Each(ctx, query, func(student models.Student) error {
+		time.Sleep(100 * time.Millisecond)
+		return fn(student)
+	})
+}
+
+// An export streams for longer than the write timeout of the server
+func TestExportOutlivesWriteTimeout(t *testing.T) {
+	store := storage.NewMemoryStore()
+	store.Students = &slowStudentRepository{StudentRepository: store.Students}
+	_, router := newStoreServer(t, store, config.Default())
+	admin := withToken(router, login(t, router, "admin", testPassword))
+	for index := range 5 {
+		createStudent(t, admin, "Student "+string(rune('A'+index)), "+12345678901"+string(rune('0'+index)))
+	}
+
+	server := httptest.NewUnstartedServer(admin)
+	server.Config.WriteTimeout = 200 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/students/export")
+	if err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	defer response.Body.Close()
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("export was cut off after %q: %v", content, err)
+	}
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(string(content), "\xef\xbb\xbf"))).ReadAll()
+	if err != nil || len(records) != 6 {
+		t.Fatalf("expected 5 students in the export, got %q, %v", content, err)
+	}
+}
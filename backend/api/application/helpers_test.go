@@ -0,0 +1,153 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/DanVerh/artschool-admin/backend/api/auth"
+	"github.com/DanVerh/artschool-admin/backend/api/config"
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/notifications"
+	"github.com/DanVerh/artschool-admin/backend/api/storage"
+)
+
+// Password of every user created in tests
+const testPassword = "correct-horse-battery"
+
+// Build the full router on top of a fresh in-memory store with an "admin" user
+func newTestServer(t *testing.T) (*storage.Store, *chi.Mux) {
+	t.Helper()
+
+	return newConfigServer(t, config.Default())
+}
+
+// Build the server on an in-memory store with the given configuration
+func newConfigServer(t *testing.T, cfg *config.Config) (*storage.Store, *chi.Mux) {
+	t.Helper()
+
+	return newStoreServer(t, storage.NewMemoryStore(), cfg)
+}
+
+// Build the server on the store, e.g. with a repository replaced by the test
+func newStoreServer(t *testing.T, store *storage.Store, cfg *config.Config) (*storage.Store, *chi.Mux) {
+	t.Helper()
+
+	authManager := auth.NewManager(store.Users, store.Sessions, []byte("test-secret-test-secret-test-secret"), time.Hour)
+	err := authManager.EnsureUser(context.Background(), "admin", testPassword, auth.RoleOwner)
+	if err != nil {
+		t.Fatalf("failed to create admin user: %v", err)
+	}
+
+	reminders, err := notifications.NewReminders(store, cfg)
+	if err != nil {
+		t.Fatalf("failed to create notifications: %v", err)
+	}
+
+	return store, loadRoutes(store, authManager, cfg, reminders)
+}
+
+// Build the router with all requests authenticated as the "admin" user
+func newTestRouter(t *testing.T) http.Handler {
+	t.Helper()
+
+	_, router := newTestServer(t)
+	return withToken(router, login(t, router, "admin", testPassword))
+}
+
+// Create a user with the role through the API of the admin and return a router authenticated as this user
+func newUserRouter(t *testing.T, adminRouter http.Handler, router http.Handler, username string, role string) http.Handler {
+	t.Helper()
+
+	recorder := doRequest(t, adminRouter, http.MethodPost, "/users", map[string]string{"username": username, "password": testPassword, "role": role})
+	expectStatus(t, recorder, http.StatusCreated)
+	return withToken(router, login(t, router, username, testPassword))
+}
+
+// Log in and return the session token
+func login(t *testing.T, router http.Handler, username string, password string) string {
+	t.Helper()
+
+	recorder := doRequest(t, router, http.MethodPost, "/auth/login", map[string]string{"username": username, "password": password})
+	expectStatus(t, recorder, http.StatusOK)
+	var response struct {
+		Token string `json:"token"`
+	}
+	decodeResponse(t, recorder, &response)
+	return response.Token
+}
+
+// Send the token with every request that has no Authorization header yet
+func withToken(router http.Handler, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, r)
+	})
+}
+
+// Send a request to the router; body is encoded as JSON unless it is a string
+func doRequest(t *testing.T, router http.Handler, method string, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var content []byte
+	switch value := body.(type) {
+	case nil:
+	case string:
+		content = []byte(value)
+	default:
+		var err error
+		content, err = json.Marshal(value)
+		if err != nil {
+			t.Fatalf("failed to encode request body: %v", err)
+		}
+	}
+
+	request := httptest.NewRequest(method, path, bytes.NewReader(content))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// Decode the JSON response body into value
+func decodeResponse(t *testing.T, recorder *httptest.ResponseRecorder, value interface{}) {
+	t.Helper()
+
+	err := json.Unmarshal(recorder.Body.Bytes(), value)
+	if err != nil {
+		t.Fatalf("failed to decode response %q: %v", recorder.Body.String(), err)
+	}
+}
+
+// Check status code of the response
+func expectStatus(t *testing.T, recorder *httptest.ResponseRecorder, statusCode int) {
+	t.Helper()
+
+	if recorder.Code != statusCode {
+		t.Fatalf("expected status %v, got %v: %v", statusCode, recorder.Code, recorder.Body.String())
+	}
+}
+
+// Check status code and error code of an error response
+func expectError(t *testing.T, recorder *httptest.ResponseRecorder, code errorHandling.Code) errorHandling.Error {
+	t.Helper()
+
+	expectStatus(t, recorder, code.StatusCode())
+	var response errorHandling.Error
+	decodeResponse(t, recorder, &response)
+	if response.Code != code {
+		t.Fatalf("expected error code %v, got %v", code, response.Code)
+	}
+	if response.RequestId == "" {
+		t.Fatalf("expected request id in error response")
+	}
+
+	return response
+}
@@ -0,0 +1,157 @@
+package application
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+func importStudents(t *testing.T, router http.Handler, query string, content string) models.StudentImport {
+	t.Helper()
+
+	recorder := doRequest(t, router, http.MethodPost, "/students/import"+query, content)
+	expectStatus(t, recorder, http.StatusOK)
+	report := models.StudentImport{}
+	decodeResponse(t, recorder, &report)
+	return report
+}
+
+func importActions(report models.StudentImport) []string {
+	actions := []string{}
+	for _, row := range report.Rows {
+		actions = append(actions, row.Action)
+	}
+	return actions
+}
+
+func TestStudentsImport(t *testing.T) {
+	router := newTestRouter(t)
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+	createStudent(t, router, "Bob Smith", "+123456789013")
+	carol := createStudent(t, router, "Carol White", "+123456789014")
+	expectStatus(t, doRequest(t, router, http.MethodDelete, "/students/"+carol.Id.Hex(), nil), http.StatusOK)
+
+	// Spreadsheet export with a semicolon, its own headers and an empty row
+	file := "Name;Phone number;Notes;Started\r\n" +
+		"Dan Green;+123456789015;Beginner;2024-03-01\r\n" +
+		"Alice Johnson;+123456789012;Prefers mornings;\r\n" +
+		"Bob Smith;+123456789013;;\r\n" +
+		";;;\r\n" +
+		"Eve Black;12345;;yesterday\r\n" +
+		"Dan Again;+123456789015;;\r\n" +
+		"Carol White;+123456789014;;\r\n"
+	query := "?fullname=Name&phone=Phone%20number&comments=Notes&startDate=Started"
+
+	report := importStudents(t, router, query+"&dryRun=true", file)
+	expected := []string{models.ImportCreate, models.ImportUpdate, models.ImportUnchanged, models.ImportReject, models.ImportReject, models.ImportReject}
+	if actions := importActions(report); !slices.Equal(actions, expected) || !report.DryRun || report.Created != 1 || report.Updated != 1 || report.Unchanged != 1 || report.Rejected != 3 {
+		t.Fatalf("expected %v, got %+v", expected, report)
+	}
+	if rows := report.Rows; rows[0].Row != 2 || rows[0].StudentId != nil || rows[1].StudentId == nil || *rows[1].StudentId != alice.Id || !slices.Equal(rows[1].Fields, []string{"comments"}) {
+		t.Fatalf("unexpected rows %+v", rows[:2])
+	}
+	if rejected := report.Rows[3]; rejected.Row != 6 || len(rejected.Errors) != 2 || rejected.Errors[0].Field != "phone" || rejected.Errors[1].Field != "startDate" {
+		t.Fatalf("unexpected rejected row %+v", rejected)
+	}
+	if errors := report.Rows[4].Errors; len(errors) != 1 || errors[0].Message != "phone is already used in row 2" {
+		t.Fatalf("expected repeated phone, got %+v", errors)
+	}
+	if len(listStudents(t, router, "").Students) != 2 {
+		t.Fatalf("expected a dry run to save nothing")
+	}
+
+	// The real import saves the rows of the dry run; importing again changes nothing
+	report = importStudents(t, router, query, file)
+	if actions := importActions(report); !slices.Equal(actions, expected) || report.DryRun || report.Rows[0].StudentId == nil {
+		t.Fatalf("expected %v, got %+v", expected, report)
+	}
+	students := listStudents(t, router, "").Students
+	if len(students) != 3 || students[0].Comments == nil || *students[0].Comments != "Prefers mornings" || students[2].Fullname != "Dan Green" || students[2].StartDate == nil {
+		t.Fatalf("unexpected students %+v", students)
+	}
+	report = importStudents(t, router, query, file)
+	if report.Created != 0 || report.Updated != 0 || report.Unchanged != 3 {
+		t.Fatalf("expected the second import to change nothing, got %+v", report)
+	}
+
+	// XLSX file with a shared string, an inline string and a spreadsheet date
+	report = importStudents(t, router, "?dryRun=true", xlsxFile(t, xlsxStudentRows))
+	if len(report.Rows) != 1 || report.Rows[0].Action != models.ImportCreate || report.Rows[0].Fullname != "Frank Brown" || report.Rows[0].Phone != "+123456789016" {
+		t.Fatalf("unexpected XLSX report %+v", report)
+	}
+
+	// Teachers may not create students
+	teacherRouter := newUserRouter(t, router, router, "anna", "teacher")
+	expectError(t, doRequest(t, teacherRouter, http.MethodPost, "/students/import", file), errorHandling.Forbidden)
+
+	expectError(t, doRequest(t, router, http.MethodPost, "/students/import", file), errorHandling.ValidationFailed)
+	expectError(t, doRequest(t, router, http.MethodPost, "/students/import"+query+"&lastDate=Last", file), errorHandling.ValidationFailed)
+	expectError(t, doRequest(t, router, http.MethodPost, "/students/import?dryRun=maybe", file), errorHandling.ValidationFailed)
+	expectError(t, doRequest(t, router, http.MethodPost, "/students/import", ""), errorHandling.ValidationFailed)
+
+	// Far cell references would pad the row up to the column
+	for _, ref := range []string{"XFE1", "ZZZZZZ1", "ZZZZZZZZZZZZZZ1", "AMK1"} {
+		file := xlsxFile(t, `<row r="1"><c r="`+ref+`" t="inlineStr"><is><t>fullname</t></is></c></row>`)
+		expectError(t, doRequest(t, router, http.MethodPost, "/students/import?dryRun=true", file), errorHandling.ValidationFailed)
+	}
+	tooLong := "fullname,phone\n" + strings.Repeat("Frank Brown,+123456789016\n", 5001)
+	expectError(t, doRequest(t, router, http.MethodPost, "/students/import?dryRun=true", tooLong), errorHandling.ValidationFailed)
+}
+
+// Rows of the sheet with a header and one student
+const xlsxStudentRows = `<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="inlineStr"><is><t>lastDate</t></is></c></row>` +
+	`<row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2" t="inlineStr"><is><t>+123456789016</t></is></c><c r="C2"><v>45352</v></c></row>`
+
+// Smallest XLSX file with the rows in its only sheet
+func xlsxFile(t *testing.T, rows string) string {
+	t.Helper()
+
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Students" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><si><t>fullname</t></si><si><t>phone</t></si><si><r><t>Frank </t></r><r><t>Brown</t></r></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+			rows + `</sheetData></worksheet>`,
+	}
+
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+	for name, content := range parts {
+		file, err := archive.Create(name)
+		if err != nil {
+			t.Fatalf("failed to create %v: %v", name, err)
+		}
+		file.Write([]byte(content))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("failed to write XLSX: %v", err)
+	}
+	return buffer.String()
+}
+
+// Text escaped by the export imports as it was
+func TestStudentsExportImportRoundTrip(t *testing.T) {
+	router := newTestRouter(t)
+	anna := createStudent(t, router, "-=Anna=-", "+123456789012")
+	expectStatus(t, doRequest(t, router, http.MethodPut, "/students/"+anna.Id.Hex(), map[string]string{"comments": "@home, +1 lesson"}), http.StatusOK)
+	createStudent(t, router, "Bob Smith", "+123456789013")
+
+	recorder := doRequest(t, router, http.MethodGet, "/students/export", nil)
+	expectStatus(t, recorder, http.StatusOK)
+	if !strings.Contains(recorder.Body.String(), "'-=Anna=-") {
+		t.Fatalf("expected the name escaped in the export, got %q", recorder.Body.String())
+	}
+
+	report := importStudents(t, router, "", recorder.Body.String())
+	if report.Unchanged != 2 || report.Updated != 0 || report.Created != 0 || report.Rejected != 0 {
+		t.Fatalf("expected the exported students unchanged, got %+v", report)
+	}
+}
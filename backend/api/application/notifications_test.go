@@ -0,0 +1,205 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DanVerh/artschool-admin/backend/api/auth"
+	"github.com/DanVerh/artschool-admin/backend/api/config"
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+	"github.com/DanVerh/artschool-admin/backend/api/notifications"
+)
+
+// Build the server of the "admin" user sending notifications to the file
+func newNotificationServer(t *testing.T, path string) (http.Handler, http.Handler) {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.NotificationSender = config.FileNotificationSender
+	cfg.NotificationFile = path
+	cfg.StudentDeletePolicy = config.KeepStudentDelete
+	_, router := newConfigServer(t, cfg)
+	return router, withToken(router, login(t, router, "admin", testPassword))
+}
+
+func sendReminders(t *testing.T, router http.Handler, date string) models.ReminderRun {
+	t.Helper()
+
+	recorder := doRequest(t, router, http.MethodPost, "/notifications/reminders?date="+date, nil)
+	expectStatus(t, recorder, http.StatusOK)
+	var run models.ReminderRun
+	decodeResponse(t, recorder, &run)
+	return run
+}
+
+func listNotifications(t *testing.T, router http.Handler, query string) []models.Notification {
+	t.Helper()
+
+	recorder := doRequest(t, router, http.MethodGet, "/notifications"+query, nil)
+	expectStatus(t, recorder, http.StatusOK)
+	var notifications []models.Notification
+	decodeResponse(t, recorder, &notifications)
+	return notifications
+}
+
+func TestReminders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.jsonl")
+	router, admin := newNotificationServer(t, path)
+	alice := createStudent(t, admin, "Alice Johnson", "+123456789012")
+	bob := createStudent(t, admin, "Bob Smith", "+123456789013")
+	anna := createTeacher(t, admin, "Anna Brown", "+380501234567", "drawing")
+	room := createRoom(t, admin, "Room A", 4)
+	createSchedule(t, admin, "2024-03-04T00:00:00Z", roomClass(alice.Id.Hex(), "16:00", room, anna), class(bob.Id.Hex(), "17:00", "painting"))
+	createSchedule(t, admin, "2024-03-05T00:00:00Z", class(alice.Id.Hex(), "10:00", "drawing"))
+	expectStatus(t, doRequest(t, admin, http.MethodDelete, "/students/"+bob.Id.Hex(), nil), http.StatusOK)
+
+	run := sendReminders(t, admin, "2024-03-04")
+	if run.Sent != 1 || run.Failed != 0 || run.Skipped != 1 {
+		t.Fatalf("expected 1 sent and archived Bob skipped, got %+v", run)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read sent messages: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	var message map[string]string
+	if err := json.Unmarshal([]byte(lines[0]), &message); len(lines) != 1 || err != nil {
+		t.Fatalf("expected one sent message, got %q", content)
+	}
+	expected := "Hello, Alice Johnson! Your drawing class is tomorrow, Monday, March 4 at 16:00, Room A. See you at the art school!"
+	if message["to"] != alice.Phone || message["text"] != expected {
+		t.Fatalf("unexpected message %v", message)
+	}
+
+	notifications := listNotifications(t, admin, "?studentId="+alice.Id.Hex())
+	if len(notifications) != 1 || notifications[0].Status != models.NotificationSent || notifications[0].Attempts != 1 ||
+		notifications[0].Channel != config.FileNotificationSender || notifications[0].SentAt == nil {
+		t.Fatalf("expected one sent notification, got %+v", notifications)
+	}
+
+	// Reminders already sent are not sent again
+	if run := sendReminders(t, admin, "2024-03-04"); run.Sent != 0 || run.Failed != 0 {
+		t.Fatalf("expected nothing sent again, got %+v", run)
+	}
+	if run := sendReminders(t, admin, "2024-03-05"); run.Sent != 1 {
+		t.Fatalf("expected reminder of the next day, got %+v", run)
+	}
+	if notifications := listNotifications(t, admin, "?status=sent&limit=1"); len(notifications) != 1 || notifications[0].Text == expected {
+		t.Fatalf("expected newest notification first, got %+v", notifications)
+	}
+
+	expectError(t, doRequest(t, admin, http.MethodPost, "/notifications/reminders?date=tomorrow", nil), errorHandling.ValidationFailed)
+	expectError(t, doRequest(t, admin, http.MethodGet, "/notifications?status=lost", nil), errorHandling.ValidationFailed)
+	teacher := newUserRouter(t, admin, router, "teacher", auth.RoleTeacher)
+	expectForbidden(t, teacher, http.MethodGet, "/notifications", nil, auth.NotificationsRead)
+	receptionist := newUserRouter(t, admin, router, "receptionist", auth.RoleReceptionist)
+	expectStatus(t, doRequest(t, receptionist, http.MethodGet, "/notifications", nil), http.StatusOK)
+	expectForbidden(t, receptionist, http.MethodPost, "/notifications/reminders", nil, auth.NotificationsSend)
+}
+
+func TestRemindersRetries(t *testing.T) {
+	// The directory of the file is missing, so every message fails
+	_, admin := newNotificationServer(t, filepath.Join(t.TempDir(), "missing", "notifications.jsonl"))
+	alice := createStudent(t, admin, "Alice Johnson", "+123456789012")
+	createSchedule(t, admin, "2024-03-04T00:00:00Z", class(alice.Id.Hex(), "16:00", "drawing"))
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		if run := sendReminders(t, admin, "2024-03-04"); run.Failed != 1 {
+			t.Fatalf("expected failed attempt %v, got %+v", attempt, run)
+		}
+	}
+	if run := sendReminders(t, admin, "2024-03-04"); run.Failed != 0 {
+		t.Fatalf("expected no attempts after the last one, got %+v", run)
+	}
+	notifications := listNotifications(t, admin, "?status=failed")
+	if len(notifications) != 1 || notifications[0].Attempts != 3 || notifications[0].Error == "" || notifications[0].SentAt != nil {
+		t.Fatalf("expected failed notification with 3 attempts, got %+v", notifications)
+	}
+}
+
+// Sender that fails until it is told otherwise and takes a while per message, so runs overlap
+type slowSender struct {
+	mutex   sync.Mutex
+	failing bool
+	sent    []notifications.Message
+}
+
+func (sender *slowSender) Channel() string {
+	return "test"
+}
+
+func (sender *slowSender) Send(ctx context.Context, message notifications.Message) error {
+	time.Sleep(20 * time.Millisecond)
+
+	sender.mutex.Lock()
+	defer sender.mutex.Unlock()
+	if sender.failing {
+		return errors.New("channel is down")
+	}
+	sender.sent = append(sender.sent, message)
+	return nil
+}
+
+// Runs at the same time, like on several servers, send every reminder once, retries too
+func TestRemindersConcurrentRuns(t *testing.T) {
+	cfg := config.Default()
+	cfg.NotificationSender = config.LogNotificationSender
+	store, router := newConfigServer(t, cfg)
+	admin := withToken(router, login(t, router, "admin", testPassword))
+	alice := createStudent(t, admin, "Alice Johnson", "+123456789012")
+	bob := createStudent(t, admin, "Bob Smith", "+123456789013")
+	carol := createStudent(t, admin, "Carol White", "+123456789014")
+	schedule := createSchedule(t, admin, "2024-03-04T00:00:00Z", class(alice.Id.Hex(), "16:00", "drawing"), class(bob.Id.Hex(), "17:00", "painting"))
+
+	reminders, err := notifications.NewReminders(store, cfg)
+	if err != nil {
+		t.Fatalf("failed to create notifications: %v", err)
+	}
+	sender := &slowSender{failing: true}
+	reminders.Sender = sender
+	day := time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC)
+
+	// The first attempts fail, the retries and a new class go out together
+	if run, err := reminders.Run(context.Background(), day); err != nil || run.Failed != 2 {
+		t.Fatalf("expected 2 failed attempts, got %+v, %v", run, err)
+	}
+	sender.failing = false
+	addClass(t, admin, schedule, class(carol.Id.Hex(), "18:00", "drawing"))
+
+	runs := make([]models.ReminderRun, 8)
+	var wait sync.WaitGroup
+	for index := range runs {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			runs[index], _ = reminders.Run(context.Background(), day)
+		}()
+	}
+	wait.Wait()
+
+	sent := 0
+	for _, run := range runs {
+		sent += run.Sent
+	}
+	if sent != 3 || len(sender.sent) != 3 {
+		t.Fatalf("expected 3 reminders sent once each, got %v sent and messages %+v", sent, sender.sent)
+	}
+}
+
+func TestRemindersOff(t *testing.T) {
+	router := newTestRouter(t)
+
+	expectError(t, doRequest(t, router, http.MethodPost, "/notifications/reminders", nil), errorHandling.NotificationsOff)
+	if notifications := listNotifications(t, router, ""); len(notifications) != 0 {
+		t.Fatalf("expected no notifications, got %+v", notifications)
+	}
+}
@@ -0,0 +1,158 @@
+package application
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DanVerh/artschool-admin/backend/api/auth"
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+// Post a ledger entry through the API and return it
+func postPayment(t *testing.T, router http.Handler, path string, body interface{}) models.Payment {
+	t.Helper()
+
+	recorder := doRequest(t, router, http.MethodPost, path, body)
+	expectStatus(t, recorder, http.StatusCreated)
+	var payment models.Payment
+	decodeResponse(t, recorder, &payment)
+	return payment
+}
+
+func TestPaymentsLedger(t *testing.T) {
+	router := newTestRouter(t)
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+	pack := sellSubscription(t, router, alice, 8)
+
+	payment := postPayment(t, router, "/payments", map[string]interface{}{
+		"method": "card", "amount": 200000, "currency": "UAH", "subscriptionId": pack.Id.Hex(),
+	})
+	if payment.Kind != models.PaymentKindPayment || payment.StudentId == nil || *payment.StudentId != alice.Id || payment.RecordedBy != "admin" {
+		t.Fatalf("unexpected payment %+v", payment)
+	}
+
+	// A correction changes the amount that can be refunded
+	correction := postPayment(t, router, "/payments/"+payment.Id.Hex()+"/correction", map[string]interface{}{"amount": -20000, "note": "Discount for the second pack"})
+	if correction.Kind != models.PaymentKindCorrection || correction.Amount != -20000 || *correction.RelatedTo != payment.Id {
+		t.Fatalf("unexpected correction %+v", correction)
+	}
+	refund := postPayment(t, router, "/payments/"+payment.Id.Hex()+"/refund", map[string]interface{}{"amount": 30000, "method": "cash"})
+	if refund.Kind != models.PaymentKindRefund || refund.Amount != -30000 || refund.Method != "cash" || refund.Currency != "UAH" {
+		t.Fatalf("unexpected refund %+v", refund)
+	}
+	rest := postPayment(t, router, "/payments/"+payment.Id.Hex()+"/refund", nil)
+	if rest.Amount != -150000 || rest.Method != "card" {
+		t.Fatalf("expected the rest of 150000 to be refunded by card, got %+v", rest)
+	}
+	recorder := doRequest(t, router, http.MethodPost, "/payments/"+payment.Id.Hex()+"/refund", map[string]int{"amount": 1})
+	expectError(t, recorder, errorHandling.ValidationFailed)
+
+	recorder = doRequest(t, router, http.MethodGet, "/payments?studentId="+alice.Id.Hex()+"&kind=refund", nil)
+	expectStatus(t, recorder, http.StatusOK)
+	var refunds []models.Payment
+	decodeResponse(t, recorder, &refunds)
+	if len(refunds) != 2 || refunds[0].Id != rest.Id {
+		t.Fatalf("expected 2 refunds, newest first, got %+v", refunds)
+	}
+}
+
+// Refunds and corrections sent at the same time can't take more than was paid
+func TestPaymentsConcurrentRefunds(t *testing.T) {
+	router := newTestRouter(t)
+	payment := postPayment(t, router, "/payments", map[string]interface{}{"method": "cash", "amount": 10000, "currency": "UAH"})
+
+	recorder := doRequest(t, router, http.MethodPost, "/payments/"+payment.Id.Hex()+"/correction", map[string]interface{}{"amount": -10001, "note": "Too much"})
+	expectError(t, recorder, errorHandling.ValidationFailed)
+
+	statuses := make([]int, 10)
+	var wait sync.WaitGroup
+	for index := range statuses {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			path, body := "/payments/"+payment.Id.Hex()+"/refund", map[string]interface{}{"amount": 6000}
+			if index%2 == 1 {
+				path, body = "/payments/"+payment.Id.Hex()+"/correction", map[string]interface{}{"amount": -6000, "note": "Discount"}
+			}
+			statuses[index] = doRequest(t, router, http.MethodPost, path, body).Code
+		}()
+	}
+	wait.Wait()
+
+	created := 0
+	for _, status := range statuses {
+		if status == http.StatusCreated {
+			created++
+		}
+	}
+	if created != 1 {
+		t.Fatalf("expected exactly one refund or correction of 6000 out of 10000, got statuses %v", statuses)
+	}
+}
+
+func TestPaymentsReconciliation(t *testing.T) {
+	router := newTestRouter(t)
+	cash := postPayment(t, router, "/payments", map[string]interface{}{"method": "cash", "amount": 25000, "currency": "UAH"})
+	postPayment(t, router, "/payments", map[string]interface{}{"method": "cash", "amount": 30000, "currency": "UAH"})
+	postPayment(t, router, "/payments", map[string]interface{}{"method": "card", "amount": 100000, "currency": "UAH"})
+	postPayment(t, router, "/payments/"+cash.Id.Hex()+"/refund", map[string]int{"amount": 5000})
+
+	recorder := doRequest(t, router, http.MethodGet, "/payments/reconciliation", nil)
+	expectStatus(t, recorder, http.StatusOK)
+	report := struct {
+		Date    string `json:"date"`
+		Methods []struct {
+			Method   string `json:"method"`
+			Currency string `json:"currency"`
+			Payments int64  `json:"payments"`
+			Refunds  int64  `json:"refunds"`
+			Total    int64  `json:"total"`
+			Count    int    `json:"count"`
+		} `json:"methods"`
+	}{}
+	decodeResponse(t, recorder, &report)
+	if report.Date != time.Now().UTC().Format(time.DateOnly) || len(report.Methods) != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	card, cashLine := report.Methods[0], report.Methods[1]
+	if card.Method != "card" || card.Total != 100000 || card.Count != 1 {
+		t.Fatalf("unexpected card line %+v", card)
+	}
+	if cashLine.Method != "cash" || cashLine.Payments != 55000 || cashLine.Refunds != -5000 || cashLine.Total != 50000 || cashLine.Count != 3 {
+		t.Fatalf("unexpected cash line %+v", cashLine)
+	}
+
+	recorder = doRequest(t, router, http.MethodGet, "/payments/reconciliation?date=2000-01-01", nil)
+	expectStatus(t, recorder, http.StatusOK)
+	decodeResponse(t, recorder, &report)
+	if len(report.Methods) != 0 {
+		t.Fatalf("expected an empty report, got %+v", report)
+	}
+}
+
+func TestPaymentsErrorsAndPermissions(t *testing.T) {
+	_, router := newTestServer(t)
+	admin := withToken(router, login(t, router, "admin", testPassword))
+	receptionist := newUserRouter(t, admin, router, "reception", auth.RoleReceptionist)
+	payment := postPayment(t, receptionist, "/payments", map[string]interface{}{"method": "cash", "amount": 25000, "currency": "UAH"})
+	refund := postPayment(t, admin, "/payments/"+payment.Id.Hex()+"/refund", nil)
+
+	recorder := doRequest(t, admin, http.MethodPost, "/payments", map[string]interface{}{"method": "crypto", "amount": 0, "currency": "uah"})
+	if response := expectError(t, recorder, errorHandling.ValidationFailed); len(response.Details) != 3 {
+		t.Fatalf("expected method, amount and currency errors, got %+v", response.Details)
+	}
+	recorder = doRequest(t, admin, http.MethodPost, "/payments", map[string]interface{}{"method": "cash", "amount": 100, "currency": "UAH", "studentId": "000000000000000000000000"})
+	expectError(t, recorder, errorHandling.ValidationFailed)
+	recorder = doRequest(t, admin, http.MethodPost, "/payments/"+payment.Id.Hex()+"/correction", map[string]int{"amount": 100})
+	expectError(t, recorder, errorHandling.ValidationFailed)
+	recorder = doRequest(t, admin, http.MethodPost, "/payments/"+refund.Id.Hex()+"/refund", nil)
+	expectError(t, recorder, errorHandling.ValidationFailed)
+	recorder = doRequest(t, admin, http.MethodGet, "/payments/reconciliation?date=today", nil)
+	expectError(t, recorder, errorHandling.ValidationFailed)
+
+	// Receptionists take payments but do not give money back
+	expectForbidden(t, receptionist, http.MethodPost, "/payments/"+payment.Id.Hex()+"/refund", nil, auth.PaymentsAdjust)
+}
@@ -0,0 +1,99 @@
+package application
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/DanVerh/artschool-admin/backend/api/auth"
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+)
+
+// Expect 403 that names the missing permission
+func expectForbidden(t *testing.T, router http.Handler, method string, path string, body interface{}, permission auth.Permission) {
+	t.Helper()
+
+	recorder := doRequest(t, router, method, path, body)
+	response := expectError(t, recorder, errorHandling.Forbidden)
+	if !strings.Contains(response.Message, string(permission)) && !strings.Contains(recorder.Body.String(), string(permission)) {
+		t.Fatalf("expected missing permission %v in response, got %v", permission, recorder.Body.String())
+	}
+}
+
+func TestTeacherPermissions(t *testing.T) {
+	_, router := newTestServer(t)
+	admin := withToken(router, login(t, router, "admin", testPassword))
+	teacher := newUserRouter(t, admin, router, "teacher", auth.RoleTeacher)
+
+	alice := createStudent(t, admin, "Alice Johnson", "+123456789012")
+	schedule := createSchedule(t, admin, "2024-03-01T00:00:00Z", class(alice.Id.Hex(), "14:00", "both"))
+	studentPath := "/students/" + alice.Id.Hex()
+	schedulePath := "/schedule/" + schedule.Id.Hex()
+
+	// Reading is allowed
+	expectStatus(t, doRequest(t, teacher, http.MethodGet, "/students", nil), http.StatusOK)
+	expectStatus(t, doRequest(t, teacher, http.MethodGet, schedulePath, nil), http.StatusOK)
+
+	// Attendance and comments are allowed
+	sellSubscription(t, admin, alice, 8)
+	expectStatus(t, doRequest(t, teacher, http.MethodPost, schedulePath+"/classes/"+alice.Id.Hex()+"/attendance", map[string]bool{"attendance": true}), http.StatusOK)
+	expectStatus(t, doRequest(t, teacher, http.MethodPut, studentPath, map[string]string{"comments": "Good work"}), http.StatusOK)
+
+	// Contacts, subscriptions and bookings are not
+	expectForbidden(t, teacher, http.MethodPut, studentPath, map[string]string{"phone": "+123456789099"}, auth.StudentsUpdateContacts)
+	expectForbidden(t, teacher, http.MethodPut, studentPath, map[string]string{"startDate": "2024-03-01T00:00:00Z"}, auth.StudentsUpdateSubscription)
+	expectForbidden(t, teacher, http.MethodPost, studentPath+"/subscriptions", map[string]int{"size": 8, "price": 100}, auth.StudentsUpdateSubscription)
+	expectForbidden(t, teacher, http.MethodPut, schedulePath, class(alice.Id.Hex(), "15:00", "both"), auth.ScheduleUpdate)
+	expectForbidden(t, teacher, http.MethodPut, schedulePath, class("000000000000000000000001", "14:00", "both"), auth.ScheduleUpdate)
+	expectForbidden(t, teacher, http.MethodPost, "/students", map[string]string{"fullname": "Bob", "phone": "+123456789013"}, auth.StudentsCreate)
+	expectForbidden(t, teacher, http.MethodDelete, schedulePath, nil, auth.ScheduleDelete)
+	expectForbidden(t, teacher, http.MethodGet, "/users", nil, auth.UsersManage)
+}
+
+func TestReceptionistPermissions(t *testing.T) {
+	_, router := newTestServer(t)
+	admin := withToken(router, login(t, router, "admin", testPassword))
+	receptionist := newUserRouter(t, admin, router, "reception", auth.RoleReceptionist)
+
+	alice := createStudent(t, receptionist, "Alice Johnson", "+123456789012")
+	schedule := createSchedule(t, receptionist, "2024-03-01T00:00:00Z", class(alice.Id.Hex(), "14:00", "both"))
+
+	sellSubscription(t, receptionist, alice, 8)
+	expectStatus(t, doRequest(t, receptionist, http.MethodPut, "/schedule/"+schedule.Id.Hex(), class(alice.Id.Hex(), "16:00", "drawing")), http.StatusOK)
+
+	// Records can not be deleted
+	expectForbidden(t, receptionist, http.MethodDelete, "/students/"+alice.Id.Hex(), nil, auth.StudentsDelete)
+	expectForbidden(t, receptionist, http.MethodDelete, "/schedule/"+schedule.Id.Hex(), nil, auth.ScheduleDelete)
+	expectForbidden(t, receptionist, http.MethodDelete, "/schedule/"+schedule.Id.Hex()+"/classes/"+schedule.Classes[0].Id.Hex(), nil, auth.ScheduleDelete)
+	expectForbidden(t, receptionist, http.MethodDelete, "/templates/"+schedule.Id.Hex(), nil, auth.ScheduleDelete)
+	expectForbidden(t, receptionist, http.MethodDelete, "/holidays/"+schedule.Id.Hex(), nil, auth.ScheduleDelete)
+}
+
+func TestCreateUserValidation(t *testing.T) {
+	_, router := newTestServer(t)
+	admin := withToken(router, login(t, router, "admin", testPassword))
+
+	recorder := doRequest(t, admin, http.MethodPost, "/users", map[string]string{"username": "x", "password": "short", "role": "janitor"})
+	response := expectError(t, recorder, errorHandling.ValidationFailed)
+	if len(response.Details) != 2 {
+		t.Fatalf("expected password and role errors, got %+v", response.Details)
+	}
+
+	// bcrypt takes at most 72 bytes
+	recorder = doRequest(t, admin, http.MethodPost, "/users", map[string]string{"username": "long", "password": strings.Repeat("ї", 37), "role": auth.RoleTeacher})
+	response = expectError(t, recorder, errorHandling.ValidationFailed)
+	if len(response.Details) != 1 || response.Details[0].Field != "password" {
+		t.Fatalf("expected password error, got %+v", response.Details)
+	}
+	recorder = doRequest(t, admin, http.MethodPost, "/users", map[string]string{"username": "long", "password": strings.Repeat("a", 72), "role": auth.RoleTeacher})
+	expectStatus(t, recorder, http.StatusCreated)
+
+	recorder = doRequest(t, admin, http.MethodPost, "/users", map[string]string{"username": "admin", "password": testPassword, "role": auth.RoleTeacher})
+	expectError(t, recorder, errorHandling.Duplicate)
+
+	recorder = doRequest(t, admin, http.MethodGet, "/users/me", nil)
+	expectStatus(t, recorder, http.StatusOK)
+	if strings.Contains(recorder.Body.String(), "passwordHash") || !strings.Contains(recorder.Body.String(), auth.RoleOwner) {
+		t.Fatalf("unexpected current user %v", recorder.Body.String())
+	}
+}
@@ -0,0 +1,133 @@
+package application
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/DanVerh/artschool-admin/backend/api/auth"
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+// Create a room through the API and return it
+func createRoom(t *testing.T, router http.Handler, name string, capacity int) models.Room {
+	t.Helper()
+
+	recorder := doRequest(t, router, http.MethodPost, "/rooms", map[string]interface{}{"name": name, "capacity": capacity})
+	expectStatus(t, recorder, http.StatusCreated)
+	var room models.Room
+	decodeResponse(t, recorder, &room)
+	return room
+}
+
+func roomClass(studentId string, time string, room models.Room, teacher models.Teacher) map[string]interface{} {
+	class := teacherClass(studentId, time, "drawing", teacher)
+	class["roomId"] = room.Id.Hex()
+	return class
+}
+
+func TestRooms(t *testing.T) {
+	_, router := newTestServer(t)
+	admin := withToken(router, login(t, router, "admin", testPassword))
+	studio := createRoom(t, admin, "Studio", 5)
+
+	recorder := doRequest(t, admin, http.MethodPut, "/rooms/"+studio.Id.Hex(), map[string]int{"capacity": 6})
+	expectStatus(t, recorder, http.StatusOK)
+	var updated models.Room
+	decodeResponse(t, recorder, &updated)
+	if updated.Name != "Studio" || updated.Capacity != 6 {
+		t.Fatalf("unexpected updated room %+v", updated)
+	}
+
+	recorder = doRequest(t, admin, http.MethodPost, "/rooms", map[string]interface{}{"name": "Studio", "capacity": 3})
+	expectError(t, recorder, errorHandling.Duplicate)
+	recorder = doRequest(t, admin, http.MethodPost, "/rooms", map[string]interface{}{"name": "", "capacity": 0})
+	if response := expectError(t, recorder, errorHandling.ValidationFailed); len(response.Details) != 2 {
+		t.Fatalf("expected name and capacity errors, got %+v", response.Details)
+	}
+
+	teacher := newUserRouter(t, admin, router, "teacher", auth.RoleTeacher)
+	recorder = doRequest(t, teacher, http.MethodGet, "/rooms", nil)
+	expectStatus(t, recorder, http.StatusOK)
+	var rooms []models.Room
+	decodeResponse(t, recorder, &rooms)
+	if len(rooms) != 1 {
+		t.Fatalf("expected 1 room, got %+v", rooms)
+	}
+	expectForbidden(t, teacher, http.MethodPost, "/rooms", map[string]interface{}{}, auth.RoomsManage)
+}
+
+func TestScheduleConflicts(t *testing.T) {
+	router := newTestRouter(t)
+	small := createRoom(t, router, "Small room", 2)
+	large := createRoom(t, router, "Large room", 10)
+	anna := createTeacher(t, router, "Anna Brown", "+380501234567", "drawing")
+	var students []models.Student
+	for _, phone := range []string{"+123456789001", "+123456789002", "+123456789003", "+123456789004"} {
+		students = append(students, createStudent(t, router, "Student "+phone, phone))
+	}
+
+	// Over capacity, the same student twice and the teacher in two rooms at once
+	recorder := doRequest(t, router, http.MethodPost, "/schedule", map[string]interface{}{
+		"date": "2024-03-04T00:00:00Z",
+		"classes": []interface{}{
+			roomClass(students[0].Id.Hex(), "14:00", small, anna),
+			roomClass(students[1].Id.Hex(), "14:00", small, anna),
+			roomClass(students[2].Id.Hex(), "14:00", small, anna),
+			roomClass(students[0].Id.Hex(), "14:00", small, anna),
+			roomClass(students[3].Id.Hex(), "14:00", large, anna),
+		},
+	})
+	response := expectError(t, recorder, errorHandling.ScheduleConflict)
+	fields := map[string]bool{}
+	for _, detail := range response.Details {
+		fields[detail.Field] = true
+	}
+	if len(response.Details) != 3 || !fields["classes[3].studentId"] || !fields["classes[4].teacherId"] || !fields["classes[2].roomId"] {
+		t.Fatalf("expected student, teacher and room conflicts, got %+v", response.Details)
+	}
+
+	// Without rooms the teacher can not run two classes at once either
+	recorder = doRequest(t, router, http.MethodPost, "/schedule", map[string]interface{}{
+		"date": "2024-03-05T00:00:00Z",
+		"classes": []interface{}{
+			teacherClass(students[0].Id.Hex(), "10:00", "drawing", anna),
+			teacherClass(students[1].Id.Hex(), "10:00", "drawing", anna),
+		},
+	})
+	if response := expectError(t, recorder, errorHandling.ScheduleConflict); len(response.Details) != 1 || response.Details[0].Field != "classes[1].teacherId" {
+		t.Fatalf("expected a teacher conflict without rooms, got %+v", response.Details)
+	}
+	recorder = doRequest(t, router, http.MethodPost, "/schedule", map[string]interface{}{
+		"date": "2024-03-05T00:00:00Z",
+		"classes": []interface{}{
+			roomClass(students[0].Id.Hex(), "10:00", small, anna),
+			teacherClass(students[1].Id.Hex(), "10:00", "drawing", anna),
+		},
+	})
+	expectError(t, recorder, errorHandling.ScheduleConflict)
+
+	// Full room: the next booking at the same time is rejected, another time is fine
+	schedule := createSchedule(t, router, "2024-03-04T00:00:00Z",
+		roomClass(students[0].Id.Hex(), "14:00", small, anna),
+		roomClass(students[1].Id.Hex(), "14:00", small, anna))
+	recorder = doRequest(t, router, http.MethodPut, "/schedule/"+schedule.Id.Hex(), roomClass(students[2].Id.Hex(), "14:00", small, anna))
+	if response := expectError(t, recorder, errorHandling.ScheduleConflict); len(response.Details) != 1 || response.Details[0].Field != "roomId" {
+		t.Fatalf("expected a room conflict, got %+v", response.Details)
+	}
+	recorder = doRequest(t, router, http.MethodPut, "/schedule/"+schedule.Id.Hex(), roomClass(students[2].Id.Hex(), "15:00", small, anna))
+	expectStatus(t, recorder, http.StatusOK)
+
+	// Moving a booked student to another room is not a conflict with the student's own class
+	recorder = doRequest(t, router, http.MethodPut, "/schedule/"+schedule.Id.Hex(), roomClass(students[2].Id.Hex(), "15:00", large, anna))
+	expectStatus(t, recorder, http.StatusOK)
+	recorder = doRequest(t, router, http.MethodPut, "/schedule/"+schedule.Id.Hex(), roomClass(students[3].Id.Hex(), "15:00", small, anna))
+	if response := expectError(t, recorder, errorHandling.ScheduleConflict); len(response.Details) != 1 || response.Details[0].Field != "teacherId" {
+		t.Fatalf("expected a teacher conflict, got %+v", response.Details)
+	}
+
+	recorder = doRequest(t, router, http.MethodPut, "/schedule/"+schedule.Id.Hex(), map[string]interface{}{
+		"studentId": students[3].Id.Hex(), "time": "16:00", "type": "drawing", "roomId": "000000000000000000000000",
+	})
+	expectError(t, recorder, errorHandling.ValidationFailed)
+}
@@ -6,40 +6,252 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 
+	"github.com/DanVerh/artschool-admin/backend/api/auth"
+	"github.com/DanVerh/artschool-admin/backend/api/config"
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
 	"github.com/DanVerh/artschool-admin/backend/api/handlers"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+	"github.com/DanVerh/artschool-admin/backend/api/notifications"
+	"github.com/DanVerh/artschool-admin/backend/api/storage"
 )
 
 // Create router with confgiured routes
-func loadRoutes() *chi.Mux {
+// Everything except /health and /auth/login requires a logged in user, calendar feeds also open with a feed link
+func loadRoutes(store *storage.Store, authManager *auth.Manager, cfg *config.Config, reminders *notifications.Reminders) *chi.Mux {
 	router := chi.NewRouter()
 
+	router.Use(middleware.RequestID)
+	// Feed links are credentials, they must not end up in the request log
+	router.Use(handler.RedactFeedToken)
 	router.Use(middleware.Logger)
 
+	// Unknown routes and methods answer with the same JSON errors as the handlers
+	router.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		errorHandling.ThrowError(w, r, errorHandling.NotFound, "Route not found", nil)
+	})
+	router.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		errorHandling.ThrowError(w, r, errorHandling.MethodNotAllowed, "Method not allowed for this route", nil)
+	})
+
 	router.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
 
-	router.Route("/schedule", loadScheduleRoutes)
-	router.Route("/students", loadStudentRoutes)
+	authHandler := &handler.AuthHandler{Auth: authManager, SecureCookies: cfg.SecureCookies}
+	router.Post("/auth/login", authHandler.Login)
+
+	// Calendar apps subscribe by link and can not log in, so the calendar feeds also accept the token of a feed link
+	feedHandler := newFeedHandler(store)
+	session := func(next http.Handler) http.Handler {
+		return authManager.Middleware(auth.Require(auth.ScheduleRead)(next))
+	}
+	calendarHandler := newCalendarHandler(store)
+	router.With(feedHandler.Authorize(models.FeedSchool, session)).Get("/schedule.ics", calendarHandler.School)
+	router.With(feedHandler.Authorize(models.FeedTeacher, session)).Get("/teachers/{id}/schedule.ics", calendarHandler.Teacher)
+	router.With(feedHandler.Authorize(models.FeedStudent, session)).Get("/students/{id}/schedule.ics", calendarHandler.Student)
+
+	// Protected routes
+	router.Group(func(router chi.Router) {
+		router.Use(authManager.Middleware)
+
+		router.Post("/auth/logout", authHandler.Logout)
+		router.Route("/users", func(router chi.Router) {
+			loadUserRoutes(router, store)
+		})
+		router.Route("/schedule", func(router chi.Router) {
+			loadScheduleRoutes(router, store)
+		})
+		router.Route("/teachers", func(router chi.Router) {
+			loadTeacherRoutes(router, store)
+		})
+		router.Route("/templates", func(router chi.Router) {
+			loadTemplateRoutes(router, store)
+		})
+		router.Route("/holidays", func(router chi.Router) {
+			holidayHandler := &handler.HolidayHandler{Holidays: store.Holidays}
+			router.With(auth.Require(auth.ScheduleCreate)).Post("/", holidayHandler.Create)
+			router.With(auth.Require(auth.ScheduleRead)).Get("/", holidayHandler.List)
+			router.With(auth.Require(auth.ScheduleDelete)).Delete("/{id}", holidayHandler.DeleteByID)
+		})
+		router.Route("/rooms", func(router chi.Router) {
+			loadRoomRoutes(router, store)
+		})
+		router.Route("/students", func(router chi.Router) {
+			loadStudentRoutes(router, store, cfg)
+		})
+		router.Route("/payments", func(router chi.Router) {
+			loadPaymentRoutes(router, store)
+		})
+		router.Route("/notifications", func(router chi.Router) {
+			notificationHandler := &handler.NotificationHandler{Notifications: store.Notifications, Reminders: reminders}
+			router.With(auth.Require(auth.NotificationsRead)).Get("/", notificationHandler.List)
+			router.With(auth.Require(auth.NotificationsSend)).Post("/reminders", notificationHandler.SendReminders)
+		})
+		router.Route("/calendar-feeds", func(router chi.Router) {
+			router.With(auth.Require(auth.FeedsManage)).Post("/", feedHandler.Create)
+			router.With(auth.Require(auth.FeedsManage)).Get("/", feedHandler.List)
+			router.With(auth.Require(auth.FeedsManage)).Delete("/{id}", feedHandler.DeleteByID)
+		})
+		auditHandler := &handler.AuditHandler{Audit: store.Audit}
+		router.With(auth.Require(auth.AuditRead)).Get("/audit", auditHandler.List)
+	})
 
 	return router
 }
 
-// Define all routes with HTTP methods
-func loadStudentRoutes(router chi.Router) {
-	studentHandler := &handler.StudentHandler{}
-	router.Post("/", studentHandler.Create)
-	router.Get("/", studentHandler.List)
-	router.Get("/{id}", studentHandler.GetByID)
-	router.Put("/{id}", studentHandler.UpdateByID)
-	router.Delete("/{id}", studentHandler.DeleteByID)
+// Define all routes with HTTP methods and permissions required for them
+// Updates accept any of the field permissions, handlers check them per field
+func loadStudentRoutes(router chi.Router, store *storage.Store, cfg *config.Config) {
+	studentHandler := &handler.StudentHandler{
+		Students:      store.Students,
+		Schedules:     store.Schedules,
+		Audit:         store.Audit,
+		Transactions:  store.Transactions,
+		Cancellations: store.Cancellations,
+		DeletePolicy:  cfg.StudentDeletePolicy,
+	}
+	router.With(auth.Require(auth.StudentsCreate)).Post("/", studentHandler.Create)
+	router.With(auth.Require(auth.StudentsCreate)).Post("/import", studentHandler.Import)
+	router.With(auth.Require(auth.StudentsRead)).Get("/", studentHandler.List)
+	router.With(auth.Require(auth.StudentsRead)).Get("/export", newExportHandler(store).ExportStudents)
+	router.With(auth.Require(auth.StudentsRead)).Get("/{id}", studentHandler.GetByID)
+	router.With(auth.Require(auth.StudentsUpdateContacts, auth.StudentsUpdateSubscription, auth.StudentsUpdateComments)).Put("/{id}", studentHandler.UpdateByID)
+	router.With(auth.Require(auth.StudentsDelete)).Delete("/{id}", studentHandler.DeleteByID)
+	router.With(auth.Require(auth.StudentsDelete)).Post("/{id}/restore", studentHandler.Restore)
+	router.With(auth.Require(auth.StudentsPurge)).Post("/{id}/purge", studentHandler.Purge)
+
+	subscriptionHandler := &handler.SubscriptionHandler{
+		Students:      store.Students,
+		Subscriptions: store.Subscriptions,
+		Audit:         store.Audit,
+		Transactions:  store.Transactions,
+		Validity:      cfg.SubscriptionValidity,
+	}
+	router.With(auth.Require(auth.StudentsUpdateSubscription)).Post("/{id}/subscriptions", subscriptionHandler.Create)
+	router.With(auth.Require(auth.StudentsRead)).Get("/{id}/subscriptions", subscriptionHandler.List)
+	router.With(auth.Require(auth.StudentsUpdateSubscription)).Post("/{id}/subscriptions/{subscriptionId}/renew", subscriptionHandler.Renew)
+
+	timelineHandler := &handler.TimelineHandler{
+		Students:      store.Students,
+		Schedules:     store.Schedules,
+		Subscriptions: store.Subscriptions,
+		Audit:         store.Audit,
+	}
+	router.With(auth.Require(auth.StudentsRead)).Get("/{id}/timeline", timelineHandler.Get)
+}
+
+func loadScheduleRoutes(router chi.Router, store *storage.Store) {
+	scheduleHandler := &handler.ScheduleHandler{
+		Schedules:     store.Schedules,
+		Students:      store.Students,
+		Teachers:      store.Teachers,
+		Rooms:         store.Rooms,
+		Audit:         store.Audit,
+		Cancellations: store.Cancellations,
+	}
+	router.With(auth.Require(auth.ScheduleCreate)).Post("/", scheduleHandler.Create)
+	router.With(auth.Require(auth.ScheduleRead)).Get("/", scheduleHandler.List)
+	router.With(auth.Require(auth.ScheduleRead)).Get("/export", newExportHandler(store).ExportSchedule)
+	router.With(auth.Require(auth.ScheduleRead)).Get("/attendance/export", newExportHandler(store).ExportAttendance)
+	router.With(auth.Require(auth.ScheduleRead)).Get("/by-date/{date}", scheduleHandler.GetByDate)
+	router.With(auth.Require(auth.ScheduleRead)).Get("/{id}", scheduleHandler.GetByID)
+	router.With(auth.Require(auth.ScheduleUpdate)).Put("/{id}", scheduleHandler.UpdateByID)
+	router.With(auth.Require(auth.ScheduleDelete)).Delete("/{id}", scheduleHandler.DeleteByID)
+	router.With(auth.Require(auth.ScheduleUpdate)).Post("/{id}/classes", scheduleHandler.AddClass)
+	router.With(auth.Require(auth.ScheduleUpdate)).Patch("/{id}/classes/{classId}", scheduleHandler.UpdateClass)
+	router.With(auth.Require(auth.ScheduleDelete)).Delete("/{id}/classes/{classId}", scheduleHandler.DeleteClass)
+
+	attendanceHandler := &handler.AttendanceHandler{
+		Schedules:     store.Schedules,
+		Students:      store.Students,
+		Subscriptions: store.Subscriptions,
+		Audit:         store.Audit,
+		Transactions:  store.Transactions,
+	}
+	router.With(auth.Require(auth.ScheduleAttendance)).Post("/{id}/classes/{classId}/attendance", attendanceHandler.Mark)
+}
+
+func loadTeacherRoutes(router chi.Router, store *storage.Store) {
+	teacherHandler := &handler.TeacherHandler{Teachers: store.Teachers, Audit: store.Audit}
+	router.With(auth.Require(auth.TeachersManage)).Post("/", teacherHandler.Create)
+	router.With(auth.Require(auth.TeachersRead)).Get("/", teacherHandler.List)
+	router.With(auth.Require(auth.TeachersRead)).Get("/{id}", teacherHandler.GetByID)
+	router.With(auth.Require(auth.TeachersManage)).Put("/{id}", teacherHandler.UpdateByID)
+}
+
+// Templates plan classes, so they need the same permissions as the schedule
+func loadTemplateRoutes(router chi.Router, store *storage.Store) {
+	templateHandler := &handler.TemplateHandler{
+		Templates:     store.Templates,
+		Holidays:      store.Holidays,
+		Schedules:     store.Schedules,
+		Students:      store.Students,
+		Subscriptions: store.Subscriptions,
+		Teachers:      store.Teachers,
+		Rooms:         store.Rooms,
+		Audit:         store.Audit,
+	}
+	router.With(auth.Require(auth.ScheduleCreate)).Post("/", templateHandler.Create)
+	router.With(auth.Require(auth.ScheduleRead)).Get("/", templateHandler.List)
+	router.With(auth.Require(auth.ScheduleCreate)).Post("/generate", templateHandler.Generate)
+	router.With(auth.Require(auth.ScheduleRead)).Get("/{id}", templateHandler.GetByID)
+	router.With(auth.Require(auth.ScheduleDelete)).Delete("/{id}", templateHandler.DeleteByID)
+}
+
+// Everybody planning or reading the schedule sees the rooms
+func loadRoomRoutes(router chi.Router, store *storage.Store) {
+	roomHandler := &handler.RoomHandler{Rooms: store.Rooms}
+	router.With(auth.Require(auth.RoomsManage)).Post("/", roomHandler.Create)
+	router.With(auth.Require(auth.ScheduleRead)).Get("/", roomHandler.List)
+	router.With(auth.Require(auth.ScheduleRead)).Get("/{id}", roomHandler.GetByID)
+	router.With(auth.Require(auth.RoomsManage)).Put("/{id}", roomHandler.UpdateByID)
+}
+
+func loadUserRoutes(router chi.Router, store *storage.Store) {
+	userHandler := &handler.UserHandler{Users: store.Users}
+	router.Get("/me", userHandler.Me)
+	router.With(auth.Require(auth.UsersManage)).Post("/", userHandler.Create)
+	router.With(auth.Require(auth.UsersManage)).Get("/", userHandler.List)
+}
+
+// Refunds and corrections are separate ledger entries, they need their own permission
+func loadPaymentRoutes(router chi.Router, store *storage.Store) {
+	paymentHandler := &handler.PaymentHandler{Payments: store.Payments, Students: store.Students, Subscriptions: store.Subscriptions, Transactions: store.Transactions}
+	router.With(auth.Require(auth.PaymentsCreate)).Post("/", paymentHandler.Create)
+	router.With(auth.Require(auth.PaymentsRead)).Get("/", paymentHandler.List)
+	router.With(auth.Require(auth.PaymentsRead)).Get("/reconciliation", paymentHandler.Reconciliation)
+	router.With(auth.Require(auth.PaymentsRead)).Get("/{id}", paymentHandler.GetByID)
+	router.With(auth.Require(auth.PaymentsAdjust)).Post("/{id}/refund", paymentHandler.Refund)
+	router.With(auth.Require(auth.PaymentsAdjust)).Post("/{id}/correction", paymentHandler.Correct)
+}
+
+// Calendar feeds of the school, the teachers and the students share one handler type
+func newCalendarHandler(store *storage.Store) *handler.CalendarHandler {
+	return &handler.CalendarHandler{
+		Schedules:     store.Schedules,
+		Cancellations: store.Cancellations,
+		Students:      store.Students,
+		Teachers:      store.Teachers,
+		Rooms:         store.Rooms,
+	}
+}
+
+// Links of calendar feeds check the students and teachers they are made for
+func newFeedHandler(store *storage.Store) *handler.FeedHandler {
+	return &handler.FeedHandler{
+		Feeds:    store.CalendarFeeds,
+		Users:    store.Users,
+		Teachers: store.Teachers,
+		Students: store.Students,
+	}
 }
 
-func loadScheduleRoutes(router chi.Router) {
-	scheduleHandler := &handler.ScheduleHandler{}
-	router.Post("/", scheduleHandler.Create)
-	router.Get("/", scheduleHandler.List)
-	router.Get("/{id}", scheduleHandler.GetByID)
-	router.Put("/{id}", scheduleHandler.UpdateByID)
-	router.Delete("/{id}", scheduleHandler.DeleteByID)
+// Exports of students, schedules and attendance share one handler type
+func newExportHandler(store *storage.Store) *handler.ExportHandler {
+	return &handler.ExportHandler{
+		Students:  store.Students,
+		Schedules: store.Schedules,
+		Teachers:  store.Teachers,
+		Rooms:     store.Rooms,
+	}
 }
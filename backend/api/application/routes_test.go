@@ -0,0 +1,22 @@
+package application
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+)
+
+func TestHealth(t *testing.T) {
+	router := newTestRouter(t)
+
+	recorder := doRequest(t, router, http.MethodGet, "/health", nil)
+	expectStatus(t, recorder, http.StatusOK)
+}
+
+func TestUnknownRoute(t *testing.T) {
+	router := newTestRouter(t)
+
+	recorder := doRequest(t, router, http.MethodGet, "/unknown", nil)
+	expectError(t, recorder, errorHandling.NotFound)
+}
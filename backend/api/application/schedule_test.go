@@ -0,0 +1,211 @@
+package application
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+// Create a schedule through the API and return it
+func createSchedule(t *testing.T, router http.Handler, date string, classes ...map[string]interface{}) models.Schedule {
+	t.Helper()
+
+	recorder := doRequest(t, router, http.MethodPost, "/schedule", map[string]interface{}{"date": date, "classes": classes})
+	expectStatus(t, recorder, http.StatusCreated)
+	var schedule models.Schedule
+	decodeResponse(t, recorder, &schedule)
+	return schedule
+}
+
+// Get a schedule through the API
+func getSchedule(t *testing.T, router http.Handler, id string) models.Schedule {
+	t.Helper()
+
+	recorder := doRequest(t, router, http.MethodGet, "/schedule/"+id, nil)
+	expectStatus(t, recorder, http.StatusOK)
+	var schedule models.Schedule
+	decodeResponse(t, recorder, &schedule)
+	return schedule
+}
+
+func class(studentId string, time string, classType string) map[string]interface{} {
+	return map[string]interface{}{"studentId": studentId, "time": time, "type": classType, "attendance": nil}
+}
+
+func TestScheduleCRUD(t *testing.T) {
+	router := newTestRouter(t)
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+
+	schedule := createSchedule(t, router, "2024-03-01T00:00:00Z", class(alice.Id.Hex(), "14:00", "both"))
+	if schedule.Id.IsZero() {
+		t.Fatalf("expected created schedule to have id")
+	}
+	createSchedule(t, router, "2024-03-02T00:00:00Z", class(alice.Id.Hex(), "10:00", "drawing"))
+
+	// List
+	recorder := doRequest(t, router, http.MethodGet, "/schedule", nil)
+	expectStatus(t, recorder, http.StatusOK)
+	var schedules []models.Schedule
+	decodeResponse(t, recorder, &schedules)
+	if len(schedules) != 2 {
+		t.Fatalf("expected 2 schedules, got %v", len(schedules))
+	}
+
+	// Get
+	found := getSchedule(t, router, schedule.Id.Hex())
+	if found.Date.Time().UTC().Format("2006-01-02") != "2024-03-01" || len(found.Classes) != 1 {
+		t.Fatalf("unexpected schedule %+v", found)
+	}
+
+	// Delete
+	recorder = doRequest(t, router, http.MethodDelete, "/schedule/"+schedule.Id.Hex(), nil)
+	expectStatus(t, recorder, http.StatusOK)
+	recorder = doRequest(t, router, http.MethodGet, "/schedule/"+schedule.Id.Hex(), nil)
+	expectError(t, recorder, errorHandling.NotFound)
+}
+
+func TestScheduleUpdateAppendsOrReplacesClass(t *testing.T) {
+	router := newTestRouter(t)
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+	bob := createStudent(t, router, "Bob Smith", "+123456789013")
+	schedule := createSchedule(t, router, "2024-03-01T00:00:00Z", class(alice.Id.Hex(), "14:00", "both"))
+
+	// A class of another student is appended
+	recorder := doRequest(t, router, http.MethodPut, "/schedule/"+schedule.Id.Hex(), class(bob.Id.Hex(), "16:00", "drawing"))
+	expectStatus(t, recorder, http.StatusOK)
+	updated := getSchedule(t, router, schedule.Id.Hex())
+	if len(updated.Classes) != 2 || updated.Classes[1].StudentId != bob.Id || updated.Classes[1].Time != "16:00" {
+		t.Fatalf("expected class of bob to be appended, got %+v", updated.Classes)
+	}
+
+	// A class of the same student replaces the existing one
+	recorder = doRequest(t, router, http.MethodPut, "/schedule/"+schedule.Id.Hex(), class(alice.Id.Hex(), "15:00", "painting"))
+	expectStatus(t, recorder, http.StatusOK)
+	updated = getSchedule(t, router, schedule.Id.Hex())
+	if len(updated.Classes) != 2 {
+		t.Fatalf("expected class of alice to be replaced, got %+v", updated.Classes)
+	}
+	aliceClass := updated.Classes[0]
+	if aliceClass.StudentId != alice.Id || aliceClass.Time != "15:00" || aliceClass.Type != "painting" {
+		t.Fatalf("unexpected replaced class %+v", aliceClass)
+	}
+}
+
+func TestScheduleErrors(t *testing.T) {
+	router := newTestRouter(t)
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+	schedule := createSchedule(t, router, "2024-03-01T00:00:00Z", class(alice.Id.Hex(), "14:00", "both"))
+	missingId := "000000000000000000000000"
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   interface{}
+		code   errorHandling.Code
+		field  string
+	}{
+		{"create with invalid JSON", http.MethodPost, "/schedule", "not json", errorHandling.InvalidJSON, ""},
+		{"create without classes", http.MethodPost, "/schedule", map[string]interface{}{"date": "2024-03-05T00:00:00Z", "classes": []interface{}{}}, errorHandling.ValidationFailed, "classes"},
+		{"create without date", http.MethodPost, "/schedule", map[string]interface{}{"classes": []interface{}{class(alice.Id.Hex(), "14:00", "both")}}, errorHandling.ValidationFailed, "date"},
+		{"create with invalid class time", http.MethodPost, "/schedule", map[string]interface{}{"date": "2024-03-05T00:00:00Z", "classes": []interface{}{class(alice.Id.Hex(), "21:30", "both")}}, errorHandling.ValidationFailed, "classes[0].time"},
+		{"create with invalid class type", http.MethodPost, "/schedule", map[string]interface{}{"date": "2024-03-05T00:00:00Z", "classes": []interface{}{class(alice.Id.Hex(), "14:00", "sculpture")}}, errorHandling.ValidationFailed, "classes[0].type"},
+		{"create for existing date", http.MethodPost, "/schedule", map[string]interface{}{"date": "2024-03-01T00:00:00Z", "classes": []interface{}{class(alice.Id.Hex(), "10:00", "both")}}, errorHandling.Duplicate, "date"},
+		{"create for existing date at another time", http.MethodPost, "/schedule", map[string]interface{}{"date": "2024-03-01T09:00:00Z", "classes": []interface{}{class(alice.Id.Hex(), "10:00", "both")}}, errorHandling.Duplicate, "date"},
+		{"get with bad ObjectId", http.MethodGet, "/schedule/bad", nil, errorHandling.InvalidId, "id"},
+		{"get missing schedule", http.MethodGet, "/schedule/" + missingId, nil, errorHandling.NotFound, ""},
+		{"update missing schedule", http.MethodPut, "/schedule/" + missingId, class(alice.Id.Hex(), "14:00", "both"), errorHandling.NotFound, ""},
+		{"update with invalid JSON", http.MethodPut, "/schedule/" + schedule.Id.Hex(), "{", errorHandling.InvalidJSON, ""},
+		{"create with attendance", http.MethodPost, "/schedule", map[string]interface{}{"date": "2024-03-05T00:00:00Z", "classes": []interface{}{map[string]interface{}{"studentId": alice.Id.Hex(), "time": "14:00", "type": "both", "attendance": true}}}, errorHandling.ValidationFailed, "classes[0].attendance"},
+		{"update attendance", http.MethodPut, "/schedule/" + schedule.Id.Hex(), map[string]interface{}{"studentId": alice.Id.Hex(), "time": "14:00", "type": "both", "attendance": true}, errorHandling.ValidationFailed, "attendance"},
+		{"update without student", http.MethodPut, "/schedule/" + schedule.Id.Hex(), map[string]interface{}{"time": "14:00", "type": "both"}, errorHandling.ValidationFailed, "studentId"},
+		{"delete with bad ObjectId", http.MethodDelete, "/schedule/bad", nil, errorHandling.InvalidId, "id"},
+		{"delete missing schedule", http.MethodDelete, "/schedule/" + missingId, nil, errorHandling.NotFound, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := doRequest(t, router, test.method, test.path, test.body)
+			response := expectError(t, recorder, test.code)
+			if test.field == "" {
+				return
+			}
+			for _, detail := range response.Details {
+				if detail.Field == test.field {
+					return
+				}
+			}
+			t.Fatalf("expected detail for field %v, got %+v", test.field, response.Details)
+		})
+	}
+}
+
+func TestScheduleByDate(t *testing.T) {
+	router := newTestRouter(t)
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+	createSchedule(t, router, "2024-03-03T00:00:00Z", class(alice.Id.Hex(), "10:00", "drawing"))
+	march1 := createSchedule(t, router, "2024-03-01T00:00:00Z", class(alice.Id.Hex(), "10:00", "drawing"))
+	createSchedule(t, router, "2024-03-02T00:00:00Z", class(alice.Id.Hex(), "10:00", "drawing"))
+
+	tests := []struct {
+		query string
+		dates []string
+	}{
+		{"", []string{"2024-03-01", "2024-03-02", "2024-03-03"}},
+		{"?date=2024-03-02", []string{"2024-03-02"}},
+		{"?date=2024-03-04", []string{}},
+		{"?from=2024-03-02", []string{"2024-03-02", "2024-03-03"}},
+		{"?from=2024-03-01&to=2024-03-02", []string{"2024-03-01", "2024-03-02"}},
+		{"?to=2024-03-01T23:30:00-05:00", []string{"2024-03-01"}},
+	}
+	for _, test := range tests {
+		recorder := doRequest(t, router, http.MethodGet, "/schedule"+test.query, nil)
+		expectStatus(t, recorder, http.StatusOK)
+		var schedules []models.Schedule
+		decodeResponse(t, recorder, &schedules)
+		dates := []string{}
+		for _, schedule := range schedules {
+			dates = append(dates, schedule.Date.Time().UTC().Format("2006-01-02"))
+		}
+		if !slices.Equal(dates, test.dates) {
+			t.Errorf("GET /schedule%v: expected %v, got %v", test.query, test.dates, dates)
+		}
+	}
+
+	// Schedules are saved at midnight UTC of their day
+	march4 := createSchedule(t, router, "2024-03-04T09:30:00Z", class(alice.Id.Hex(), "10:00", "drawing"))
+	if !march4.Date.Time().Equal(time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the schedule at midnight, got %v", march4.Date.Time())
+	}
+
+	// The day of a time is the date in its own offset
+	recorder := doRequest(t, router, http.MethodGet, "/schedule/by-date/2024-03-01T01:00:00+02:00", nil)
+	expectStatus(t, recorder, http.StatusOK)
+	var found models.Schedule
+	decodeResponse(t, recorder, &found)
+	if found.Id != march1.Id {
+		t.Fatalf("expected the schedule of March 1, got %+v", found)
+	}
+
+	// A schedule created with an offset is found by the date the client sent
+	march6 := createSchedule(t, router, "2024-03-06T00:00:00+02:00", class(alice.Id.Hex(), "10:00", "drawing"))
+	if !march6.Date.Time().Equal(time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the schedule on March 6, got %v", march6.Date.Time())
+	}
+	recorder = doRequest(t, router, http.MethodGet, "/schedule/by-date/2024-03-06T00:00:00+02:00", nil)
+	expectStatus(t, recorder, http.StatusOK)
+	decodeResponse(t, recorder, &found)
+	if found.Id != march6.Id {
+		t.Fatalf("expected the schedule of March 6, got %+v", found)
+	}
+
+	expectError(t, doRequest(t, router, http.MethodGet, "/schedule/by-date/2024-03-05", nil), errorHandling.NotFound)
+	expectError(t, doRequest(t, router, http.MethodGet, "/schedule/by-date/March", nil), errorHandling.ValidationFailed)
+	expectError(t, doRequest(t, router, http.MethodPost, "/schedule", map[string]interface{}{"date": "March", "classes": []interface{}{class(alice.Id.Hex(), "10:00", "both")}}), errorHandling.ValidationFailed)
+	expectError(t, doRequest(t, router, http.MethodGet, "/schedule?date=2024-03-01&from=2024-03-01", nil), errorHandling.ValidationFailed)
+	expectError(t, doRequest(t, router, http.MethodGet, "/schedule?from=2024-03-03&to=2024-03-01", nil), errorHandling.ValidationFailed)
+}
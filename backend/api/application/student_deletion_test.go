@@ -0,0 +1,136 @@
+package application
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DanVerh/artschool-admin/backend/api/config"
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+)
+
+// Build the router of the "admin" user with the given student delete policy
+func newPolicyRouter(t *testing.T, policy string) http.Handler {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.StudentDeletePolicy = policy
+	_, router := newConfigServer(t, cfg)
+	return withToken(router, login(t, router, "admin", testPassword))
+}
+
+func TestScheduleMissingStudent(t *testing.T) {
+	router := newTestRouter(t)
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+	schedule := createSchedule(t, router, "2024-03-01T00:00:00Z", class(alice.Id.Hex(), "10:00", "drawing"))
+	missingId := "000000000000000000000001"
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   interface{}
+		field  string
+	}{
+		{"create", http.MethodPost, "/schedule", map[string]interface{}{"date": "2024-03-02T00:00:00Z", "classes": []interface{}{class(missingId, "10:00", "drawing")}}, "classes[0].studentId"},
+		{"put", http.MethodPut, "/schedule/" + schedule.Id.Hex(), class(missingId, "11:00", "drawing"), "studentId"},
+		{"add class", http.MethodPost, "/schedule/" + schedule.Id.Hex() + "/classes", class(missingId, "12:00", "drawing"), "studentId"},
+		{"template", http.MethodPost, "/templates", map[string]interface{}{"studentId": missingId, "weekday": "monday", "time": "10:00", "type": "drawing", "startDate": "2024-03-04T00:00:00Z"}, "studentId"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := expectError(t, doRequest(t, router, test.method, test.path, test.body), errorHandling.ValidationFailed)
+			if len(response.Details) != 1 || response.Details[0].Field != test.field {
+				t.Fatalf("expected detail for field %v, got %+v", test.field, response.Details)
+			}
+		})
+	}
+}
+
+func TestStudentDeletePolicies(t *testing.T) {
+	future := time.Now().UTC().AddDate(0, 0, 7).Format("2006-01-02") + "T00:00:00Z"
+
+	tests := []struct {
+		policy        string
+		status        int
+		futureClasses int
+	}{
+		{config.RejectStudentDelete, http.StatusConflict, 1},
+		{config.CascadeStudentDelete, http.StatusOK, 0},
+		{config.KeepStudentDelete, http.StatusOK, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.policy, func(t *testing.T) {
+			router := newPolicyRouter(t, test.policy)
+			alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+			bob := createStudent(t, router, "Bob Smith", "+123456789013")
+			past := createSchedule(t, router, "2024-03-01T00:00:00Z", class(alice.Id.Hex(), "10:00", "drawing"))
+			next := createSchedule(t, router, future, class(alice.Id.Hex(), "10:00", "drawing"), class(bob.Id.Hex(), "11:00", "drawing"))
+
+			recorder := doRequest(t, router, http.MethodDelete, "/students/"+alice.Id.Hex(), nil)
+			if test.status == http.StatusConflict {
+				response := expectError(t, recorder, errorHandling.StudentHasClasses)
+				if len(response.Details) != 1 || response.Details[0].Field != "classes" {
+					t.Fatalf("expected the future class in details, got %+v", response.Details)
+				}
+				if found := getSchedule(t, router, past.Id.Hex()); found.Classes[0].StudentDeleted {
+					t.Fatalf("expected past class to stay unchanged when deletion is rejected")
+				}
+				return
+			}
+			expectStatus(t, recorder, test.status)
+
+			// Past classes stay as history
+			found := getSchedule(t, router, past.Id.Hex())
+			if len(found.Classes) != 1 || !found.Classes[0].StudentDeleted {
+				t.Fatalf("expected past class marked as deleted, got %+v", found.Classes)
+			}
+
+			found = getSchedule(t, router, next.Id.Hex())
+			if len(found.Classes) != 1+test.futureClasses {
+				t.Fatalf("expected %d future classes of Alice, got %+v", test.futureClasses, found.Classes)
+			}
+			for _, class := range found.Classes {
+				if class.StudentDeleted != (class.StudentId == alice.Id) {
+					t.Fatalf("unexpected marker on class %+v", class)
+				}
+			}
+
+			// The marked class can still be moved, but the student can not be booked again
+			recorder = doRequest(t, router, http.MethodPatch, "/schedule/"+past.Id.Hex()+"/classes/"+past.Classes[0].Id.Hex(), map[string]interface{}{"time": "12:00"})
+			expectStatus(t, recorder, http.StatusOK)
+			recorder = doRequest(t, router, http.MethodPost, "/schedule/"+past.Id.Hex()+"/classes", map[string]interface{}{"studentId": alice.Id.Hex(), "time": "14:00", "type": "drawing", "studentDeleted": true})
+			expectError(t, recorder, errorHandling.ValidationFailed)
+
+			// Restoring removes the marks from the classes left
+			expectStatus(t, doRequest(t, router, http.MethodPost, "/students/"+alice.Id.Hex()+"/restore", nil), http.StatusOK)
+			if found := getSchedule(t, router, past.Id.Hex()); found.Classes[0].StudentDeleted {
+				t.Fatalf("expected past class unmarked after restore, got %+v", found.Classes)
+			}
+		})
+	}
+}
+
+// A class of today with marked attendance is history, whatever the policy
+func TestStudentDeleteKeepsMarkedClasses(t *testing.T) {
+	today := time.Now().UTC().Format("2006-01-02") + "T00:00:00Z"
+
+	for _, policy := range []string{config.RejectStudentDelete, config.CascadeStudentDelete, config.KeepStudentDelete} {
+		t.Run(policy, func(t *testing.T) {
+			router := newPolicyRouter(t, policy)
+			alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+			pack := sellSubscription(t, router, alice, 8)
+			schedule := createSchedule(t, router, today, class(alice.Id.Hex(), "10:00", "drawing"))
+			markAttendance(t, router, schedule, alice, true)
+
+			expectStatus(t, doRequest(t, router, http.MethodDelete, "/students/"+alice.Id.Hex(), nil), http.StatusOK)
+			found := getSchedule(t, router, schedule.Id.Hex())
+			if len(found.Classes) != 1 || !found.Classes[0].StudentDeleted || !isTrue(found.Classes[0].Attendence) {
+				t.Fatalf("expected the visited class kept and marked as deleted, got %+v", found.Classes)
+			}
+			if found.Classes[0].SubscriptionId == nil || *found.Classes[0].SubscriptionId != pack.Id {
+				t.Fatalf("expected the visit to stay on its pack, got %+v", found.Classes[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package application
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+// Create a student through the API and return it
+func createStudent(t *testing.T, router http.Handler, fullname string, phone string) models.Student {
+	t.Helper()
+
+	recorder := doRequest(t, router, http.MethodPost, "/students", map[string]string{"fullname": fullname, "phone": phone})
+	expectStatus(t, recorder, http.StatusCreated)
+	var student models.Student
+	decodeResponse(t, recorder, &student)
+	return student
+}
+
+func TestStudentsCRUD(t *testing.T) {
+	router := newTestRouter(t)
+
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+	if alice.Id.IsZero() {
+		t.Fatalf("expected created student to have id")
+	}
+	if alice.Subscription != nil || alice.StartDate != nil || alice.LastDate != nil || alice.Comments != nil {
+		t.Fatalf("expected optional fields of a new student to be null, got %+v", alice)
+	}
+	createStudent(t, router, "Bob Smith", "+123456789013")
+
+	// List
+	recorder := doRequest(t, router, http.MethodGet, "/students", nil)
+	expectStatus(t, recorder, http.StatusOK)
+	page := models.StudentPage{}
+	decodeResponse(t, recorder, &page)
+	if len(page.Students) != 2 || page.Total != 2 || page.Next != "" {
+		t.Fatalf("expected 2 students on one page, got %+v", page)
+	}
+
+	// Update
+	recorder = doRequest(t, router, http.MethodPut, "/students/"+alice.Id.Hex(), map[string]interface{}{
+		"startDate": "2024-03-01T00:00:00Z",
+		"comments":  "Great progress in drawing",
+	})
+	expectStatus(t, recorder, http.StatusOK)
+
+	// Get
+	recorder = doRequest(t, router, http.MethodGet, "/students/"+alice.Id.Hex(), nil)
+	expectStatus(t, recorder, http.StatusOK)
+	var updated models.Student
+	decodeResponse(t, recorder, &updated)
+	if updated.Id != alice.Id || updated.Fullname != "Alice Johnson" {
+		t.Fatalf("unexpected student %+v", updated)
+	}
+	if updated.StartDate == nil || updated.StartDate.Format("2006-01-02") != "2024-03-01" {
+		t.Fatalf("expected start date 2024-03-01, got %v", updated.StartDate)
+	}
+	if updated.Comments == nil || *updated.Comments != "Great progress in drawing" {
+		t.Fatalf("expected comments to be updated, got %v", updated.Comments)
+	}
+
+	// Delete
+	recorder = doRequest(t, router, http.MethodDelete, "/students/"+alice.Id.Hex(), nil)
+	expectStatus(t, recorder, http.StatusOK)
+	recorder = doRequest(t, router, http.MethodGet, "/students/"+alice.Id.Hex(), nil)
+	expectError(t, recorder, errorHandling.NotFound)
+}
+
+func TestStudentsErrors(t *testing.T) {
+	router := newTestRouter(t)
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+	missingId := "000000000000000000000000"
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   interface{}
+		code   errorHandling.Code
+		field  string
+	}{
+		{"create with invalid JSON", http.MethodPost, "/students", "{", errorHandling.InvalidJSON, ""},
+		{"create without fullname", http.MethodPost, "/students", map[string]string{"phone": "+123456789099"}, errorHandling.ValidationFailed, "fullname"},
+		{"create without phone", http.MethodPost, "/students", map[string]string{"fullname": "Bob"}, errorHandling.ValidationFailed, "phone"},
+		{"create with invalid phone", http.MethodPost, "/students", map[string]string{"fullname": "Bob", "phone": "12345"}, errorHandling.ValidationFailed, "phone"},
+		{"create with duplicate phone", http.MethodPost, "/students", map[string]string{"fullname": "Bob", "phone": "+123456789012"}, errorHandling.Duplicate, "phone"},
+		{"get with bad ObjectId", http.MethodGet, "/students/not-an-id", nil, errorHandling.InvalidId, "id"},
+		{"get missing student", http.MethodGet, "/students/" + missingId, nil, errorHandling.NotFound, ""},
+		{"update with bad ObjectId", http.MethodPut, "/students/123", map[string]string{"fullname": "Bob"}, errorHandling.InvalidId, "id"},
+		{"update missing student", http.MethodPut, "/students/" + missingId, map[string]string{"fullname": "Bob"}, errorHandling.NotFound, ""},
+		{"update unknown field", http.MethodPut, "/students/" + alice.Id.Hex(), map[string]string{"age": "12"}, errorHandling.ValidationFailed, "age"},
+		{"update subscription", http.MethodPut, "/students/" + alice.Id.Hex(), map[string]int{"subscription": 8}, errorHandling.ValidationFailed, "subscription"},
+		{"update with invalid date", http.MethodPut, "/students/" + alice.Id.Hex(), map[string]string{"lastDate": "yesterday"}, errorHandling.ValidationFailed, "lastDate"},
+		{"update with empty body", http.MethodPut, "/students/" + alice.Id.Hex(), map[string]string{}, errorHandling.ValidationFailed, ""},
+		{"list with unknown sort", http.MethodGet, "/students?sort=phone", nil, errorHandling.ValidationFailed, "sort"},
+		{"list with bad limit", http.MethodGet, "/students?limit=0", nil, errorHandling.ValidationFailed, "limit"},
+		{"list with bad lastDate filter", http.MethodGet, "/students?lastDateBefore=soon", nil, errorHandling.ValidationFailed, "lastDateBefore"},
+		{"delete with bad ObjectId", http.MethodDelete, "/students/xyz", nil, errorHandling.InvalidId, "id"},
+		{"delete missing student", http.MethodDelete, "/students/" + missingId, nil, errorHandling.NotFound, ""},
+		{"unsupported method", http.MethodPatch, "/students/" + alice.Id.Hex(), nil, errorHandling.MethodNotAllowed, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := doRequest(t, router, test.method, test.path, test.body)
+			response := expectError(t, recorder, test.code)
+			if test.field == "" {
+				return
+			}
+			for _, detail := range response.Details {
+				if detail.Field == test.field {
+					return
+				}
+			}
+			t.Fatalf("expected detail for field %v, got %+v", test.field, response.Details)
+		})
+	}
+}
+
+func TestStudentsUpdateDuplicatePhone(t *testing.T) {
+	router := newTestRouter(t)
+	createStudent(t, router, "Alice Johnson", "+123456789012")
+	bob := createStudent(t, router, "Bob Smith", "+123456789013")
+
+	recorder := doRequest(t, router, http.MethodPut, "/students/"+bob.Id.Hex(), map[string]string{"phone": "+123456789012"})
+	expectError(t, recorder, errorHandling.Duplicate)
+}
+
+func listStudents(t *testing.T, router http.Handler, query string) models.StudentPage {
+	t.Helper()
+
+	recorder := doRequest(t, router, http.MethodGet, "/students"+query, nil)
+	expectStatus(t, recorder, http.StatusOK)
+	page := models.StudentPage{}
+	decodeResponse(t, recorder, &page)
+	return page
+}
+
+func studentNames(students []models.Student) []string {
+	names := []string{}
+	for _, student := range students {
+		names = append(names, student.Fullname)
+	}
+	return names
+}
+
+func TestStudentsListQuery(t *testing.T) {
+	router := newTestRouter(t)
+	createStudent(t, router, "Carol White", "+123456789014")
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+	bob := createStudent(t, router, "Bob Smith", "+123456789013")
+
+	expectStatus(t, doRequest(t, router, http.MethodPut, "/students/"+alice.Id.Hex(), map[string]interface{}{
+		"lastDate": "2024-03-01T00:00:00Z", "comments": "Prefers Watercolor",
+	}), http.StatusOK)
+	sellSubscription(t, router, alice, 8)
+	expectStatus(t, doRequest(t, router, http.MethodPut, "/students/"+bob.Id.Hex(), map[string]interface{}{
+		"lastDate": "2024-05-01T00:00:00Z",
+	}), http.StatusOK)
+	sellSubscription(t, router, bob, 4)
+
+	tests := []struct {
+		query string
+		names []string
+	}{
+		{"", []string{"Carol White", "Alice Johnson", "Bob Smith"}},
+		{"?sort=fullname", []string{"Alice Johnson", "Bob Smith", "Carol White"}},
+		{"?sort=-subscription", []string{"Alice Johnson", "Bob Smith", "Carol White"}},
+		{"?sort=lastDate", []string{"Carol White", "Alice Johnson", "Bob Smith"}},
+		{"?subscription=null", []string{"Carol White"}},
+		{"?subscription=4", []string{"Bob Smith"}},
+		{"?lastDateBefore=2024-04-01", []string{"Alice Johnson"}},
+		{"?comments=watercolor", []string{"Alice Johnson"}},
+	}
+	for _, test := range tests {
+		page := listStudents(t, router, test.query)
+		if names := studentNames(page.Students); !slices.Equal(names, test.names) {
+			t.Errorf("GET /students%v: expected %v, got %v", test.query, test.names, names)
+		}
+	}
+
+	// Pages follow the next links until the last page
+	page := listStudents(t, router, "?sort=fullname&limit=2")
+	if page.Total != 3 || len(page.Students) != 2 || page.Next != "/students?limit=2&offset=2&sort=fullname" {
+		t.Fatalf("unexpected first page %+v", page)
+	}
+	page = listStudents(t, router, strings.TrimPrefix(page.Next, "/students"))
+	if names := studentNames(page.Students); !slices.Equal(names, []string{"Carol White"}) || page.Next != "" {
+		t.Fatalf("unexpected last page %+v", page)
+	}
+}
+
+func TestStudentsArchive(t *testing.T) {
+	router := newTestRouter(t)
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+	createStudent(t, router, "Bob Smith", "+123456789013")
+	studentPath := "/students/" + alice.Id.Hex()
+
+	expectStatus(t, doRequest(t, router, http.MethodDelete, studentPath, nil), http.StatusOK)
+
+	// Archived students are hidden unless includeArchived is set
+	if names := studentNames(listStudents(t, router, "").Students); !slices.Equal(names, []string{"Bob Smith"}) {
+		t.Fatalf("expected only Bob in the default list, got %v", names)
+	}
+	page := listStudents(t, router, "?includeArchived=true")
+	if page.Total != 2 || page.Students[0].DeletedAt == nil || page.Students[1].DeletedAt != nil {
+		t.Fatalf("expected archived Alice and active Bob, got %+v", page.Students)
+	}
+	expectError(t, doRequest(t, router, http.MethodGet, studentPath, nil), errorHandling.NotFound)
+	expectStatus(t, doRequest(t, router, http.MethodGet, studentPath+"?includeArchived=true", nil), http.StatusOK)
+
+	// Archived students can not be changed, booked or archived again
+	expectError(t, doRequest(t, router, http.MethodPut, studentPath, map[string]string{"comments": "Back soon"}), errorHandling.StudentArchived)
+	expectError(t, doRequest(t, router, http.MethodDelete, studentPath, nil), errorHandling.StudentArchived)
+	expectError(t, doRequest(t, router, http.MethodPost, "/schedule", map[string]interface{}{"date": "2024-03-01T00:00:00Z", "classes": []interface{}{class(alice.Id.Hex(), "10:00", "drawing")}}), errorHandling.ValidationFailed)
+
+	// Restore
+	recorder := doRequest(t, router, http.MethodPost, studentPath+"/restore", nil)
+	expectStatus(t, recorder, http.StatusOK)
+	var restored models.Student
+	decodeResponse(t, recorder, &restored)
+	if restored.Id != alice.Id || restored.DeletedAt != nil {
+		t.Fatalf("expected restored Alice, got %+v", restored)
+	}
+	expectError(t, doRequest(t, router, http.MethodPost, studentPath+"/restore", nil), errorHandling.ValidationFailed)
+
+	// Only archived students can be purged
+	expectError(t, doRequest(t, router, http.MethodPost, studentPath+"/purge", nil), errorHandling.ValidationFailed)
+	expectStatus(t, doRequest(t, router, http.MethodDelete, studentPath, nil), http.StatusOK)
+	expectStatus(t, doRequest(t, router, http.MethodPost, studentPath+"/purge", nil), http.StatusNoContent)
+	expectError(t, doRequest(t, router, http.MethodGet, studentPath+"?includeArchived=true", nil), errorHandling.NotFound)
+}
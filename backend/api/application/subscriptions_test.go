@@ -0,0 +1,184 @@
+package application
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/handlers"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+// Sell a pack of size classes to the student through the API
+func sellSubscription(t *testing.T, router http.Handler, student models.Student, size int) models.Subscription {
+	t.Helper()
+
+	recorder := doRequest(t, router, http.MethodPost, "/students/"+student.Id.Hex()+"/subscriptions", map[string]int{"size": size, "price": size * 25000})
+	expectStatus(t, recorder, http.StatusCreated)
+	var subscription models.Subscription
+	decodeResponse(t, recorder, &subscription)
+	return subscription
+}
+
+func getStudent(t *testing.T, router http.Handler, id string) models.Student {
+	t.Helper()
+
+	recorder := doRequest(t, router, http.MethodGet, "/students/"+id, nil)
+	expectStatus(t, recorder, http.StatusOK)
+	var student models.Student
+	decodeResponse(t, recorder, &student)
+	return student
+}
+
+func TestSubscriptionSaleAndRenewal(t *testing.T) {
+	router := newTestRouter(t)
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+	studentPath := "/students/" + alice.Id.Hex()
+
+	first := sellSubscription(t, router, alice, 1)
+	if first.Size != 1 || first.Price != 25000 || first.Consumed != 0 || first.RenewedFrom != nil {
+		t.Fatalf("unexpected pack %+v", first)
+	}
+	if validity := first.ExpiresAt.Sub(first.PurchaseDate); validity != 60*24*time.Hour {
+		t.Fatalf("expected the default validity of 60 days, got %v", validity)
+	}
+	if student := getStudent(t, router, alice.Id.Hex()); student.Subscription == nil || *student.Subscription != 1 {
+		t.Fatalf("expected 1 class left, got %v", student.Subscription)
+	}
+
+	// The renewal waits until the first pack is used up
+	recorder := doRequest(t, router, http.MethodPost, studentPath+"/subscriptions/"+first.Id.Hex()+"/renew", nil)
+	expectStatus(t, recorder, http.StatusCreated)
+	var renewal models.Subscription
+	decodeResponse(t, recorder, &renewal)
+	if renewal.Size != 1 || renewal.Price != 25000 || renewal.RenewedFrom == nil || *renewal.RenewedFrom != first.Id {
+		t.Fatalf("unexpected renewal %+v", renewal)
+	}
+
+	schedule := createSchedule(t, router, time.Now().UTC().Format(time.DateOnly)+"T00:00:00Z", class(alice.Id.Hex(), "14:00", "both"))
+	student := markAttendance(t, router, schedule, alice, true)
+	if student.Subscription == nil || *student.Subscription != 1 {
+		t.Fatalf("expected the renewal to become active, got %v", student.Subscription)
+	}
+
+	recorder = doRequest(t, router, http.MethodGet, studentPath+"/subscriptions", nil)
+	expectStatus(t, recorder, http.StatusOK)
+	var subscriptions []models.Subscription
+	decodeResponse(t, recorder, &subscriptions)
+	if len(subscriptions) != 2 || subscriptions[0].Id != first.Id || subscriptions[0].Consumed != 1 || subscriptions[1].Consumed != 0 {
+		t.Fatalf("unexpected packs %+v", subscriptions)
+	}
+
+	// Un-marking gives the class back to the first pack
+	student = markAttendance(t, router, schedule, alice, nil)
+	if student.Subscription == nil || *student.Subscription != 1 {
+		t.Fatalf("expected 1 class left in the first pack, got %v", student.Subscription)
+	}
+}
+
+func TestExpiredSubscription(t *testing.T) {
+	router := newTestRouter(t)
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+
+	recorder := doRequest(t, router, http.MethodPost, "/students/"+alice.Id.Hex()+"/subscriptions", map[string]interface{}{
+		"size": 4, "price": 100000, "purchaseDate": "2024-01-01T00:00:00Z", "expiresAt": "2024-03-01T00:00:00Z",
+	})
+	expectStatus(t, recorder, http.StatusCreated)
+	if student := getStudent(t, router, alice.Id.Hex()); student.Subscription != nil {
+		t.Fatalf("expected no active pack, got %v", *student.Subscription)
+	}
+
+	// A class before the expiry still uses the pack
+	february := createSchedule(t, router, "2024-02-20T00:00:00Z", class(alice.Id.Hex(), "14:00", "both"))
+	markAttendance(t, router, february, alice, true)
+	march := createSchedule(t, router, "2024-03-05T00:00:00Z", class(alice.Id.Hex(), "14:00", "both"))
+	recorder = doRequest(t, router, http.MethodPost, "/schedule/"+march.Id.Hex()+"/classes/"+alice.Id.Hex()+"/attendance", map[string]bool{"attendance": true})
+	expectError(t, recorder, errorHandling.NoClassesLeft)
+}
+
+func TestSubscriptionExpiry(t *testing.T) {
+	store, server := newTestServer(t)
+	router := withToken(server, login(t, server, "admin", testPassword))
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+	bob := createStudent(t, router, "Bob Smith", "+123456789013")
+	pack := sellSubscription(t, router, alice, 4)
+	sellSubscription(t, router, bob, 4)
+
+	// The pack runs out of time without a sale or a visit
+	now := time.Now()
+	pack.ExpiresAt = now.Add(-time.Hour)
+	err := store.Subscriptions.Update(context.Background(), &pack)
+	if err != nil {
+		t.Fatalf("failed to expire the pack: %v", err)
+	}
+
+	expiry := &handler.SubscriptionExpiry{
+		Students:      store.Students,
+		Subscriptions: store.Subscriptions,
+		Transactions:  store.Transactions,
+		Now:           time.Now,
+	}
+	synced, err := expiry.Run(context.Background(), time.Time{}, now)
+	if err != nil || synced != 1 {
+		t.Fatalf("expected 1 student synced, got %v, %v", synced, err)
+	}
+	if student := getStudent(t, router, alice.Id.Hex()); student.Subscription != nil {
+		t.Fatalf("expected no active pack, got %v", *student.Subscription)
+	}
+	if student := getStudent(t, router, bob.Id.Hex()); student.Subscription == nil || *student.Subscription != 4 {
+		t.Fatalf("expected 4 classes left, got %v", student.Subscription)
+	}
+	if names := studentNames(listStudents(t, router, "?subscription=null").Students); !slices.Equal(names, []string{"Alice Johnson"}) {
+		t.Fatalf("expected the expired student in the list without subscription, got %v", names)
+	}
+
+	// Later runs only look at the packs that expired since
+	synced, err = expiry.Run(context.Background(), now, now.Add(time.Hour))
+	if err != nil || synced != 0 {
+		t.Fatalf("expected no student synced, got %v, %v", synced, err)
+	}
+}
+
+func TestSubscriptionErrors(t *testing.T) {
+	router := newTestRouter(t)
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+	bob := createStudent(t, router, "Bob Smith", "+123456789013")
+	bobPack := sellSubscription(t, router, bob, 4)
+	path := "/students/" + alice.Id.Hex() + "/subscriptions"
+	missingId := "000000000000000000000000"
+
+	tests := []struct {
+		name  string
+		path  string
+		body  interface{}
+		code  errorHandling.Code
+		field string
+	}{
+		{"invalid JSON", path, "{", errorHandling.InvalidJSON, ""},
+		{"missing size and price", path, map[string]int{}, errorHandling.ValidationFailed, "size"},
+		{"size out of range", path, map[string]int{"size": 9, "price": 100}, errorHandling.ValidationFailed, "size"},
+		{"negative price", path, map[string]int{"size": 4, "price": -1}, errorHandling.ValidationFailed, "price"},
+		{"expiry before purchase", path, map[string]interface{}{"size": 4, "price": 100, "purchaseDate": "2024-03-01T00:00:00Z", "expiresAt": "2024-02-01T00:00:00Z"}, errorHandling.ValidationFailed, "expiresAt"},
+		{"missing student", "/students/" + missingId + "/subscriptions", map[string]int{"size": 4, "price": 100}, errorHandling.NotFound, ""},
+		{"renew pack of another student", path + "/" + bobPack.Id.Hex() + "/renew", nil, errorHandling.NotFound, ""},
+		{"renew with bad id", path + "/bad/renew", nil, errorHandling.InvalidId, "subscriptionId"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := expectError(t, doRequest(t, router, http.MethodPost, test.path, test.body), test.code)
+			if test.field == "" {
+				return
+			}
+			for _, detail := range response.Details {
+				if detail.Field == test.field {
+					return
+				}
+			}
+			t.Fatalf("expected detail for field %v, got %+v", test.field, response.Details)
+		})
+	}
+}
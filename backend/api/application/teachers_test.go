@@ -0,0 +1,119 @@
+package application
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/DanVerh/artschool-admin/backend/api/auth"
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+// Create a teacher through the API and return it
+func createTeacher(t *testing.T, router http.Handler, fullname string, phone string, classTypes ...string) models.Teacher {
+	t.Helper()
+
+	recorder := doRequest(t, router, http.MethodPost, "/teachers", map[string]interface{}{"fullname": fullname, "phone": phone, "classTypes": classTypes})
+	expectStatus(t, recorder, http.StatusCreated)
+	var teacher models.Teacher
+	decodeResponse(t, recorder, &teacher)
+	return teacher
+}
+
+func teacherClass(studentId string, time string, classType string, teacher models.Teacher) map[string]interface{} {
+	class := class(studentId, time, classType)
+	class["teacherId"] = teacher.Id.Hex()
+	return class
+}
+
+func TestTeachersCRUD(t *testing.T) {
+	router := newTestRouter(t)
+	anna := createTeacher(t, router, "Anna Brown", "+380501234567", "drawing", "both")
+	if !anna.Active || anna.Email != nil {
+		t.Fatalf("expected an active teacher without email, got %+v", anna)
+	}
+	createTeacher(t, router, "Oleh Green", "+380501234568", "painting")
+
+	recorder := doRequest(t, router, http.MethodPut, "/teachers/"+anna.Id.Hex(), map[string]interface{}{"email": "anna@example.com", "active": false})
+	expectStatus(t, recorder, http.StatusOK)
+	var updated models.Teacher
+	decodeResponse(t, recorder, &updated)
+	if updated.Active || updated.Email == nil || updated.Fullname != "Anna Brown" || len(updated.ClassTypes) != 2 {
+		t.Fatalf("unexpected updated teacher %+v", updated)
+	}
+
+	recorder = doRequest(t, router, http.MethodGet, "/teachers?active=true", nil)
+	expectStatus(t, recorder, http.StatusOK)
+	var teachers []models.Teacher
+	decodeResponse(t, recorder, &teachers)
+	if len(teachers) != 1 || teachers[0].Fullname != "Oleh Green" {
+		t.Fatalf("expected only the active teacher, got %+v", teachers)
+	}
+
+	recorder = doRequest(t, router, http.MethodGet, "/teachers?classType=drawing", nil)
+	expectStatus(t, recorder, http.StatusOK)
+	decodeResponse(t, recorder, &teachers)
+	if len(teachers) != 1 || teachers[0].Id != anna.Id {
+		t.Fatalf("expected the drawing teacher, got %+v", teachers)
+	}
+}
+
+func TestTeachersErrors(t *testing.T) {
+	router := newTestRouter(t)
+	anna := createTeacher(t, router, "Anna Brown", "+380501234567", "drawing")
+
+	recorder := doRequest(t, router, http.MethodPost, "/teachers", map[string]interface{}{"fullname": "", "phone": "123", "email": "anna", "classTypes": []string{"sculpture"}})
+	if response := expectError(t, recorder, errorHandling.ValidationFailed); len(response.Details) != 4 {
+		t.Fatalf("expected fullname, phone, email and class type errors, got %+v", response.Details)
+	}
+	recorder = doRequest(t, router, http.MethodPut, "/teachers/"+anna.Id.Hex(), map[string]interface{}{"classTypes": []string{}})
+	expectError(t, recorder, errorHandling.ValidationFailed)
+	recorder = doRequest(t, router, http.MethodPut, "/teachers/"+anna.Id.Hex(), map[string]interface{}{"age": 30})
+	expectError(t, recorder, errorHandling.InvalidJSON)
+	recorder = doRequest(t, router, http.MethodGet, "/teachers/000000000000000000000000", nil)
+	expectError(t, recorder, errorHandling.NotFound)
+	recorder = doRequest(t, router, http.MethodGet, "/teachers?active=maybe", nil)
+	expectError(t, recorder, errorHandling.ValidationFailed)
+}
+
+func TestScheduleTeachers(t *testing.T) {
+	_, router := newTestServer(t)
+	admin := withToken(router, login(t, router, "admin", testPassword))
+	alice := createStudent(t, admin, "Alice Johnson", "+123456789012")
+	bob := createStudent(t, admin, "Bob Smith", "+123456789013")
+	anna := createTeacher(t, admin, "Anna Brown", "+380501234567", "drawing", "both")
+	oleh := createTeacher(t, admin, "Oleh Green", "+380501234568", "painting")
+
+	monday := createSchedule(t, admin, "2024-03-04T00:00:00Z",
+		teacherClass(alice.Id.Hex(), "10:00", "drawing", anna),
+		teacherClass(bob.Id.Hex(), "12:00", "painting", oleh))
+	createSchedule(t, admin, "2024-03-05T00:00:00Z", teacherClass(alice.Id.Hex(), "10:00", "painting", oleh))
+	createSchedule(t, admin, "2024-03-06T00:00:00Z", class(bob.Id.Hex(), "10:00", "both"))
+
+	// Only the days and classes of the teacher
+	recorder := doRequest(t, admin, http.MethodGet, "/schedule?teacherId="+anna.Id.Hex(), nil)
+	expectStatus(t, recorder, http.StatusOK)
+	var schedules []models.Schedule
+	decodeResponse(t, recorder, &schedules)
+	if len(schedules) != 1 || schedules[0].Id != monday.Id || len(schedules[0].Classes) != 1 || schedules[0].Classes[0].StudentId != alice.Id {
+		t.Fatalf("expected the drawing class of Monday, got %+v", schedules)
+	}
+
+	// The teacher must teach the type of the class and be active
+	recorder = doRequest(t, admin, http.MethodPut, "/schedule/"+monday.Id.Hex(), teacherClass(bob.Id.Hex(), "12:00", "painting", anna))
+	expectError(t, recorder, errorHandling.ValidationFailed)
+	expectStatus(t, doRequest(t, admin, http.MethodPut, "/teachers/"+oleh.Id.Hex(), map[string]bool{"active": false}), http.StatusOK)
+	recorder = doRequest(t, admin, http.MethodPost, "/schedule", map[string]interface{}{
+		"date": "2024-03-07T00:00:00Z", "classes": []interface{}{teacherClass(alice.Id.Hex(), "10:00", "painting", oleh)},
+	})
+	if response := expectError(t, recorder, errorHandling.ValidationFailed); len(response.Details) != 1 || response.Details[0].Field != "classes[0].teacherId" {
+		t.Fatalf("expected an inactive teacher error, got %+v", response.Details)
+	}
+	recorder = doRequest(t, admin, http.MethodGet, "/schedule?teacherId=anna", nil)
+	expectError(t, recorder, errorHandling.ValidationFailed)
+
+	// Teachers see the list of teachers but do not manage it
+	teacher := newUserRouter(t, admin, router, "teacher", auth.RoleTeacher)
+	expectStatus(t, doRequest(t, teacher, http.MethodGet, "/teachers", nil), http.StatusOK)
+	expectForbidden(t, teacher, http.MethodPost, "/teachers", map[string]interface{}{}, auth.TeachersManage)
+}
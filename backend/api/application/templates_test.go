@@ -0,0 +1,110 @@
+package application
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+type generated struct {
+	Created  []string `json:"created"`
+	Updated  []string `json:"updated"`
+	Booked   int      `json:"booked"`
+	Holidays []string `json:"holidays"`
+	Skipped  []struct {
+		Date      string `json:"date"`
+		StudentId string `json:"studentId"`
+		Reason    string `json:"reason"`
+	} `json:"skipped"`
+}
+
+func generate(t *testing.T, router http.Handler, from time.Time, to time.Time) generated {
+	t.Helper()
+
+	recorder := doRequest(t, router, http.MethodPost, "/templates/generate", map[string]string{"from": from.Format(time.DateOnly), "to": to.Format(time.DateOnly)})
+	expectStatus(t, recorder, http.StatusOK)
+	var response generated
+	decodeResponse(t, recorder, &response)
+	return response
+}
+
+func TestTemplatesGenerateSchedules(t *testing.T) {
+	router := newTestRouter(t)
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+	bob := createStudent(t, router, "Bob Smith", "+123456789013")
+	carol := createStudent(t, router, "Carol White", "+123456789014")
+	sellSubscription(t, router, alice, 2)
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	first := today.AddDate(0, 0, 1)
+	weekday := strings.ToLower(first.Weekday().String())
+	dates := func(days ...int) []string {
+		result := []string{}
+		for _, day := range days {
+			result = append(result, first.AddDate(0, 0, day).Format(time.DateOnly))
+		}
+		return result
+	}
+
+	for _, body := range []map[string]interface{}{
+		{"studentId": alice.Id.Hex(), "weekday": weekday, "time": "14:00", "type": "painting", "startDate": today, "untilSubscriptionEnds": true},
+		{"studentId": bob.Id.Hex(), "weekday": weekday, "time": "15:00", "type": "drawing", "startDate": today},
+	} {
+		expectStatus(t, doRequest(t, router, http.MethodPost, "/templates", body), http.StatusCreated)
+	}
+	expectStatus(t, doRequest(t, router, http.MethodPost, "/holidays", map[string]string{"date": dates(7)[0], "name": "School trip"}), http.StatusCreated)
+	createSchedule(t, router, first.Format(time.RFC3339), class(carol.Id.Hex(), "10:00", "both"))
+
+	// Alice has 2 classes in the pack, so her third week is not booked
+	response := generate(t, router, today, today.AddDate(0, 0, 27))
+	if !slices.Equal(response.Updated, dates(0)) || !slices.Equal(response.Created, dates(14, 21)) || !slices.Equal(response.Holidays, dates(7)) || response.Booked != 5 {
+		t.Fatalf("unexpected generate response %+v", response)
+	}
+	if len(response.Skipped) != 1 || response.Skipped[0].StudentId != alice.Id.Hex() || response.Skipped[0].Date != dates(21)[0] {
+		t.Fatalf("expected the third class of Alice to be skipped, got %+v", response.Skipped)
+	}
+
+	// Running it again books nothing new
+	response = generate(t, router, today, today.AddDate(0, 0, 27))
+	if len(response.Created) != 0 || len(response.Updated) != 0 || response.Booked != 0 {
+		t.Fatalf("expected generating twice to change nothing, got %+v", response)
+	}
+
+	recorder := doRequest(t, router, http.MethodGet, "/schedule", nil)
+	expectStatus(t, recorder, http.StatusOK)
+	var schedules []models.Schedule
+	decodeResponse(t, recorder, &schedules)
+	if len(schedules) != 3 || len(schedules[0].Classes) != 3 {
+		t.Fatalf("expected 3 days with 3 classes on the first one, got %+v", schedules)
+	}
+}
+
+func TestTemplatesErrors(t *testing.T) {
+	router := newTestRouter(t)
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+
+	recorder := doRequest(t, router, http.MethodPost, "/templates", map[string]interface{}{
+		"studentId": alice.Id.Hex(), "weekday": "Tue", "time": "21:00", "type": "drawing",
+		"startDate": "2024-03-05T00:00:00Z", "endDate": "2024-03-01T00:00:00Z",
+	})
+	if response := expectError(t, recorder, errorHandling.ValidationFailed); len(response.Details) != 3 {
+		t.Fatalf("expected weekday, time and endDate errors, got %+v", response.Details)
+	}
+	recorder = doRequest(t, router, http.MethodPost, "/templates", map[string]interface{}{
+		"studentId": "000000000000000000000000", "weekday": "tuesday", "time": "10:00", "type": "drawing", "startDate": "2024-03-05T00:00:00Z",
+	})
+	expectError(t, recorder, errorHandling.ValidationFailed)
+	recorder = doRequest(t, router, http.MethodPost, "/templates/generate", map[string]string{"from": "2024-03-05", "to": "2024-03-01"})
+	expectError(t, recorder, errorHandling.ValidationFailed)
+	recorder = doRequest(t, router, http.MethodPost, "/templates/generate", map[string]string{"from": "2024-01-01", "to": "2025-06-01"})
+	expectError(t, recorder, errorHandling.ValidationFailed)
+	expectStatus(t, doRequest(t, router, http.MethodPost, "/holidays", map[string]string{"date": "2024-12-25", "name": "Christmas"}), http.StatusCreated)
+	recorder = doRequest(t, router, http.MethodPost, "/holidays", map[string]string{"date": "2024-12-25", "name": "Again"})
+	expectError(t, recorder, errorHandling.Duplicate)
+}
@@ -0,0 +1,77 @@
+package application
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+func getTimeline(t *testing.T, router http.Handler, student models.Student, query string) models.TimelinePage {
+	t.Helper()
+
+	recorder := doRequest(t, router, http.MethodGet, "/students/"+student.Id.Hex()+"/timeline"+query, nil)
+	expectStatus(t, recorder, http.StatusOK)
+	page := models.TimelinePage{}
+	decodeResponse(t, recorder, &page)
+	return page
+}
+
+func timelineTypes(entries []models.TimelineEntry) []string {
+	types := []string{}
+	for _, entry := range entries {
+		types = append(types, entry.Type)
+	}
+	return types
+}
+
+func TestStudentTimeline(t *testing.T) {
+	router := newTestRouter(t)
+	alice := createStudent(t, router, "Alice Johnson", "+123456789012")
+	bob := createStudent(t, router, "Bob Smith", "+123456789013")
+	createSchedule(t, router, "2024-03-05T00:00:00Z", class(alice.Id.Hex(), "16:00", "painting"), class(bob.Id.Hex(), "10:00", "drawing"))
+	createSchedule(t, router, "2024-03-01T00:00:00Z", class(alice.Id.Hex(), "10:00", "drawing"))
+	sellSubscription(t, router, alice, 8)
+	expectStatus(t, doRequest(t, router, http.MethodPut, "/students/"+alice.Id.Hex(), map[string]string{"comments": "Beginner"}), http.StatusOK)
+	expectStatus(t, doRequest(t, router, http.MethodPut, "/students/"+alice.Id.Hex(), map[string]interface{}{"comments": nil}), http.StatusOK)
+
+	page := getTimeline(t, router, alice, "")
+	expected := []string{models.TimelineTypeClass, models.TimelineTypeClass, models.TimelineTypeSubscription, models.TimelineTypeComment, models.TimelineTypeComment}
+	if types := timelineTypes(page.Entries); !slices.Equal(types, expected) || page.Total != 5 {
+		t.Fatalf("expected %v, got %v", expected, types)
+	}
+	first := page.Entries[0]
+	if first.Date.Format("2006-01-02T15:04") != "2024-03-01T10:00" || first.Class == nil || first.Class.Type != "drawing" || first.ScheduleId == nil {
+		t.Fatalf("unexpected first class entry %+v", first)
+	}
+	if page.Entries[2].Subscription == nil || page.Entries[2].Subscription.Size != 8 {
+		t.Fatalf("unexpected subscription entry %+v", page.Entries[2])
+	}
+	comments := page.Entries[3:]
+	if comments[0].Comment.Text == nil || *comments[0].Comment.Text != "Beginner" || comments[0].Comment.Actor != "admin" || comments[1].Comment.Text != nil {
+		t.Fatalf("unexpected comment entries %+v %+v", comments[0].Comment, comments[1].Comment)
+	}
+
+	// Date range, both days included
+	page = getTimeline(t, router, alice, "?from=2024-03-02&to=2024-03-05")
+	if len(page.Entries) != 1 || page.Entries[0].Class.Time != "16:00" {
+		t.Fatalf("expected only the class of March 5, got %+v", page.Entries)
+	}
+
+	// Pages follow the next links until the last page
+	page = getTimeline(t, router, alice, "?limit=3")
+	if len(page.Entries) != 3 || page.Next != "/students/"+alice.Id.Hex()+"/timeline?limit=3&offset=3" {
+		t.Fatalf("unexpected first page %+v", page)
+	}
+	page = getTimeline(t, router, alice, strings.TrimPrefix(page.Next, "/students/"+alice.Id.Hex()+"/timeline"))
+	if types := timelineTypes(page.Entries); !slices.Equal(types, expected[3:]) || page.Next != "" {
+		t.Fatalf("unexpected last page %+v", page)
+	}
+
+	expectError(t, doRequest(t, router, http.MethodGet, "/students/"+alice.Id.Hex()+"/timeline?from=soon", nil), errorHandling.ValidationFailed)
+	expectError(t, doRequest(t, router, http.MethodGet, "/students/"+alice.Id.Hex()+"/timeline?from=2024-03-05&to=2024-03-01", nil), errorHandling.ValidationFailed)
+	expectError(t, doRequest(t, router, http.MethodGet, "/students/000000000000000000000000/timeline", nil), errorHandling.NotFound)
+}
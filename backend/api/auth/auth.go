@@ -0,0 +1,291 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+	"github.com/DanVerh/artschool-admin/backend/api/storage"
+)
+
+// Name of the cookie holding the session token
+const CookieName = "session"
+
+// Minimal password length accepted for new users
+const MinPasswordLength = 8
+
+// Maximal password length in bytes; bcrypt does not hash longer passwords
+const MaxPasswordLength = 72
+
+// Errors returned by the Manager
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrInvalidToken       = errors.New("invalid or expired session token")
+)
+
+// Hash compared when the user does not exist, so a login takes the same time
+// whether the username is known or not
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("artschool-admin"), bcrypt.DefaultCost)
+
+// Manager logs users in and out and checks session tokens.
+// A token is a random session id signed with HMAC-SHA256; the signature lets the server
+// reject forged tokens without a database lookup, and only a hash of the id is stored,
+// so a leaked sessions collection cannot be used to log in
+type Manager struct {
+	users    storage.UserRepository
+	sessions storage.SessionRepository
+	secret   []byte
+	ttl      time.Duration
+}
+
+// NewManager creates a Manager signing tokens with secret; sessions live for ttl
+func NewManager(users storage.UserRepository, sessions storage.SessionRepository, secret []byte, ttl time.Duration) *Manager {
+	return &Manager{
+		users:    users,
+		sessions: sessions,
+		secret:   secret,
+		ttl:      ttl,
+	}
+}
+
+// HashPassword returns the bcrypt hash of the password
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// NewUser builds a user with a hashed password
+func NewUser(username string, password string, role string) (*models.User, error) {
+	if len(password) < MinPasswordLength {
+		return nil, fmt.Errorf("password of %v must have at least %v characters", username, MinPasswordLength)
+	}
+	if len(password) > MaxPasswordLength {
+		return nil, fmt.Errorf("password of %v must have at most %v bytes", username, MaxPasswordLength)
+	}
+	if !IsRole(role) {
+		return nil, fmt.Errorf("unknown role %q", role)
+	}
+	hash, err := HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Id:           primitive.NewObjectID(),
+		Username:     username,
+		PasswordHash: hash,
+		Role:         role,
+		CreatedAt:    time.Now().UTC(),
+	}
+
+	return user, nil
+}
+
+// EnsureUser creates the user if there is no user with this username yet.
+// Used to bootstrap the first admin account from the configuration
+func (manager *Manager) EnsureUser(ctx context.Context, username string, password string, role string) error {
+	_, err := manager.users.GetByUsername(ctx, username)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	user, err := NewUser(username, password, role)
+	if err != nil {
+		return err
+	}
+	err = manager.users.Create(ctx, user)
+	if err != nil && !errors.Is(err, storage.ErrDuplicate) {
+		return err
+	}
+
+	log.Printf("Created %v user %v", role, username)
+	return nil
+}
+
+// Login checks the credentials and starts a new session; returns the token for the client
+func (manager *Manager) Login(ctx context.Context, username string, password string) (string, *models.Session, *models.User, error) {
+	user, err := manager.users.GetByUsername(ctx, username)
+	if errors.Is(err, storage.ErrNotFound) {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return "", nil, nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
+	if err != nil {
+		return "", nil, nil, ErrInvalidCredentials
+	}
+
+	// Random session id
+	idBytes := make([]byte, 32)
+	_, err = rand.Read(idBytes)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	id := base64.RawURLEncoding.EncodeToString(idBytes)
+
+	now := time.Now().UTC()
+	session := &models.Session{
+		Id:        hashId(id),
+		UserId:    user.Id,
+		CreatedAt: now,
+		ExpiresAt: now.Add(manager.ttl),
+	}
+	err = manager.sessions.Create(ctx, session)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	return id + "." + manager.sign(id), session, user, nil
+}
+
+// Logout ends the session of the token
+func (manager *Manager) Logout(ctx context.Context, token string) error {
+	id, err := manager.verify(token)
+	if err != nil {
+		return err
+	}
+
+	err = manager.sessions.Delete(ctx, hashId(id))
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrInvalidToken
+	}
+
+	return err
+}
+
+// Authenticate returns the user of a valid session token
+func (manager *Manager) Authenticate(ctx context.Context, token string) (*models.User, error) {
+	id, err := manager.verify(token)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := manager.sessions.Get(ctx, hashId(id))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := manager.users.Get(ctx, session.UserId)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrInvalidToken
+	}
+
+	return user, err
+}
+
+// Middleware rejects requests without a valid session with 401
+// and stores the user in the request context for the handlers
+func (manager *Manager) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := TokenFromRequest(r)
+		if token == "" {
+			errorHandling.ThrowError(w, r, errorHandling.Unauthorized, "Authentication required", nil)
+			return
+		}
+
+		user, err := manager.Authenticate(r.Context(), token)
+		if errors.Is(err, ErrInvalidToken) {
+			errorHandling.ThrowError(w, r, errorHandling.Unauthorized, "Session is invalid or expired, log in again", nil)
+			return
+		}
+		if err != nil {
+			errorHandling.ThrowDbError(w, r, "Failed to check session", err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+	})
+}
+
+// TokenFromRequest reads the token from the "Authorization: Bearer" header or the session cookie
+func TokenFromRequest(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if token, found := strings.CutPrefix(header, "Bearer "); found {
+		return strings.TrimSpace(token)
+	}
+
+	cookie, err := r.Cookie(CookieName)
+	if err != nil {
+		return ""
+	}
+
+	return cookie.Value
+}
+
+// Split the token and check its signature; returns the session id
+func (manager *Manager) verify(token string) (string, error) {
+	id, signature, found := strings.Cut(token, ".")
+	if !found || !hmac.Equal([]byte(signature), []byte(manager.sign(id))) {
+		return "", ErrInvalidToken
+	}
+
+	return id, nil
+}
+
+func (manager *Manager) sign(id string) string {
+	mac := hmac.New(sha256.New, manager.secret)
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// NewFeedToken returns a random token for the link of a calendar feed and the hash of it that is stored
+func NewFeedToken() (string, string, error) {
+	tokenBytes := make([]byte, 32)
+	_, err := rand.Read(tokenBytes)
+	if err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(tokenBytes)
+
+	return token, hashId(token), nil
+}
+
+// HashFeedToken returns the stored hash of a calendar feed token
+func HashFeedToken(token string) string {
+	return hashId(token)
+}
+
+func hashId(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])
+}
+
+type contextKey struct{}
+
+// WithUser returns a copy of ctx carrying the authenticated user
+func WithUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, contextKey{}, user)
+}
+
+// UserFromContext returns the authenticated user of the request, nil if there is none
+func UserFromContext(ctx context.Context) *models.User {
+	user, _ := ctx.Value(contextKey{}).(*models.User)
+	return user
+}
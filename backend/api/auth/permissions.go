@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+// Permission allows one kind of action; routes require permissions and handlers check them per field
+type Permission string
+
+// Catalogue of permissions
+const (
+	StudentsRead               Permission = "students:read"
+	StudentsCreate             Permission = "students:create"
+	StudentsUpdateContacts     Permission = "students:update:contacts"
+	StudentsUpdateSubscription Permission = "students:update:subscription"
+	StudentsUpdateComments     Permission = "students:update:comments"
+	StudentsDelete             Permission = "students:delete"
+	StudentsPurge              Permission = "students:purge"
+	ScheduleRead               Permission = "schedule:read"
+	ScheduleCreate             Permission = "schedule:create"
+	ScheduleUpdate             Permission = "schedule:update"
+	ScheduleAttendance         Permission = "schedule:attendance"
+	ScheduleDelete             Permission = "schedule:delete"
+	TeachersRead               Permission = "teachers:read"
+	TeachersManage             Permission = "teachers:manage"
+	RoomsManage                Permission = "rooms:manage"
+	UsersManage                Permission = "users:manage"
+	AuditRead                  Permission = "audit:read"
+	PaymentsRead               Permission = "payments:read"
+	PaymentsCreate             Permission = "payments:create"
+	PaymentsAdjust             Permission = "payments:adjust"
+	NotificationsRead          Permission = "notifications:read"
+	NotificationsSend          Permission = "notifications:send"
+	FeedsManage                Permission = "feeds:manage"
+)
+
+// Roles of the staff
+const (
+	RoleOwner        = "owner"
+	RoleTeacher      = "teacher"
+	RoleReceptionist = "receptionist"
+)
+
+// Permissions granted to every role.
+// Teachers run classes: they mark attendance and leave comments, but do not touch contacts or subscriptions.
+// Receptionists do the paperwork and take payments, but never delete records or give money back
+var rolePermissions = map[string][]Permission{
+	RoleOwner: {
+		StudentsRead, StudentsCreate, StudentsUpdateContacts, StudentsUpdateSubscription, StudentsUpdateComments, StudentsDelete, StudentsPurge,
+		ScheduleRead, ScheduleCreate, ScheduleUpdate, ScheduleAttendance, ScheduleDelete,
+		TeachersRead, TeachersManage, RoomsManage,
+		PaymentsRead, PaymentsCreate, PaymentsAdjust,
+		NotificationsRead, NotificationsSend,
+		UsersManage, AuditRead, FeedsManage,
+	},
+	RoleTeacher: {
+		StudentsRead, StudentsUpdateComments,
+		ScheduleRead, ScheduleAttendance,
+		TeachersRead,
+	},
+	RoleReceptionist: {
+		StudentsRead, StudentsCreate, StudentsUpdateContacts, StudentsUpdateSubscription, StudentsUpdateComments,
+		ScheduleRead, ScheduleCreate, ScheduleUpdate, ScheduleAttendance,
+		TeachersRead,
+		PaymentsRead, PaymentsCreate,
+		NotificationsRead,
+	},
+}
+
+// IsRole reports whether the role is known
+func IsRole(role string) bool {
+	_, found := rolePermissions[role]
+	return found
+}
+
+// Roles returns all known roles
+func Roles() []string {
+	return []string{RoleOwner, RoleTeacher, RoleReceptionist}
+}
+
+// HasPermission reports whether the role of the user grants the permission
+func HasPermission(user *models.User, permission Permission) bool {
+	if user == nil {
+		return false
+	}
+
+	return slices.Contains(rolePermissions[user.Role], permission)
+}
+
+// Can reports whether the user of the request has the permission
+func Can(r *http.Request, permission Permission) bool {
+	return HasPermission(UserFromContext(r.Context()), permission)
+}
+
+// Require lets the request through if the user has at least one of the permissions, otherwise responds 403.
+// Must be used after Manager.Middleware
+func Require(permissions ...Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, permission := range permissions {
+				if Can(r, permission) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			ThrowForbidden(w, r, "", permissions...)
+		})
+	}
+}
+
+// ThrowForbidden responds 403 naming the missing permissions; field is set when only a part of the request is not allowed
+func ThrowForbidden(w http.ResponseWriter, r *http.Request, field string, permissions ...Permission) {
+	details := []errorHandling.Detail{}
+	for _, permission := range permissions {
+		details = append(details, errorHandling.Detail{Field: field, Message: "missing permission " + string(permission)})
+	}
+
+	message := "Missing permission " + string(permissions[0])
+	if len(permissions) > 1 {
+		message = "Missing one of the permissions required for this action"
+	}
+	if field != "" {
+		message += " to change " + field
+	}
+
+	errorHandling.ThrowError(w, r, errorHandling.Forbidden, message, nil, details...)
+}
@@ -0,0 +1,429 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds all settings of the API server.
+// Values are applied in order: defaults, config file, env vars, command-line flags;
+// every next source overrides the previous one
+type Config struct {
+	Port            int
+	Storage         string
+	DbUri           string
+	DbName          string
+	DbTimeout       time.Duration
+	QueryTimeout    time.Duration
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	ShutdownTimeout time.Duration
+	// Key for signing session tokens; a random one is generated when empty,
+	// then all sessions end on restart
+	SessionSecret string
+	SessionTTL    time.Duration
+	SecureCookies bool
+	// First admin account, created on start if it does not exist yet
+	AdminUsername string
+	AdminPassword string
+	// How long a subscription pack can be used after the purchase
+	SubscriptionValidity time.Duration
+	// What happens to the classes of a deleted student: reject, cascade or keep
+	StudentDeletePolicy string
+	// Where class reminders go: none, log or file; NotificationFile is the path of the file sender
+	NotificationSender string
+	NotificationFile   string
+	// Time of the day, as HH:MM on the clock of the server, after which reminders of tomorrow's classes are sent
+	ReminderTime string
+	// text/template of the reminders; empty for the default text
+	ReminderTemplate string
+}
+
+// fileConfig is the JSON layout of the optional config file.
+// Pointers tell the difference between a missing key and a zero value
+type fileConfig struct {
+	Port            *int    `json:"port"`
+	Storage         *string `json:"storage"`
+	DbUri           *string `json:"dbUri"`
+	DbName          *string `json:"dbName"`
+	DbTimeout       *string `json:"dbTimeout"`
+	QueryTimeout    *string `json:"queryTimeout"`
+	ReadTimeout     *string `json:"readTimeout"`
+	WriteTimeout    *string `json:"writeTimeout"`
+	ShutdownTimeout *string `json:"shutdownTimeout"`
+	SessionSecret   *string `json:"sessionSecret"`
+	SessionTTL      *string `json:"sessionTtl"`
+	SecureCookies   *bool   `json:"secureCookies"`
+	AdminUsername   *string `json:"adminUsername"`
+	AdminPassword   *string `json:"adminPassword"`
+	// Duration such as "1440h"
+	SubscriptionValidity *string `json:"subscriptionValidity"`
+	StudentDeletePolicy  *string `json:"studentDeletePolicy"`
+	NotificationSender   *string `json:"notificationSender"`
+	NotificationFile     *string `json:"notificationFile"`
+	ReminderTime         *string `json:"reminderTime"`
+	ReminderTemplate     *string `json:"reminderTemplate"`
+}
+
+// Storage backends
+const (
+	MongoStorage  = "mongo"
+	MemoryStorage = "memory"
+)
+
+// Policies of student deletion. Past classes always stay as history and are marked with studentDeleted;
+// future classes block the deletion (reject), are removed with the student (cascade) or stay marked too (keep)
+const (
+	RejectStudentDelete  = "reject"
+	CascadeStudentDelete = "cascade"
+	KeepStudentDelete    = "keep"
+)
+
+// Senders of notifications. Log and file senders do not deliver anything and are meant for development and tests;
+// providers like SMS, Telegram or email implement notifications.Sender
+const (
+	NoNotificationSender   = "none"
+	LogNotificationSender  = "log"
+	FileNotificationSender = "file"
+)
+
+// Default returns the settings used for local development
+func Default() *Config {
+	return &Config{
+		Port:            8080,
+		Storage:         MongoStorage,
+		DbUri:           "mongodb://localhost:27017",
+		DbName:          "artschool-admin",
+		DbTimeout:       5 * time.Second,
+		QueryTimeout:    10 * time.Second,
+		ReadTimeout:     15 * time.Second,
+		WriteTimeout:    15 * time.Second,
+		ShutdownTimeout: 20 * time.Second,
+		SessionTTL:      12 * time.Hour,
+		// Two months
+		SubscriptionValidity: 60 * 24 * time.Hour,
+		StudentDeletePolicy:  RejectStudentDelete,
+		NotificationSender:   NoNotificationSender,
+		ReminderTime:         "17:00",
+	}
+}
+
+// Load builds the configuration from the config file, env vars and the given command-line arguments
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+
+	flagSet := flag.NewFlagSet("api", flag.ContinueOnError)
+	configFile := flagSet.String("config", os.Getenv("CONFIG_FILE"), "path to JSON config file (env CONFIG_FILE)")
+	port := flagSet.Int("port", 0, "port to listen on (env PORT)")
+	storageBackend := flagSet.String("storage", "", "storage backend: mongo or memory (env STORAGE)")
+	dbUri := flagSet.String("db-uri", "", "MongoDB connection string (env DBURI)")
+	dbName := flagSet.String("db-name", "", "MongoDB database name (env DBNAME)")
+	dbTimeout := flagSet.Duration("db-timeout", 0, "how long to wait for a reachable MongoDB server (env DB_TIMEOUT)")
+	queryTimeout := flagSet.Duration("query-timeout", 0, "maximum duration of a single database query (env QUERY_TIMEOUT)")
+	readTimeout := flagSet.Duration("read-timeout", 0, "maximum duration for reading a request (env READ_TIMEOUT)")
+	writeTimeout := flagSet.Duration("write-timeout", 0, "maximum duration for writing a response (env WRITE_TIMEOUT)")
+	shutdownTimeout := flagSet.Duration("shutdown-timeout", 0, "how long to wait for in-flight requests on shutdown (env SHUTDOWN_TIMEOUT)")
+	sessionTTL := flagSet.Duration("session-ttl", 0, "how long a login session lives (env SESSION_TTL)")
+	secureCookies := flagSet.Bool("secure-cookies", false, "send the session cookie over HTTPS only (env SECURE_COOKIES)")
+	subscriptionValidity := flagSet.Duration("subscription-validity", 0, "how long a subscription pack can be used after the purchase (env SUBSCRIPTION_VALIDITY)")
+	studentDeletePolicy := flagSet.String("student-delete-policy", "", "what happens to future classes of a deleted student: reject, cascade or keep (env STUDENT_DELETE_POLICY)")
+	notificationSender := flagSet.String("notification-sender", "", "where class reminders go: none, log or file (env NOTIFICATION_SENDER)")
+	notificationFile := flagSet.String("notification-file", "", "file of the file notification sender (env NOTIFICATION_FILE)")
+	reminderTime := flagSet.String("reminder-time", "", "time of the day, HH:MM, after which reminders of tomorrow's classes are sent (env REMINDER_TIME)")
+	adminUsername := flagSet.String("admin-username", "", "username of the first admin account; the password is read from env ADMIN_PASSWORD (env ADMIN_USERNAME)")
+
+	err := flagSet.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	// Config file
+	if *configFile != "" {
+		err = cfg.loadFile(*configFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Env vars
+	err = cfg.loadEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// Command-line flags; only the ones passed explicitly override other sources
+	flagSet.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "port":
+			cfg.Port = *port
+		case "storage":
+			cfg.Storage = *storageBackend
+		case "db-uri":
+			cfg.DbUri = *dbUri
+		case "db-name":
+			cfg.DbName = *dbName
+		case "db-timeout":
+			cfg.DbTimeout = *dbTimeout
+		case "query-timeout":
+			cfg.QueryTimeout = *queryTimeout
+		case "read-timeout":
+			cfg.ReadTimeout = *readTimeout
+		case "write-timeout":
+			cfg.WriteTimeout = *writeTimeout
+		case "shutdown-timeout":
+			cfg.ShutdownTimeout = *shutdownTimeout
+		case "session-ttl":
+			cfg.SessionTTL = *sessionTTL
+		case "secure-cookies":
+			cfg.SecureCookies = *secureCookies
+		case "admin-username":
+			cfg.AdminUsername = *adminUsername
+		case "subscription-validity":
+			cfg.SubscriptionValidity = *subscriptionValidity
+		case "student-delete-policy":
+			cfg.StudentDeletePolicy = *studentDeletePolicy
+		case "notification-sender":
+			cfg.NotificationSender = *notificationSender
+		case "notification-file":
+			cfg.NotificationFile = *notificationFile
+		case "reminder-time":
+			cfg.ReminderTime = *reminderTime
+		}
+	})
+
+	err = cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Read settings from JSON config file
+func (cfg *Config) loadFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var file fileConfig
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&file)
+	if err != nil {
+		return fmt.Errorf("invalid config file %v: %w", path, err)
+	}
+
+	if file.Port != nil {
+		cfg.Port = *file.Port
+	}
+	if file.Storage != nil {
+		cfg.Storage = *file.Storage
+	}
+	if file.DbUri != nil {
+		cfg.DbUri = *file.DbUri
+	}
+	if file.DbName != nil {
+		cfg.DbName = *file.DbName
+	}
+	if file.SessionSecret != nil {
+		cfg.SessionSecret = *file.SessionSecret
+	}
+	if file.SecureCookies != nil {
+		cfg.SecureCookies = *file.SecureCookies
+	}
+	if file.AdminUsername != nil {
+		cfg.AdminUsername = *file.AdminUsername
+	}
+	if file.AdminPassword != nil {
+		cfg.AdminPassword = *file.AdminPassword
+	}
+	if file.StudentDeletePolicy != nil {
+		cfg.StudentDeletePolicy = *file.StudentDeletePolicy
+	}
+	if file.NotificationSender != nil {
+		cfg.NotificationSender = *file.NotificationSender
+	}
+	if file.NotificationFile != nil {
+		cfg.NotificationFile = *file.NotificationFile
+	}
+	if file.ReminderTime != nil {
+		cfg.ReminderTime = *file.ReminderTime
+	}
+	if file.ReminderTemplate != nil {
+		cfg.ReminderTemplate = *file.ReminderTemplate
+	}
+
+	durations := []struct {
+		key   string
+		value *string
+		field *time.Duration
+	}{
+		{"dbTimeout", file.DbTimeout, &cfg.DbTimeout},
+		{"queryTimeout", file.QueryTimeout, &cfg.QueryTimeout},
+		{"readTimeout", file.ReadTimeout, &cfg.ReadTimeout},
+		{"writeTimeout", file.WriteTimeout, &cfg.WriteTimeout},
+		{"shutdownTimeout", file.ShutdownTimeout, &cfg.ShutdownTimeout},
+		{"sessionTtl", file.SessionTTL, &cfg.SessionTTL},
+		{"subscriptionValidity", file.SubscriptionValidity, &cfg.SubscriptionValidity},
+	}
+	for _, duration := range durations {
+		if duration.value == nil {
+			continue
+		}
+		parsed, err := time.ParseDuration(*duration.value)
+		if err != nil {
+			return fmt.Errorf("invalid %v in config file: %w", duration.key, err)
+		}
+		*duration.field = parsed
+	}
+
+	return nil
+}
+
+// Read settings from env vars
+func (cfg *Config) loadEnv() error {
+	if value := os.Getenv("PORT"); value != "" {
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid PORT env var: %w", err)
+		}
+		cfg.Port = port
+	}
+	if value := os.Getenv("STORAGE"); value != "" {
+		cfg.Storage = value
+	}
+	if value := os.Getenv("DBURI"); value != "" {
+		cfg.DbUri = value
+	}
+	if value := os.Getenv("DBNAME"); value != "" {
+		cfg.DbName = value
+	}
+	if value := os.Getenv("SESSION_SECRET"); value != "" {
+		cfg.SessionSecret = value
+	}
+	if value := os.Getenv("SECURE_COOKIES"); value != "" {
+		secureCookies, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid SECURE_COOKIES env var: %w", err)
+		}
+		cfg.SecureCookies = secureCookies
+	}
+	if value := os.Getenv("ADMIN_USERNAME"); value != "" {
+		cfg.AdminUsername = value
+	}
+	if value := os.Getenv("ADMIN_PASSWORD"); value != "" {
+		cfg.AdminPassword = value
+	}
+	if value := os.Getenv("STUDENT_DELETE_POLICY"); value != "" {
+		cfg.StudentDeletePolicy = value
+	}
+	if value := os.Getenv("NOTIFICATION_SENDER"); value != "" {
+		cfg.NotificationSender = value
+	}
+	if value := os.Getenv("NOTIFICATION_FILE"); value != "" {
+		cfg.NotificationFile = value
+	}
+	if value := os.Getenv("REMINDER_TIME"); value != "" {
+		cfg.ReminderTime = value
+	}
+	if value := os.Getenv("REMINDER_TEMPLATE"); value != "" {
+		cfg.ReminderTemplate = value
+	}
+
+	durations := []struct {
+		env   string
+		field *time.Duration
+	}{
+		{"DB_TIMEOUT", &cfg.DbTimeout},
+		{"QUERY_TIMEOUT", &cfg.QueryTimeout},
+		{"READ_TIMEOUT", &cfg.ReadTimeout},
+		{"WRITE_TIMEOUT", &cfg.WriteTimeout},
+		{"SHUTDOWN_TIMEOUT", &cfg.ShutdownTimeout},
+		{"SESSION_TTL", &cfg.SessionTTL},
+		{"SUBSCRIPTION_VALIDITY", &cfg.SubscriptionValidity},
+	}
+	for _, duration := range durations {
+		value := os.Getenv(duration.env)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid %v env var: %w", duration.env, err)
+		}
+		*duration.field = parsed
+	}
+
+	return nil
+}
+
+// Validate checks that all settings can be used to start the server
+func (cfg *Config) Validate() error {
+	var problems []string
+
+	if cfg.Port < 1 || cfg.Port > 65535 {
+		problems = append(problems, fmt.Sprintf("port must be between 1 and 65535, got %v", cfg.Port))
+	}
+	if cfg.Storage != MongoStorage && cfg.Storage != MemoryStorage {
+		problems = append(problems, fmt.Sprintf("storage must be %v or %v, got %q", MongoStorage, MemoryStorage, cfg.Storage))
+	}
+	if !strings.HasPrefix(cfg.DbUri, "mongodb://") && !strings.HasPrefix(cfg.DbUri, "mongodb+srv://") {
+		problems = append(problems, "db uri must start with mongodb:// or mongodb+srv://")
+	}
+	if cfg.DbName == "" || strings.ContainsAny(cfg.DbName, `/\. "$`) {
+		problems = append(problems, fmt.Sprintf("invalid db name %q", cfg.DbName))
+	}
+	if cfg.DbTimeout <= 0 {
+		problems = append(problems, "db timeout must be positive")
+	}
+	if cfg.QueryTimeout <= 0 {
+		problems = append(problems, "query timeout must be positive")
+	}
+	if cfg.ReadTimeout <= 0 {
+		problems = append(problems, "read timeout must be positive")
+	}
+	if cfg.WriteTimeout <= 0 {
+		problems = append(problems, "write timeout must be positive")
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		problems = append(problems, "shutdown timeout must be positive")
+	}
+	if cfg.SessionTTL <= 0 {
+		problems = append(problems, "session ttl must be positive")
+	}
+	if cfg.SubscriptionValidity <= 0 {
+		problems = append(problems, "subscription validity must be positive")
+	}
+	if cfg.StudentDeletePolicy != RejectStudentDelete && cfg.StudentDeletePolicy != CascadeStudentDelete && cfg.StudentDeletePolicy != KeepStudentDelete {
+		problems = append(problems, fmt.Sprintf("student delete policy must be %v, %v or %v, got %q", RejectStudentDelete, CascadeStudentDelete, KeepStudentDelete, cfg.StudentDeletePolicy))
+	}
+	if cfg.NotificationSender != NoNotificationSender && cfg.NotificationSender != LogNotificationSender && cfg.NotificationSender != FileNotificationSender {
+		problems = append(problems, fmt.Sprintf("notification sender must be %v, %v or %v, got %q", NoNotificationSender, LogNotificationSender, FileNotificationSender, cfg.NotificationSender))
+	}
+	if cfg.NotificationSender == FileNotificationSender && cfg.NotificationFile == "" {
+		problems = append(problems, "notification file must be set for the file sender")
+	}
+	if _, err := time.Parse("15:04", cfg.ReminderTime); err != nil {
+		problems = append(problems, fmt.Sprintf("reminder time must be HH:MM, got %q", cfg.ReminderTime))
+	}
+	if cfg.SessionSecret != "" && len(cfg.SessionSecret) < 32 {
+		problems = append(problems, "session secret must have at least 32 characters")
+	}
+	if (cfg.AdminUsername == "") != (cfg.AdminPassword == "") {
+		problems = append(problems, "admin username and admin password must be set together")
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+
+	return nil
+}
@@ -2,54 +2,102 @@
 package db
 
 import (
-    "log"
+	"context"
+	"errors"
+	"log"
+	"time"
 
-    "go.mongodb.org/mongo-driver/mongo"
-    "go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
 )
 
-const dbUri = "mongodb://localhost:27017"
-
+// Database holds the long-lived client shared by all handlers.
+// The driver keeps a connection pool inside the client, so it must be created once
 type Database struct {
-	Client *mongo.Client
+	Client       *mongo.Client
+	Name         string
+	QueryTimeout time.Duration
 }
 
-func DbConnect() *Database {
+// DbConnect creates the client and its connection pool.
+// An unreachable server is not an error here: the driver reconnects on its own
+// and the handlers answer 503 until the database is back.
+// timeout is how long the driver waits for a reachable server before failing an operation,
+// queryTimeout limits every single query started with QueryContext
+func DbConnect(uri string, name string, timeout time.Duration, queryTimeout time.Duration) (*Database, error) {
+	client, err := connectClient(uri, timeout)
+	if err != nil {
+		return nil, err
+	}
+
 	db := &Database{
-		Client: connectClient(),
+		Client:       client,
+		Name:         name,
+		QueryTimeout: queryTimeout,
 	}
 
-	return db
+	return db, nil
 }
 
-func connectClient() *mongo.Client {
-    client, err := mongo.NewClient(options.Client().ApplyURI(dbUri))
-    if err != nil {
-        log.Fatal(err)
-    }
+func connectClient(uri string, timeout time.Duration) (*mongo.Client, error) {
+	clientOptions := options.Client().
+		ApplyURI(uri).
+		SetServerSelectionTimeout(timeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
-    err = client.Connect(nil)
-    if err != nil {
-        log.Fatal(err)
-    }
+	client, err := mongo.Connect(ctx, clientOptions)
+	if err != nil {
+		return nil, err
+	}
 
-    err = client.Ping(nil, nil)
-    if err != nil {
-        log.Fatal(err)
-    }
+	err = client.Ping(ctx, nil)
+	if err != nil {
+		log.Printf("MongoDB is not reachable yet: %v", err)
+		return client, nil
+	}
 
-    log.Println("Connected to MongoDB")
-    return client
+	log.Println("Connected to MongoDB")
+	return client, nil
 }
 
+// Collection returns a handle for the collection in the application database
+func (db *Database) Collection(name string, opts ...*options.CollectionOptions) *mongo.Collection {
+	return db.Client.Database(db.Name).Collection(name, opts...)
+}
 
-// Close disconnects the MongoDB client and drops the connection
-func (db *Database) DbDisconnect() {
-	// Disconnect the MongoDB client without using context
-	err := db.Client.Disconnect(nil)
+// QueryContext derives the context for database calls of one request.
+// The query is cancelled when the client goes away or the query timeout passes
+func (db *Database) QueryContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, db.QueryTimeout)
+}
+
+// DbDisconnect closes all pooled connections; called once on shutdown
+func (db *Database) DbDisconnect(ctx context.Context) error {
+	err := db.Client.Disconnect(ctx)
 	if err != nil {
-		log.Fatalf("Failed to disconnect MongoDB client: %v", err)
+		return err
 	}
 
 	log.Println("Disconnected from MongoDB")
-}
\ No newline at end of file
+	return nil
+}
+
+// IsUnavailable reports whether the error means the database could not be reached,
+// as opposed to the query itself failing
+func IsUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, topology.ErrServerSelectionTimeout) || errors.Is(err, mongo.ErrClientDisconnected) {
+		return true
+	}
+	var selectionErr topology.ServerSelectionError
+	if errors.As(err, &selectionErr) {
+		return true
+	}
+
+	return mongo.IsNetworkError(err) || mongo.IsTimeout(err)
+}
@@ -1,33 +1,118 @@
 package errorHandling
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/DanVerh/artschool-admin/backend/api/db"
+)
+
+// Code is a machine-readable error code; the values never change once released,
+// so clients can rely on them instead of the message text
+type Code string
+
+// Catalogue of error codes returned by the API
+const (
+	MethodNotAllowed    Code = "METHOD_NOT_ALLOWED"
+	InvalidJSON         Code = "INVALID_JSON"
+	InvalidId           Code = "INVALID_ID"
+	ValidationFailed    Code = "VALIDATION_FAILED"
+	Unauthorized        Code = "UNAUTHORIZED"
+	InvalidCredentials  Code = "INVALID_CREDENTIALS"
+	Forbidden           Code = "FORBIDDEN"
+	NotFound            Code = "NOT_FOUND"
+	Duplicate           Code = "DUPLICATE"
+	NoClassesLeft       Code = "NO_CLASSES_LEFT"
+	ScheduleConflict    Code = "SCHEDULE_CONFLICT"
+	StudentHasClasses   Code = "STUDENT_HAS_CLASSES"
+	StudentArchived     Code = "STUDENT_ARCHIVED"
+	NotificationsOff    Code = "NOTIFICATIONS_OFF"
+	DatabaseUnavailable Code = "DATABASE_UNAVAILABLE"
+	Internal            Code = "INTERNAL_ERROR"
 )
 
+// HTTP status code returned with each error code
+var statusCodes = map[Code]int{
+	MethodNotAllowed:    http.StatusMethodNotAllowed,
+	InvalidJSON:         http.StatusBadRequest,
+	InvalidId:           http.StatusBadRequest,
+	ValidationFailed:    http.StatusBadRequest,
+	Unauthorized:        http.StatusUnauthorized,
+	InvalidCredentials:  http.StatusUnauthorized,
+	Forbidden:           http.StatusForbidden,
+	NotFound:            http.StatusNotFound,
+	Duplicate:           http.StatusConflict,
+	NoClassesLeft:       http.StatusConflict,
+	ScheduleConflict:    http.StatusConflict,
+	StudentHasClasses:   http.StatusConflict,
+	StudentArchived:     http.StatusConflict,
+	NotificationsOff:    http.StatusConflict,
+	DatabaseUnavailable: http.StatusServiceUnavailable,
+	Internal:            http.StatusInternalServerError,
+}
+
+// Detail describes a problem with one field of the request
+type Detail struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// Error is the JSON body of every error response
 type Error struct {
-	w               http.ResponseWriter
-	statusCode      int
-	responseMessage string
-	errorMessage    *error
+	Code      Code     `json:"code"`
+	Message   string   `json:"message"`
+	Details   []Detail `json:"details,omitempty"`
+	RequestId string   `json:"requestId,omitempty"`
 }
 
-func (e *Error) Init(w http.ResponseWriter, statusCode int, responseMessage string, errorMessage *error) {
-	e.w = w
-	e.statusCode = statusCode
-	e.responseMessage = responseMessage
-	e.errorMessage = errorMessage
+// StatusCode returns the HTTP status code for the error code
+func (code Code) StatusCode() int {
+	statusCode, found := statusCodes[code]
+	if !found {
+		return http.StatusInternalServerError
+	}
+
+	return statusCode
 }
 
-func ThrowError(w http.ResponseWriter, statusCode int, responseMessage string, errorMessage *error) {
-	e := &Error{}
-	e.Init(w, statusCode, responseMessage, errorMessage)
+// ThrowError logs the error and writes it to the response as JSON.
+// errorMessage is the internal cause; it is logged but never sent to the client
+func ThrowError(w http.ResponseWriter, r *http.Request, code Code, responseMessage string, errorMessage *error, details ...Detail) {
+	e := &Error{
+		Code:      code,
+		Message:   responseMessage,
+		Details:   details,
+		RequestId: middleware.GetReqID(r.Context()),
+	}
 
 	if errorMessage == nil {
-		log.Println(e.responseMessage)
+		log.Printf("[%v] %v: %v", e.RequestId, e.Code, e.Message)
 	} else {
-		log.Printf(e.responseMessage, "%v: ", e.errorMessage)
+		log.Printf("[%v] %v: %v: %v", e.RequestId, e.Code, e.Message, *errorMessage)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code.StatusCode())
+	json.NewEncoder(w).Encode(e)
+}
+
+// ThrowDbError responds 503 when the database cannot be reached and 500 for any other failure,
+// so a Mongo outage does not look like a bug in the request
+func ThrowDbError(w http.ResponseWriter, r *http.Request, responseMessage string, errorMessage error) {
+	// The client has gone away, nobody reads the response
+	if errors.Is(errorMessage, context.Canceled) {
+		log.Printf("[%v] Request cancelled: %v", middleware.GetReqID(r.Context()), responseMessage)
+		return
+	}
+	if db.IsUnavailable(errorMessage) {
+		ThrowError(w, r, DatabaseUnavailable, "Database is unavailable, try again later", &errorMessage)
+		return
 	}
 
-	http.Error(w, e.responseMessage, e.statusCode)
+	ThrowError(w, r, Internal, responseMessage, &errorMessage)
 }
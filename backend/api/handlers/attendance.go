@@ -0,0 +1,279 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+	"github.com/DanVerh/artschool-admin/backend/api/storage"
+)
+
+// Errors of the attendance transaction
+var (
+	errClassNotFound  = errors.New("schedule has no such class")
+	errAmbiguousClass = errors.New("student has several classes in the schedule")
+	errNoClassesLeft  = errors.New("student has no classes left in the subscription")
+)
+
+// Create struct (class) for AttendanceHandler to mark attendance of classes.
+// A visit uses one class of the student's active subscription pack, so the class, the pack
+// and the student are always changed together in one transaction
+type AttendanceHandler struct {
+	Schedules     storage.ScheduleRepository
+	Students      storage.StudentRepository
+	Subscriptions storage.SubscriptionRepository
+	Audit         storage.AuditRepository
+	Transactions  storage.Transactor
+}
+
+// Response of the attendance marking
+type attendanceResponse struct {
+	Class   models.Class    `json:"class"`
+	Student *models.Student `json:"student"`
+}
+
+// POST for marking attendance of a class
+// The class is found by its id; a student id also works when the student has only one class on the day.
+// Body is {"attendance": true} for presence, false for absence and null to un-mark.
+// Presence uses one class of the pack active on the date of the schedule, so the subscription goes down
+// and becomes null when no pack is left; startDate is set on the first visit and lastDate on every visit.
+// Un-marking a presence reverses it
+func (attendanceHandler *AttendanceHandler) Mark(w http.ResponseWriter, r *http.Request) {
+	// Extract the ObjectIds from the URL path
+	scheduleId, ok := parseObjectId(w, r, "id")
+	if !ok {
+		return
+	}
+	classId, ok := parseObjectId(w, r, "classId")
+	if !ok {
+		return
+	}
+
+	// Decode the body; attendance must be present, null is a valid value
+	var body map[string]interface{}
+	jsonDecoder := json.NewDecoder(r.Body)
+	err := jsonDecoder.Decode(&body)
+	if err != nil {
+		errorHandling.ThrowError(w, r, errorHandling.InvalidJSON, "Invalid request body", nil)
+		return
+	}
+	value, found := body["attendance"]
+	_, isBool := value.(bool)
+	if !found || (value != nil && !isBool) || len(body) != 1 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid attendance", nil, errorHandling.Detail{Field: "attendance", Message: "body must be {\"attendance\": true, false or null}"})
+		return
+	}
+	var attendance *bool
+	if isBool {
+		present := value.(bool)
+		attendance = &present
+	}
+
+	// States before and after the change for the response and the audit log
+	var scheduleBefore, scheduleAfter *models.Schedule
+	var studentBefore, studentAfter *models.Student
+	var class models.Class
+	var studentId primitive.ObjectID
+
+	err = attendanceHandler.Transactions.WithTransaction(r.Context(), func(ctx context.Context) error {
+		schedule, err := attendanceHandler.Schedules.Get(ctx, scheduleId)
+		if err != nil {
+			return err
+		}
+		classIndex, err := findClass(schedule.Classes, classId)
+		if err != nil {
+			return err
+		}
+		studentId = schedule.Classes[classIndex].StudentId
+		student, err := attendanceHandler.Students.Get(ctx, studentId)
+		if err != nil {
+			return err
+		}
+
+		before := *schedule
+		before.Classes = slices.Clone(schedule.Classes)
+		scheduleBefore, studentBefore = &before, student
+
+		// Change the subscription only when presence is marked or un-marked
+		date := schedule.Date.Time().UTC()
+		currentClass := &schedule.Classes[classIndex]
+		wasPresent := isPresent(currentClass.Attendence)
+		var fields map[string]interface{}
+		if !wasPresent && isPresent(attendance) {
+			fields, err = attendanceHandler.useClass(ctx, currentClass, student, date)
+		} else if wasPresent && !isPresent(attendance) {
+			fields, err = attendanceHandler.returnClass(ctx, currentClass, student, date)
+		}
+		if err != nil {
+			return err
+		}
+
+		currentClass.Attendence = attendance
+		err = attendanceHandler.Schedules.Update(ctx, schedule)
+		if err != nil {
+			return err
+		}
+		scheduleAfter, class = schedule, *currentClass
+
+		if len(fields) > 0 {
+			err = attendanceHandler.Students.Update(ctx, studentId, fields)
+			if err != nil {
+				return err
+			}
+		}
+		_, studentAfter, err = syncSubscription(ctx, attendanceHandler.Students, attendanceHandler.Subscriptions, studentId)
+		return err
+	})
+	if errors.Is(err, errClassNotFound) {
+		errorHandling.ThrowError(w, r, errorHandling.NotFound, "Schedule has no class with this id", nil)
+		return
+	}
+	if errors.Is(err, errAmbiguousClass) {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Student has several classes in this schedule", nil, errorHandling.Detail{Field: "classId", Message: "use the id of the class instead of the student id"})
+		return
+	}
+	if errors.Is(err, errNoClassesLeft) {
+		errorHandling.ThrowError(w, r, errorHandling.NoClassesLeft, "Student has no classes left in the subscription", nil, errorHandling.Detail{Field: "subscription", Message: "subscription is used up or not set"})
+		return
+	}
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to mark attendance")
+		return
+	}
+
+	recordAudit(r, attendanceHandler.Audit, auditSchedule, scheduleId, models.AuditUpdate, scheduleBefore, scheduleAfter)
+	if studentAfter != studentBefore {
+		recordAudit(r, attendanceHandler.Audit, auditStudent, studentId, models.AuditUpdate, studentBefore, studentAfter)
+	}
+
+	writeJSON(w, http.StatusOK, attendanceResponse{Class: class, Student: studentAfter})
+}
+
+// Find the class by its id, or by the student id when the student has only one class
+func findClass(classes []models.Class, id primitive.ObjectID) (int, error) {
+	if index := slices.IndexFunc(classes, func(class models.Class) bool { return class.Id == id }); index >= 0 {
+		return index, nil
+	}
+
+	classIndex := -1
+	for index, class := range classes {
+		if class.StudentId != id {
+			continue
+		}
+		if classIndex >= 0 {
+			return -1, errAmbiguousClass
+		}
+		classIndex = index
+	}
+	if classIndex < 0 {
+		return -1, errClassNotFound
+	}
+
+	return classIndex, nil
+}
+
+func isPresent(attendance *bool) bool {
+	return attendance != nil && *attendance
+}
+
+// Use a class of the pack active on date for the visit; returns the changed student dates
+func (attendanceHandler *AttendanceHandler) useClass(ctx context.Context, class *models.Class, student *models.Student, date time.Time) (map[string]interface{}, error) {
+	subscriptions, err := attendanceHandler.Subscriptions.ListByStudent(ctx, student.Id)
+	if err != nil {
+		return nil, err
+	}
+	active := activeSubscription(subscriptions, date)
+	if active == nil {
+		return nil, errNoClassesLeft
+	}
+	active.Consumed++
+	err = attendanceHandler.Subscriptions.Update(ctx, active)
+	if err != nil {
+		return nil, err
+	}
+	class.SubscriptionId = &active.Id
+
+	fields := map[string]interface{}{}
+	if student.StartDate == nil || date.Before(*student.StartDate) {
+		fields["startDate"] = date
+	}
+	if student.LastDate == nil || date.After(*student.LastDate) {
+		fields["lastDate"] = date
+	}
+
+	return fields, nil
+}
+
+// Give the class of the un-marked visit back to its pack; returns the changed student dates.
+// Dates set by this visit move to the nearest other visits of the student
+func (attendanceHandler *AttendanceHandler) returnClass(ctx context.Context, class *models.Class, student *models.Student, date time.Time) (map[string]interface{}, error) {
+	// Visits marked before subscription packs existed do not have a pack
+	if class.SubscriptionId != nil {
+		subscription, err := attendanceHandler.Subscriptions.Get(ctx, *class.SubscriptionId)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return nil, err
+		}
+		if err == nil && subscription.Consumed > 0 {
+			subscription.Consumed--
+			err = attendanceHandler.Subscriptions.Update(ctx, subscription)
+			if err != nil {
+				return nil, err
+			}
+		}
+		class.SubscriptionId = nil
+	}
+
+	fields := map[string]interface{}{}
+
+	startSetHere := student.StartDate != nil && student.StartDate.Equal(date)
+	lastSetHere := student.LastDate != nil && student.LastDate.Equal(date)
+	if !startSetHere && !lastSetHere {
+		return fields, nil
+	}
+
+	// Other visits of the student, ordered by date
+	schedules, err := attendanceHandler.Schedules.ListByStudent(ctx, student.Id)
+	if err != nil {
+		return nil, err
+	}
+	var visits []time.Time
+	for _, schedule := range schedules {
+		for _, other := range schedule.Classes {
+			if other.Id != class.Id && other.StudentId == student.Id && isPresent(other.Attendence) {
+				visits = append(visits, schedule.Date.Time().UTC())
+			}
+		}
+	}
+
+	startDate := student.StartDate
+	if startSetHere {
+		// The next visit becomes the first one, another visit on the same day keeps the date
+		startDate = nil
+		for _, visit := range visits {
+			if !visit.Before(date) {
+				startDate = &visit
+				break
+			}
+		}
+		fields["startDate"] = startDate
+	}
+	if lastSetHere {
+		// The latest other visit since the start becomes the last one
+		var lastDate *time.Time
+		for _, visit := range visits {
+			if startDate != nil && !visit.Before(*startDate) {
+				lastDate = &visit
+			}
+		}
+		fields["lastDate"] = lastDate
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/auth"
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+	"github.com/DanVerh/artschool-admin/backend/api/storage"
+)
+
+// Audited entities
+const (
+	auditStudent  = "student"
+	auditSchedule = "schedule"
+	auditTeacher  = "teacher"
+)
+
+// AuditEntities lists every audited entity; the schema of the audit collection must allow all of them
+var AuditEntities = []string{auditStudent, auditSchedule, auditTeacher}
+
+// Limits of the audit list
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// Create struct (class) for AuditHandler to read the audit log
+type AuditHandler struct {
+	Audit storage.AuditRepository
+}
+
+// GET for audit entries, newest first
+// Filters: entity, entityId, actor, from and to (RFC3339 or YYYY-MM-DD, to is exclusive), limit
+func (auditHandler *AuditHandler) List(w http.ResponseWriter, r *http.Request) {
+	query, details := parseAuditQuery(r)
+	if len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid audit filters", nil, details...)
+		return
+	}
+
+	entries, err := auditHandler.Audit.List(r.Context(), query)
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve audit entries from the database", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// Read the audit filters from the query string
+func parseAuditQuery(r *http.Request) (models.AuditQuery, []errorHandling.Detail) {
+	values := r.URL.Query()
+	query := models.AuditQuery{
+		Entity: values.Get("entity"),
+		Actor:  values.Get("actor"),
+		Limit:  defaultAuditLimit,
+	}
+	var details []errorHandling.Detail
+
+	if query.Entity != "" && !slices.Contains(AuditEntities, query.Entity) {
+		details = append(details, errorHandling.Detail{Field: "entity", Message: "entity must be one of student, schedule, teacher"})
+	}
+	if entityId := values.Get("entityId"); entityId != "" {
+		objectID, err := primitive.ObjectIDFromHex(entityId)
+		if err != nil {
+			details = append(details, errorHandling.Detail{Field: "entityId", Message: "must be a 24 characters hex ObjectId"})
+		}
+		query.EntityId = objectID
+	}
+	for _, filter := range []struct {
+		name  string
+		value *time.Time
+	}{{"from", &query.From}, {"to", &query.To}} {
+		text := values.Get(filter.name)
+		if text == "" {
+			continue
+		}
+		parsed, err := parseTimeParam(text)
+		if err != nil {
+			details = append(details, errorHandling.Detail{Field: filter.name, Message: "must be an RFC3339 time or a YYYY-MM-DD date"})
+		}
+		*filter.value = parsed
+	}
+	if limit := values.Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed < 1 || parsed > maxAuditLimit {
+			details = append(details, errorHandling.Detail{Field: "limit", Message: "limit must be between 1 and " + strconv.Itoa(maxAuditLimit)})
+		}
+		query.Limit = parsed
+	}
+
+	return query, details
+}
+
+// Save the audit entry of a mutation; before is nil for create and after is nil for delete.
+// The mutation is already done, so a failure is only logged and never fails the request
+func recordAudit(r *http.Request, audit storage.AuditRepository, entity string, entityId primitive.ObjectID, action string, before interface{}, after interface{}) {
+	entry := &models.AuditEntry{
+		Id:        primitive.NewObjectID(),
+		Timestamp: time.Now().UTC(),
+		Entity:    entity,
+		EntityId:  entityId,
+		Action:    action,
+	}
+	if user := auth.UserFromContext(r.Context()); user != nil {
+		entry.ActorId, entry.Actor = user.Id, user.Username
+	}
+
+	// The change is already saved, so the entry is written even when the client has gone away
+	changes, err := diffFields(before, after)
+	if err == nil {
+		entry.Changes = changes
+		err = audit.Create(context.WithoutCancel(r.Context()), entry)
+	}
+	if err != nil {
+		log.Printf("[%v] Failed to record audit of %v %v %v: %v", middleware.GetReqID(r.Context()), action, entity, entityId.Hex(), err)
+	}
+}
+
+// Compare the JSON forms of two documents and return the changed fields sorted by name
+func diffFields(before interface{}, after interface{}) ([]models.FieldChange, error) {
+	beforeFields, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]bool{}
+	for name := range beforeFields {
+		names[name] = true
+	}
+	for name := range afterFields {
+		names[name] = true
+	}
+
+	changes := []models.FieldChange{}
+	for name := range names {
+		// The id never changes and is stored as entityId
+		if name == "id" || reflect.DeepEqual(beforeFields[name], afterFields[name]) {
+			continue
+		}
+		changes = append(changes, models.FieldChange{Field: name, Before: beforeFields[name], After: afterFields[name]})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+
+	return changes, nil
+}
+
+// Convert a document to a map of its JSON fields; null fields are left out
+func jsonFields(document interface{}) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if document == nil || reflect.ValueOf(document).IsNil() {
+		return fields, nil
+	}
+
+	data, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range fields {
+		if value == nil {
+			delete(fields, name)
+		}
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/DanVerh/artschool-admin/backend/api/auth"
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+)
+
+// Create struct (class) for AuthHandler to handle login and logout
+type AuthHandler struct {
+	Auth *auth.Manager
+	// Send the session cookie over HTTPS only
+	SecureCookies bool
+}
+
+// Credentials of the login request
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Response of the login request; the token can be sent as "Authorization: Bearer <token>"
+// by clients that do not use the cookie
+type loginResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+}
+
+// POST for login
+func (authHandler *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	// Parse JSON request body with credentials
+	credentials := loginRequest{}
+	jsonDecoder := json.NewDecoder(r.Body)
+	err := jsonDecoder.Decode(&credentials)
+	if err != nil {
+		errorHandling.ThrowError(w, r, errorHandling.InvalidJSON, "Invalid JSON", nil)
+		return
+	}
+	if credentials.Username == "" || credentials.Password == "" {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Missing username or password", nil)
+		return
+	}
+
+	token, session, user, err := authHandler.Auth.Login(r.Context(), credentials.Username, credentials.Password)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		errorHandling.ThrowError(w, r, errorHandling.InvalidCredentials, "Invalid username or password", nil)
+		return
+	}
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to log in", err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.CookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		HttpOnly: true,
+		Secure:   authHandler.SecureCookies,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	writeJSON(w, http.StatusOK, loginResponse{Token: token, ExpiresAt: session.ExpiresAt, Username: user.Username, Role: user.Role})
+}
+
+// POST for logout
+func (authHandler *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	token := auth.TokenFromRequest(r)
+	if token == "" {
+		errorHandling.ThrowError(w, r, errorHandling.Unauthorized, "Authentication required", nil)
+		return
+	}
+
+	err := authHandler.Auth.Logout(r.Context(), token)
+	if errors.Is(err, auth.ErrInvalidToken) {
+		errorHandling.ThrowError(w, r, errorHandling.Unauthorized, "Session is invalid or expired", nil)
+		return
+	}
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to log out", err)
+		return
+	}
+
+	// Remove the cookie in the browser
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.CookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   authHandler.SecureCookies,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,276 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+	"github.com/DanVerh/artschool-admin/backend/api/storage"
+)
+
+// Length of every class; the schedule keeps only the start time
+const classDuration = time.Hour
+
+// How far back the feeds go when the request does not set from
+const defaultCalendarDays = 30
+
+// Create struct (class) for CalendarHandler to publish the schedule as iCalendar (ICS) feeds.
+// Every class is one event with the class id as UID, so changed classes replace their events;
+// removed classes stay in the feeds as cancelled events
+type CalendarHandler struct {
+	Schedules     storage.ScheduleRepository
+	Cancellations storage.CancellationRepository
+	Students      storage.StudentRepository
+	Teachers      storage.TeacherRepository
+	Rooms         storage.RoomRepository
+}
+
+// One event of the feed
+type calendarEvent struct {
+	date      time.Time
+	class     models.Class
+	cancelled bool
+}
+
+// GET for the feed of the whole school
+func (calendarHandler *CalendarHandler) School(w http.ResponseWriter, r *http.Request) {
+	from, ok := parseCalendarFrom(w, r)
+	if !ok {
+		return
+	}
+
+	schedules, err := calendarHandler.Schedules.List(r.Context(), models.ScheduleQuery{From: from})
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve schedules", err)
+		return
+	}
+	cancellations, err := calendarHandler.Cancellations.List(r.Context(), models.CancellationQuery{From: from})
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve cancelled classes", err)
+		return
+	}
+
+	calendarHandler.writeCalendar(w, r, "Art school", calendarEvents(schedules, cancellations, func(class models.Class) bool { return true }))
+}
+
+// GET for the feed of one teacher
+func (calendarHandler *CalendarHandler) Teacher(w http.ResponseWriter, r *http.Request) {
+	teacherId, ok := parseObjectId(w, r, "id")
+	if !ok {
+		return
+	}
+	from, ok := parseCalendarFrom(w, r)
+	if !ok {
+		return
+	}
+
+	teacher, err := calendarHandler.Teachers.Get(r.Context(), teacherId)
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to retrieve document")
+		return
+	}
+	schedules, err := calendarHandler.Schedules.List(r.Context(), models.ScheduleQuery{TeacherId: &teacherId, From: from})
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve schedules", err)
+		return
+	}
+	cancellations, err := calendarHandler.Cancellations.List(r.Context(), models.CancellationQuery{TeacherId: &teacherId, From: from})
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve cancelled classes", err)
+		return
+	}
+
+	events := calendarEvents(schedules, cancellations, func(class models.Class) bool {
+		return class.TeacherId != nil && *class.TeacherId == teacherId
+	})
+	calendarHandler.writeCalendar(w, r, "Classes of "+teacher.Fullname, events)
+}
+
+// GET for the feed of one student
+func (calendarHandler *CalendarHandler) Student(w http.ResponseWriter, r *http.Request) {
+	studentId, ok := parseObjectId(w, r, "id")
+	if !ok {
+		return
+	}
+	from, ok := parseCalendarFrom(w, r)
+	if !ok {
+		return
+	}
+
+	student, err := calendarHandler.Students.Get(r.Context(), studentId)
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to retrieve document")
+		return
+	}
+	schedules, err := calendarHandler.Schedules.ListByStudent(r.Context(), studentId)
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve schedules", err)
+		return
+	}
+	var upcoming []models.Schedule
+	for _, schedule := range schedules {
+		if !schedule.Date.Time().Before(from) {
+			upcoming = append(upcoming, schedule)
+		}
+	}
+	cancellations, err := calendarHandler.Cancellations.List(r.Context(), models.CancellationQuery{StudentId: &studentId, From: from})
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve cancelled classes", err)
+		return
+	}
+
+	events := calendarEvents(upcoming, cancellations, func(class models.Class) bool { return class.StudentId == studentId })
+	calendarHandler.writeCalendar(w, r, "Classes of "+student.Fullname, events)
+}
+
+// Read the first day of the feed; 30 days ago by default, so past events are not lost right away
+func parseCalendarFrom(w http.ResponseWriter, r *http.Request) (time.Time, bool) {
+	text := r.URL.Query().Get("from")
+	if text == "" {
+		return dayStart(time.Now()).AddDate(0, 0, -defaultCalendarDays), true
+	}
+
+	from, err := parseDay(text)
+	if err != nil {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid calendar query", nil, errorHandling.Detail{Field: "from", Message: "must be an RFC3339 time or a YYYY-MM-DD date"})
+		return time.Time{}, false
+	}
+
+	return from, true
+}
+
+// Events of the classes matching the filter and of their cancellations
+func calendarEvents(schedules []models.Schedule, cancellations []models.Cancellation, match func(class models.Class) bool) []calendarEvent {
+	var events []calendarEvent
+	for _, schedule := range schedules {
+		for _, class := range schedule.Classes {
+			if match(class) {
+				events = append(events, calendarEvent{date: schedule.Date.Time(), class: class})
+			}
+		}
+	}
+	for _, cancellation := range cancellations {
+		if match(cancellation.Class) {
+			events = append(events, calendarEvent{date: cancellation.Date.Time(), class: cancellation.Class, cancelled: true})
+		}
+	}
+
+	return events
+}
+
+// Write the events as an iCalendar document
+func (calendarHandler *CalendarHandler) writeCalendar(w http.ResponseWriter, r *http.Request, name string, events []calendarEvent) {
+	names := newNameCache(calendarHandler.Students, calendarHandler.Teachers, calendarHandler.Rooms)
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+
+	var builder strings.Builder
+	writeLine := func(name string, value string) {
+		builder.WriteString(foldLine(name + ":" + value))
+	}
+	writeLine("BEGIN", "VCALENDAR")
+	writeLine("VERSION", "2.0")
+	writeLine("PRODID", "-//Art School Admin//Schedule//EN")
+	writeLine("CALSCALE", "GREGORIAN")
+	writeLine("METHOD", "PUBLISH")
+	writeLine("X-WR-CALNAME", escapeText(name))
+
+	for _, event := range events {
+		class := event.class
+		studentName, err := names.student(r.Context(), class.StudentId)
+		if err != nil {
+			errorHandling.ThrowDbError(w, r, "Failed to retrieve students", err)
+			return
+		}
+		start := classTime(event.date, class.Time)
+
+		writeLine("BEGIN", "VEVENT")
+		writeLine("UID", class.Id.Hex()+"@artschool-admin")
+		writeLine("DTSTAMP", stamp)
+		// Floating times: classes happen at the same wall-clock time wherever the calendar is
+		writeLine("DTSTART", start.Format("20060102T150405"))
+		writeLine("DTEND", start.Add(classDuration).Format("20060102T150405"))
+		writeLine("SUMMARY", escapeText(fmt.Sprintf("%v class: %v", strings.ToUpper(class.Type[:1])+class.Type[1:], studentName)))
+		if class.RoomId != nil {
+			roomName, err := names.room(r.Context(), *class.RoomId)
+			if err != nil {
+				errorHandling.ThrowDbError(w, r, "Failed to retrieve rooms", err)
+				return
+			}
+			writeLine("LOCATION", escapeText(roomName))
+		}
+		if class.TeacherId != nil {
+			teacherName, err := names.teacher(r.Context(), *class.TeacherId)
+			if err != nil {
+				errorHandling.ThrowDbError(w, r, "Failed to retrieve teachers", err)
+				return
+			}
+			writeLine("DESCRIPTION", escapeText("Teacher: "+teacherName))
+		}
+		// A cancellation is a newer version of the event
+		if event.cancelled {
+			writeLine("STATUS", "CANCELLED")
+			writeLine("SEQUENCE", "1")
+		} else {
+			writeLine("STATUS", "CONFIRMED")
+			writeLine("SEQUENCE", "0")
+		}
+		writeLine("END", "VEVENT")
+	}
+	writeLine("END", "VCALENDAR")
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(builder.String()))
+}
+
+// Escape a TEXT value of iCalendar
+func escapeText(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return replacer.Replace(text)
+}
+
+// Fold a content line at 75 octets and end it with CRLF, as iCalendar requires;
+// continuation lines start with a space and multi-byte characters are never split
+func foldLine(line string) string {
+	var builder strings.Builder
+	length := 0
+	for _, char := range line {
+		size := len(string(char))
+		if length+size > 75 {
+			builder.WriteString("\r\n ")
+			length = 1
+		}
+		builder.WriteRune(char)
+		length += size
+	}
+	builder.WriteString("\r\n")
+
+	return builder.String()
+}
+
+// Save the removed classes so calendar feeds can cancel their events.
+// The classes are already removed, so a failure is only logged and never fails the request
+func recordCancellations(r *http.Request, cancellations storage.CancellationRepository, schedule *models.Schedule, classes []models.Class) {
+	ctx := context.WithoutCancel(r.Context())
+	for _, class := range classes {
+		cancellation := &models.Cancellation{
+			Id:          class.Id,
+			ScheduleId:  schedule.Id,
+			Date:        schedule.Date,
+			Class:       class,
+			CancelledAt: time.Now().UTC(),
+		}
+		err := cancellations.Create(ctx, cancellation)
+		if err != nil && !errors.Is(err, storage.ErrDuplicate) {
+			log.Printf("[%v] Failed to record cancellation of class %v: %v", middleware.GetReqID(r.Context()), class.Id.Hex(), err)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+	"github.com/DanVerh/artschool-admin/backend/api/storage"
+)
+
+// Fields of a class that PATCH can change; the student stays, attendance has its own endpoint
+var classPatchFields = []string{"time", "type", "teacherId", "roomId"}
+
+// POST for adding a class to the schedule; a student can have several classes on one day
+func (scheduleHandler *ScheduleHandler) AddClass(w http.ResponseWriter, r *http.Request) {
+	scheduleId, ok := parseObjectId(w, r, "id")
+	if !ok {
+		return
+	}
+
+	class := models.Class{}
+	jsonDecoder := json.NewDecoder(r.Body)
+	err := jsonDecoder.Decode(&class)
+	if err != nil {
+		errorHandling.ThrowError(w, r, errorHandling.InvalidJSON, "Invalid request body", nil)
+		return
+	}
+	details := validateClass(&class, "")
+	if class.Attendence != nil {
+		details = append(details, errorHandling.Detail{Field: "attendance", Message: attendanceMessage})
+	}
+	if len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid class fields", nil, details...)
+		return
+	}
+	class.Id, class.SubscriptionId, class.StudentDeleted = primitive.NewObjectID(), nil, false
+
+	before, err := scheduleHandler.Schedules.Get(r.Context(), scheduleId)
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to retrieve document")
+		return
+	}
+	if !scheduleHandler.checkClass(w, r, append(slices.Clone(before.Classes), class), len(before.Classes)) {
+		return
+	}
+
+	err = scheduleHandler.Schedules.AddClass(r.Context(), scheduleId, &class)
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to add class")
+		return
+	}
+	scheduleHandler.auditClassChange(r, before)
+
+	writeJSON(w, http.StatusCreated, class)
+}
+
+// PATCH for changing fields of one class; teacherId and roomId can be set to null
+func (scheduleHandler *ScheduleHandler) UpdateClass(w http.ResponseWriter, r *http.Request) {
+	scheduleId, ok := parseObjectId(w, r, "id")
+	if !ok {
+		return
+	}
+	classId, ok := parseObjectId(w, r, "classId")
+	if !ok {
+		return
+	}
+
+	var body map[string]json.RawMessage
+	jsonDecoder := json.NewDecoder(r.Body)
+	err := jsonDecoder.Decode(&body)
+	if err != nil {
+		errorHandling.ThrowError(w, r, errorHandling.InvalidJSON, "Invalid request body", nil)
+		return
+	}
+	var details []errorHandling.Detail
+	if len(body) == 0 {
+		details = append(details, errorHandling.Detail{Message: "no class field is updated"})
+	}
+	for key := range body {
+		if key == "attendance" {
+			details = append(details, errorHandling.Detail{Field: key, Message: attendanceMessage})
+		} else if !slices.Contains(classPatchFields, key) {
+			details = append(details, errorHandling.Detail{Field: key, Message: "field can not be changed, add a new class instead"})
+		}
+	}
+	if len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid class fields", nil, details...)
+		return
+	}
+
+	before, err := scheduleHandler.Schedules.Get(r.Context(), scheduleId)
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to retrieve document")
+		return
+	}
+	index := slices.IndexFunc(before.Classes, func(class models.Class) bool { return class.Id == classId })
+	if index < 0 {
+		errorHandling.ThrowError(w, r, errorHandling.NotFound, "Schedule has no class with this id", nil)
+		return
+	}
+
+	// Apply the body on top of the current class through its JSON representation
+	document := map[string]json.RawMessage{}
+	content, _ := json.Marshal(before.Classes[index])
+	json.Unmarshal(content, &document)
+	for key, value := range body {
+		document[key] = value
+	}
+	content, _ = json.Marshal(document)
+	class := models.Class{}
+	err = json.Unmarshal(content, &class)
+	if err != nil {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid class fields", nil, errorHandling.Detail{Message: "fields have invalid types"})
+		return
+	}
+	if details := validateClass(&class, ""); len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid class fields", nil, details...)
+		return
+	}
+
+	classes := slices.Clone(before.Classes)
+	classes[index] = class
+	if !scheduleHandler.checkClass(w, r, classes, index) {
+		return
+	}
+
+	// Only the fields of the body are saved, so attendance marked meanwhile is kept
+	fields := slices.Sorted(maps.Keys(body))
+	err = scheduleHandler.Schedules.UpdateClass(r.Context(), scheduleId, &class, fields)
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to update class")
+		return
+	}
+	after := scheduleHandler.auditClassChange(r, before)
+	if after != nil {
+		if index := slices.IndexFunc(after.Classes, func(saved models.Class) bool { return saved.Id == classId }); index >= 0 {
+			class = after.Classes[index]
+		}
+	}
+
+	writeJSON(w, http.StatusOK, class)
+}
+
+// DELETE for removing one class; a visit must be un-marked first so its pack gets the class back
+func (scheduleHandler *ScheduleHandler) DeleteClass(w http.ResponseWriter, r *http.Request) {
+	scheduleId, ok := parseObjectId(w, r, "id")
+	if !ok {
+		return
+	}
+	classId, ok := parseObjectId(w, r, "classId")
+	if !ok {
+		return
+	}
+
+	before, err := scheduleHandler.Schedules.Get(r.Context(), scheduleId)
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to retrieve document")
+		return
+	}
+	index := slices.IndexFunc(before.Classes, func(class models.Class) bool { return class.Id == classId })
+	if index < 0 {
+		errorHandling.ThrowError(w, r, errorHandling.NotFound, "Schedule has no class with this id", nil)
+		return
+	}
+	if isPresent(before.Classes[index].Attendence) {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Class was attended", nil, errorHandling.Detail{Field: "attendance", Message: "un-mark the attendance before removing the class"})
+		return
+	}
+
+	err = scheduleHandler.Schedules.RemoveClass(r.Context(), scheduleId, classId)
+	// Marked present after it was read
+	if errors.Is(err, storage.ErrAttended) {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Class was attended", nil, errorHandling.Detail{Field: "attendance", Message: "un-mark the attendance before removing the class"})
+		return
+	}
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to remove class")
+		return
+	}
+	scheduleHandler.auditClassChange(r, before)
+	recordCancellations(r, scheduleHandler.Cancellations, before, before.Classes[index:index+1])
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Check the student, the teacher, the room and the conflicts of the class at index among the classes of the day;
+// responds with the error and returns false when the class is not valid
+func (scheduleHandler *ScheduleHandler) checkClass(w http.ResponseWriter, r *http.Request, classes []models.Class, index int) bool {
+	details, err := checkReferences(r.Context(), scheduleHandler.Students, scheduleHandler.Teachers, scheduleHandler.Rooms, &classes[index], "")
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve student, teacher or room", err)
+		return false
+	}
+	if len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid class fields", nil, details...)
+		return false
+	}
+
+	details, err = conflictDetails(r.Context(), scheduleHandler.Rooms, classes, index)
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve rooms", err)
+		return false
+	}
+	if len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ScheduleConflict, "Class conflicts with other classes of the day", nil, details...)
+		return false
+	}
+
+	return true
+}
+
+// Record the change of the schedule in the audit log with the schedule as it is saved now;
+// returns the saved schedule, nil when it could not be read and no entry was recorded
+func (scheduleHandler *ScheduleHandler) auditClassChange(r *http.Request, before *models.Schedule) *models.Schedule {
+	after, err := scheduleHandler.Schedules.Get(r.Context(), before.Id)
+	if err != nil {
+		log.Printf("[%v] Failed to read updated schedule %v for the audit log: %v", middleware.GetReqID(r.Context()), before.Id.Hex(), err)
+		return nil
+	}
+	recordAudit(r, scheduleHandler.Audit, auditSchedule, before.Id, models.AuditUpdate, before, after)
+	return after
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+	"github.com/DanVerh/artschool-admin/backend/api/storage"
+)
+
+// A booking problem between classes of one day
+type conflict struct {
+	// Class the conflict is reported on and its field
+	index int
+	field string
+	// All classes taking part in the conflict
+	classes []int
+	message string
+}
+
+// Find the booking conflicts between classes of one day:
+// more classes in a room than its capacity, a student booked twice at the same time
+// and a teacher booked twice at the same time, unless both classes are in the same room.
+// Classes without a room are not counted in any room
+func findConflicts(classes []models.Class, rooms map[primitive.ObjectID]models.Room) []conflict {
+	var conflicts []conflict
+
+	// Indexes of the classes of every time slot, in the order of the classes
+	var times []string
+	slots := map[string][]int{}
+	for index, class := range classes {
+		if _, found := slots[class.Time]; !found {
+			times = append(times, class.Time)
+		}
+		slots[class.Time] = append(slots[class.Time], index)
+	}
+
+	for _, time := range times {
+		var roomIds []primitive.ObjectID
+		roomClasses := map[primitive.ObjectID][]int{}
+		studentClasses := map[primitive.ObjectID]int{}
+		teacherClasses := map[primitive.ObjectID]int{}
+
+		for _, index := range slots[time] {
+			class := classes[index]
+
+			if first, found := studentClasses[class.StudentId]; found {
+				conflicts = append(conflicts, conflict{index: index, field: "studentId", classes: []int{first, index}, message: "student is already booked at " + time})
+			} else {
+				studentClasses[class.StudentId] = index
+			}
+
+			// A teacher runs one class at a time; classes in the same room at the same time are one group class
+			if class.TeacherId != nil {
+				first, found := teacherClasses[*class.TeacherId]
+				if !found {
+					teacherClasses[*class.TeacherId] = index
+				} else if class.RoomId == nil || classes[first].RoomId == nil || *classes[first].RoomId != *class.RoomId {
+					conflicts = append(conflicts, conflict{index: index, field: "teacherId", classes: []int{first, index}, message: "teacher is already booked at " + time})
+				}
+			}
+
+			if class.RoomId == nil {
+				continue
+			}
+			if _, found := roomClasses[*class.RoomId]; !found {
+				roomIds = append(roomIds, *class.RoomId)
+			}
+			roomClasses[*class.RoomId] = append(roomClasses[*class.RoomId], index)
+		}
+
+		for _, roomId := range roomIds {
+			room, found := rooms[roomId]
+			booked := roomClasses[roomId]
+			if !found || len(booked) <= room.Capacity {
+				continue
+			}
+			message := fmt.Sprintf("room %v has %v places, %v classes are booked at %v", room.Name, room.Capacity, len(booked), time)
+			conflicts = append(conflicts, conflict{index: booked[room.Capacity], field: "roomId", classes: booked, message: message})
+		}
+	}
+
+	return conflicts
+}
+
+// Load the rooms of the classes; rooms that do not exist anymore are left out
+func classRooms(ctx context.Context, roomRepository storage.RoomRepository, classes []models.Class) (map[primitive.ObjectID]models.Room, error) {
+	rooms := map[primitive.ObjectID]models.Room{}
+	for _, class := range classes {
+		if class.RoomId == nil {
+			continue
+		}
+		if _, found := rooms[*class.RoomId]; found {
+			continue
+		}
+		room, err := roomRepository.Get(ctx, *class.RoomId)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		rooms[room.Id] = *room
+	}
+
+	return rooms, nil
+}
+
+// Conflicts of the day as response details. With changed >= 0 only the conflicts of that class
+// are reported, without the classes[i]. prefix, so days booked before the checks existed can still be changed
+func conflictDetails(ctx context.Context, roomRepository storage.RoomRepository, classes []models.Class, changed int) ([]errorHandling.Detail, error) {
+	rooms, err := classRooms(ctx, roomRepository, classes)
+	if err != nil {
+		return nil, err
+	}
+
+	var details []errorHandling.Detail
+	for _, conflict := range findConflicts(classes, rooms) {
+		if changed < 0 {
+			details = append(details, errorHandling.Detail{Field: fmt.Sprintf("classes[%d].%v", conflict.index, conflict.field), Message: conflict.message})
+		} else if slices.Contains(conflict.classes, changed) {
+			details = append(details, errorHandling.Detail{Field: conflict.field, Message: conflict.message})
+		}
+	}
+
+	return details, nil
+}
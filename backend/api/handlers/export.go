@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+	"github.com/DanVerh/artschool-admin/backend/api/storage"
+)
+
+// Create struct (class) for ExportHandler to download students, schedules and attendance as CSV or XLSX.
+// Rows are read from the database one at a time and written right away, so big exports do not fill the memory
+type ExportHandler struct {
+	Students  storage.StudentRepository
+	Schedules storage.ScheduleRepository
+	Teachers  storage.TeacherRepository
+	Rooms     storage.RoomRepository
+}
+
+// Time an export may take to stream; the write timeout of the server is meant for ordinary responses
+const exportWriteTimeout = 30 * time.Minute
+
+// GET for export of students; same filters and sorting as the students list, without pages
+func (exportHandler *ExportHandler) ExportStudents(w http.ResponseWriter, r *http.Request) {
+	extendWriteDeadline(w)
+	format, ok := parseExportFormat(w, r)
+	if !ok {
+		return
+	}
+	query, details := parseStudentQuery(r)
+	if len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid students query", nil, details...)
+		return
+	}
+	// Exports have every matching student
+	query.Limit, query.Offset = 0, 0
+
+	sheet := newSheetWriter(w, format, "students", []string{"id", "fullname", "phone", "subscription", "startDate", "lastDate", "comments", "deletedAt"})
+	err := exportHandler.Students.Each(r.Context(), query, func(student models.Student) error {
+		var subscription, comments interface{}
+		if student.Subscription != nil {
+			subscription = *student.Subscription
+		}
+		if student.Comments != nil {
+			comments = *student.Comments
+		}
+		return sheet.WriteRow(student.Id.Hex(), student.Fullname, student.Phone, subscription, exportDate(student.StartDate), exportDate(student.LastDate), comments, exportDate(student.DeletedAt))
+	})
+	finishExport(w, r, sheet, err, "Failed to retrieve students")
+}
+
+// GET for export of the schedule, one row per class; same filters as the schedules list
+func (exportHandler *ExportHandler) ExportSchedule(w http.ResponseWriter, r *http.Request) {
+	header := []string{"date", "time", "type", "studentId", "student", "teacher", "room", "attendance", "classId", "scheduleId"}
+	exportHandler.exportClasses(w, r, "schedule", header, func(schedule models.Schedule, class models.Class, names classNames) []interface{} {
+		return []interface{}{schedule.Date.Time().Format(time.DateOnly), class.Time, class.Type, class.StudentId.Hex(), names.student, names.teacher, names.room, exportAttendance(class.Attendence), class.Id.Hex(), schedule.Id.Hex()}
+	})
+}
+
+// GET for export of attendance, one row per class with marked attendance; same filters as the schedules list
+func (exportHandler *ExportHandler) ExportAttendance(w http.ResponseWriter, r *http.Request) {
+	header := []string{"date", "time", "type", "studentId", "student", "teacher", "attendance", "subscriptionId"}
+	exportHandler.exportClasses(w, r, "attendance", header, func(schedule models.Schedule, class models.Class, names classNames) []interface{} {
+		if class.Attendence == nil {
+			return nil
+		}
+		var subscriptionId interface{}
+		if class.SubscriptionId != nil {
+			subscriptionId = class.SubscriptionId.Hex()
+		}
+		return []interface{}{schedule.Date.Time().Format(time.DateOnly), class.Time, class.Type, class.StudentId.Hex(), names.student, names.teacher, exportAttendance(class.Attendence), subscriptionId}
+	})
+}
+
+// Resolved names of one class
+type classNames struct {
+	student string
+	teacher string
+	room    string
+}
+
+// Write one row for every class of the schedules matching the query; row returns nil to skip a class
+func (exportHandler *ExportHandler) exportClasses(w http.ResponseWriter, r *http.Request, name string, header []string, row func(schedule models.Schedule, class models.Class, names classNames) []interface{}) {
+	extendWriteDeadline(w)
+	format, ok := parseExportFormat(w, r)
+	if !ok {
+		return
+	}
+	query, details := parseScheduleQuery(r)
+	if len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid schedule filters", nil, details...)
+		return
+	}
+
+	sheet := newSheetWriter(w, format, name, header)
+	cache := newNameCache(exportHandler.Students, exportHandler.Teachers, exportHandler.Rooms)
+	err := exportHandler.Schedules.Each(r.Context(), query, func(schedule models.Schedule) error {
+		for _, class := range schedule.Classes {
+			// Like the list, only the classes of the teacher
+			if query.TeacherId != nil && (class.TeacherId == nil || *class.TeacherId != *query.TeacherId) {
+				continue
+			}
+
+			names := classNames{}
+			var err error
+			names.student, err = cache.student(r.Context(), class.StudentId)
+			if err != nil {
+				return err
+			}
+			if class.TeacherId != nil {
+				names.teacher, err = cache.teacher(r.Context(), *class.TeacherId)
+				if err != nil {
+					return err
+				}
+			}
+			if class.RoomId != nil {
+				names.room, err = cache.room(r.Context(), *class.RoomId)
+				if err != nil {
+					return err
+				}
+			}
+
+			if cells := row(schedule, class, names); cells != nil {
+				if err := sheet.WriteRow(cells...); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	finishExport(w, r, sheet, err, "Failed to retrieve schedules")
+}
+
+// Give the export exportWriteTimeout to stream. Writers that can't change the deadline, like test recorders, have none
+func extendWriteDeadline(w http.ResponseWriter) {
+	err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Printf("Failed to extend the write deadline of the export: %v", err)
+	}
+}
+
+// Read the format of the export, csv by default
+func parseExportFormat(w http.ResponseWriter, r *http.Request) (string, bool) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		return formatCSV, true
+	}
+	if format != formatCSV && format != formatXLSX {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid export format", nil, errorHandling.Detail{Field: "format", Message: "format must be csv or xlsx"})
+		return "", false
+	}
+
+	return format, true
+}
+
+// End the export. An error before the first row is answered with JSON; after it the response
+// is already sent, so the connection is aborted and the client does not take a cut file for a whole one
+func finishExport(w http.ResponseWriter, r *http.Request, sheet *sheetWriter, err error, message string) {
+	if err != nil && !sheet.started {
+		errorHandling.ThrowDbError(w, r, message, err)
+		return
+	}
+	if err == nil {
+		err = sheet.Close()
+	}
+	if err != nil {
+		log.Printf("[%v] Failed to export %v: %v", middleware.GetReqID(r.Context()), sheet.filename, err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// Date cell of the export, empty for null dates
+func exportDate(date *time.Time) interface{} {
+	if date == nil {
+		return nil
+	}
+
+	return date.UTC().Format(time.DateOnly)
+}
+
+// Attendance cell of the export: present, absent or empty when it is not marked
+func exportAttendance(attendance *bool) interface{} {
+	if attendance == nil {
+		return nil
+	}
+	if *attendance {
+		return "present"
+	}
+
+	return "absent"
+}
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/auth"
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+	"github.com/DanVerh/artschool-admin/backend/api/storage"
+)
+
+// Query parameter carrying the token of a calendar feed
+const feedTokenParam = "token"
+
+// Create struct (class) for FeedHandler to manage the secret links of calendar feeds.
+// Calendar apps subscribe by link and can not log in, so a feed link carries a token that opens one feed only
+type FeedHandler struct {
+	Feeds    storage.CalendarFeedRepository
+	Users    storage.UserRepository
+	Teachers storage.TeacherRepository
+	Students storage.StudentRepository
+}
+
+// Body of the feed creation; entityId is the teacher or the student, left out for the school feed
+type feedRequest struct {
+	Scope    string `json:"scope"`
+	EntityId string `json:"entityId"`
+}
+
+// POST for a new calendar feed link; the token is only returned here
+func (feedHandler *FeedHandler) Create(w http.ResponseWriter, r *http.Request) {
+	request := feedRequest{}
+	jsonDecoder := json.NewDecoder(r.Body)
+	err := jsonDecoder.Decode(&request)
+	if err != nil {
+		errorHandling.ThrowError(w, r, errorHandling.InvalidJSON, "Invalid JSON", nil)
+		return
+	}
+
+	feed := &models.CalendarFeed{Id: primitive.NewObjectID(), Scope: request.Scope, CreatedAt: time.Now().UTC()}
+	var details []errorHandling.Detail
+	switch request.Scope {
+	case models.FeedSchool:
+		if request.EntityId != "" {
+			details = append(details, errorHandling.Detail{Field: "entityId", Message: "entityId must be left out for the school feed"})
+		}
+	case models.FeedTeacher, models.FeedStudent:
+		entityId, err := primitive.ObjectIDFromHex(request.EntityId)
+		if err != nil {
+			details = append(details, errorHandling.Detail{Field: "entityId", Message: "entityId must be a 24 characters hex ObjectId"})
+			break
+		}
+		if request.Scope == models.FeedTeacher {
+			_, err = feedHandler.Teachers.Get(r.Context(), entityId)
+		} else {
+			_, err = feedHandler.Students.Get(r.Context(), entityId)
+		}
+		if errors.Is(err, storage.ErrNotFound) {
+			details = append(details, errorHandling.Detail{Field: "entityId", Message: request.Scope + " not found"})
+			break
+		}
+		if err != nil {
+			errorHandling.ThrowDbError(w, r, "Failed to retrieve the "+request.Scope+" of the feed", err)
+			return
+		}
+		feed.EntityId = &entityId
+	default:
+		details = append(details, errorHandling.Detail{Field: "scope", Message: "scope must be one of school, teacher, student"})
+	}
+	if len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid calendar feed fields", nil, details...)
+		return
+	}
+
+	if user := auth.UserFromContext(r.Context()); user != nil {
+		feed.CreatedBy = user.Id
+	}
+	token, tokenHash, err := auth.NewFeedToken()
+	if err != nil {
+		errorHandling.ThrowError(w, r, errorHandling.Internal, "Failed to create the feed token", &err)
+		return
+	}
+	feed.TokenHash = tokenHash
+
+	err = feedHandler.Feeds.Create(r.Context(), feed)
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to insert the calendar feed into the database", err)
+		return
+	}
+
+	feed.Token = token
+	feed.Path = feedPath(feed) + "?" + feedTokenParam + "=" + token
+	writeJSON(w, http.StatusCreated, feed)
+}
+
+// GET for every calendar feed, newest first
+func (feedHandler *FeedHandler) List(w http.ResponseWriter, r *http.Request) {
+	feeds, err := feedHandler.Feeds.List(r.Context(), nil)
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve calendar feeds from the database", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, feeds)
+}
+
+// DELETE for revoking a calendar feed; its link stops working at once
+func (feedHandler *FeedHandler) DeleteByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseObjectId(w, r, "id")
+	if !ok {
+		return
+	}
+
+	err := feedHandler.Feeds.Delete(r.Context(), id)
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to delete the calendar feed")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Authorize lets requests with the token of a feed of the scope through without a session; for teacher and student
+// feeds the token must belong to the id of the path. Requests without a token go through session instead.
+// A feed works only while the user who created it can manage feeds
+func (feedHandler *FeedHandler) Authorize(scope string, session func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		withSession := session(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.URL.Query().Get(feedTokenParam)
+			if token == "" {
+				withSession.ServeHTTP(w, r)
+				return
+			}
+
+			feed, err := feedHandler.Feeds.GetByToken(r.Context(), auth.HashFeedToken(token))
+			if errors.Is(err, storage.ErrNotFound) {
+				errorHandling.ThrowError(w, r, errorHandling.Unauthorized, "Calendar feed link is invalid or revoked", nil)
+				return
+			}
+			if err != nil {
+				errorHandling.ThrowDbError(w, r, "Failed to check the calendar feed", err)
+				return
+			}
+			if feed.Scope != scope || (feed.EntityId != nil && feed.EntityId.Hex() != chi.URLParam(r, "id")) {
+				errorHandling.ThrowError(w, r, errorHandling.Forbidden, "Calendar feed link is for another feed", nil)
+				return
+			}
+
+			user, err := feedHandler.Users.Get(r.Context(), feed.CreatedBy)
+			if err != nil && !errors.Is(err, storage.ErrNotFound) {
+				errorHandling.ThrowDbError(w, r, "Failed to check the calendar feed", err)
+				return
+			}
+			if err != nil || !auth.HasPermission(user, auth.FeedsManage) {
+				errorHandling.ThrowError(w, r, errorHandling.Unauthorized, "Calendar feed link is no longer valid", nil)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithUser(r.Context(), user)))
+		})
+	}
+}
+
+// RedactFeedToken hides the token of feed links from the request URI that the request log prints;
+// handlers read the token from the URL, which stays as it is
+func RedactFeedToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Has(feedTokenParam) {
+			query.Set(feedTokenParam, "redacted")
+			r = r.WithContext(r.Context())
+			r.RequestURI = r.URL.EscapedPath() + "?" + query.Encode()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Path of the feed, without the token
+func feedPath(feed *models.CalendarFeed) string {
+	switch feed.Scope {
+	case models.FeedTeacher:
+		return "/teachers/" + feed.EntityId.Hex() + "/schedule.ics"
+	case models.FeedStudent:
+		return "/students/" + feed.EntityId.Hex() + "/schedule.ics"
+	}
+	return "/schedule.ics"
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/storage"
+)
+
+// Helpers shared by all handlers
+
+// Write the value as JSON response with the given status code
+func writeJSON(w http.ResponseWriter, statusCode int, value interface{}) {
+	// Headers must be set before WriteHeader, otherwise they are ignored
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(value)
+}
+
+// Convert the URL parameter to a MongoDB ObjectId; responds 400 and returns false if it is invalid
+func parseObjectId(w http.ResponseWriter, r *http.Request, param string) (primitive.ObjectID, bool) {
+	objectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, param))
+	if err != nil {
+		errorHandling.ThrowError(w, r, errorHandling.InvalidId, "Invalid ObjectId format", nil, errorHandling.Detail{Field: param, Message: "must be a 24 characters hex ObjectId"})
+		return primitive.NilObjectID, false
+	}
+
+	return objectID, true
+}
+
+// Parse a time of the query string given as RFC3339 time or YYYY-MM-DD date
+func parseTimeParam(text string) (time.Time, error) {
+	parsed, err := time.Parse(time.RFC3339, text)
+	if err == nil {
+		return parsed, nil
+	}
+
+	return time.Parse(time.DateOnly, text)
+}
+
+// Parse the day of a schedule given as YYYY-MM-DD date or RFC3339 time; returns midnight UTC of the day,
+// which is how schedule dates are stored
+func parseDay(text string) (time.Time, error) {
+	parsed, err := time.Parse(time.RFC3339, text)
+	if err != nil {
+		parsed, err = time.Parse(time.DateOnly, text)
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return calendarDay(parsed), nil
+}
+
+// Midnight UTC of the calendar date of a time sent by a client. The day of a time is the date in its own offset,
+// so 2024-03-01T01:00:00+02:00 is March 1 even though it is still February 29 in UTC
+func calendarDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Read limit and offset of a page from the query string
+func parsePage(r *http.Request, defaultLimit int, maxLimit int) (int, int, []errorHandling.Detail) {
+	values := r.URL.Query()
+	limit, offset := defaultLimit, 0
+	var details []errorHandling.Detail
+
+	if text := values.Get("limit"); text != "" {
+		parsed, err := strconv.Atoi(text)
+		if err != nil || parsed < 1 || parsed > maxLimit {
+			details = append(details, errorHandling.Detail{Field: "limit", Message: fmt.Sprintf("limit must be between 1 and %v", maxLimit)})
+		}
+		limit = parsed
+	}
+	if text := values.Get("offset"); text != "" {
+		parsed, err := strconv.Atoi(text)
+		if err != nil || parsed < 0 {
+			details = append(details, errorHandling.Detail{Field: "offset", Message: "offset must not be negative"})
+		}
+		offset = parsed
+	}
+
+	return limit, offset, details
+}
+
+// Link to the next page of a list; keeps all other parameters of the request, empty on the last page
+func nextPage(r *http.Request, offset int, count int, total int64) string {
+	nextOffset := offset + count
+	if int64(nextOffset) >= total {
+		return ""
+	}
+	values := r.URL.Query()
+	values.Set("offset", strconv.Itoa(nextOffset))
+	return r.URL.Path + "?" + values.Encode()
+}
+
+// Respond to a repository error: 404 for a missing document, 503/500 for database failures
+func throwStorageError(w http.ResponseWriter, r *http.Request, err error, responseMessage string) {
+	if errors.Is(err, storage.ErrNotFound) {
+		errorHandling.ThrowError(w, r, errorHandling.NotFound, "No document found with the given ObjectId", nil)
+		return
+	}
+
+	errorHandling.ThrowDbError(w, r, responseMessage, err)
+}
+
+// Names of the students, teachers and rooms of one response, each loaded once.
+// Purged students are named "Deleted student", missing teachers and rooms have empty names
+type nameCache struct {
+	studentRepo storage.StudentRepository
+	teacherRepo storage.TeacherRepository
+	roomRepo    storage.RoomRepository
+	students    map[primitive.ObjectID]string
+	teachers    map[primitive.ObjectID]string
+	rooms       map[primitive.ObjectID]string
+}
+
+func newNameCache(students storage.StudentRepository, teachers storage.TeacherRepository, rooms storage.RoomRepository) *nameCache {
+	return &nameCache{
+		studentRepo: students,
+		teacherRepo: teachers,
+		roomRepo:    rooms,
+		students:    map[primitive.ObjectID]string{},
+		teachers:    map[primitive.ObjectID]string{},
+		rooms:       map[primitive.ObjectID]string{},
+	}
+}
+
+func (names *nameCache) student(ctx context.Context, id primitive.ObjectID) (string, error) {
+	if name, found := names.students[id]; found {
+		return name, nil
+	}
+	name := "Deleted student"
+	student, err := names.studentRepo.Get(ctx, id)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return "", err
+	}
+	if err == nil {
+		name = student.Fullname
+	}
+	names.students[id] = name
+	return name, nil
+}
+
+func (names *nameCache) teacher(ctx context.Context, id primitive.ObjectID) (string, error) {
+	if name, found := names.teachers[id]; found {
+		return name, nil
+	}
+	teacher, err := names.teacherRepo.Get(ctx, id)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return "", err
+	}
+	name := ""
+	if err == nil {
+		name = teacher.Fullname
+	}
+	names.teachers[id] = name
+	return name, nil
+}
+
+func (names *nameCache) room(ctx context.Context, id primitive.ObjectID) (string, error) {
+	if name, found := names.rooms[id]; found {
+		return name, nil
+	}
+	room, err := names.roomRepo.Get(ctx, id)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return "", err
+	}
+	name := ""
+	if err == nil {
+		name = room.Name
+	}
+	names.rooms[id] = name
+	return name, nil
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+	"github.com/DanVerh/artschool-admin/backend/api/storage"
+)
+
+// Create struct (class) for HolidayHandler to handle days the school is closed
+type HolidayHandler struct {
+	Holidays storage.HolidayRepository
+}
+
+// Body of the holiday creation; date is YYYY-MM-DD
+type holidayRequest struct {
+	Date string `json:"date"`
+	Name string `json:"name"`
+}
+
+// POST for holiday creation
+func (holidayHandler *HolidayHandler) Create(w http.ResponseWriter, r *http.Request) {
+	request := holidayRequest{}
+	jsonDecoder := json.NewDecoder(r.Body)
+	err := jsonDecoder.Decode(&request)
+	if err != nil {
+		errorHandling.ThrowError(w, r, errorHandling.InvalidJSON, "Invalid JSON", nil)
+		return
+	}
+
+	var details []errorHandling.Detail
+	date, err := time.Parse(time.DateOnly, request.Date)
+	if err != nil {
+		details = append(details, errorHandling.Detail{Field: "date", Message: "date must be a YYYY-MM-DD date"})
+	}
+	if request.Name == "" {
+		details = append(details, errorHandling.Detail{Field: "name", Message: "name is required"})
+	}
+	if len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid holiday fields", nil, details...)
+		return
+	}
+
+	holiday := &models.Holiday{Id: primitive.NewObjectID(), Date: date, Name: request.Name}
+	err = holidayHandler.Holidays.Create(r.Context(), holiday)
+	if errors.Is(err, storage.ErrDuplicate) {
+		errorHandling.ThrowError(w, r, errorHandling.Duplicate, "Holiday for this date already exists", nil, errorHandling.Detail{Field: "date", Message: "date must be unique"})
+		return
+	}
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to insert the holiday into the database", err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, holiday)
+}
+
+// GET for holidays list, ordered by date; ?from= and ?to= (YYYY-MM-DD, both included) limit the range
+func (holidayHandler *HolidayHandler) List(w http.ResponseWriter, r *http.Request) {
+	var limits [2]time.Time
+	var details []errorHandling.Detail
+	for index, name := range []string{"from", "to"} {
+		text := r.URL.Query().Get(name)
+		if text == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.DateOnly, text)
+		if err != nil {
+			details = append(details, errorHandling.Detail{Field: name, Message: name + " must be a YYYY-MM-DD date"})
+		}
+		limits[index] = parsed
+	}
+	if len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid holiday filters", nil, details...)
+		return
+	}
+
+	holidays, err := holidayHandler.Holidays.List(r.Context(), limits[0], limits[1])
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve holidays from the database", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, holidays)
+}
+
+// DELETE for holiday removal
+func (holidayHandler *HolidayHandler) DeleteByID(w http.ResponseWriter, r *http.Request) {
+	objectID, ok := parseObjectId(w, r, "id")
+	if !ok {
+		return
+	}
+
+	err := holidayHandler.Holidays.Delete(r.Context(), objectID)
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to delete holiday")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,321 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/auth"
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+	"github.com/DanVerh/artschool-admin/backend/api/storage"
+)
+
+// Limits of an import file
+const (
+	maxImportSize = 10 << 20
+	maxImportRows = 5000
+)
+
+// Student fields read from the import file; each is taken from the column with the same name
+// unless the query string maps it to another column, e.g. ?phone=Phone%20number
+var importFields = []string{"fullname", "phone", "comments", "startDate", "lastDate"}
+
+// Saving planned for one row of the import
+type importChange struct {
+	report *models.StudentImportRow
+	// New student, or the current state of the updated one
+	student *models.Student
+	fields  map[string]interface{}
+}
+
+// POST for import of students from a CSV or XLSX file.
+// Rows are matched to students by phone: new phones create students, known phones update them.
+// Every row is checked before anything is saved; rejected rows are skipped and the others are saved,
+// so the same file can be imported again after its rejected rows are fixed
+func (studentHandler *StudentHandler) Import(w http.ResponseWriter, r *http.Request) {
+	// Check if the method is POST; return 405 in case of error
+	if r.Method != http.MethodPost {
+		errorHandling.ThrowError(w, r, errorHandling.MethodNotAllowed, "Invalid request method. Needs to be POST", nil)
+		return
+	}
+
+	dryRun := false
+	if text := r.URL.Query().Get("dryRun"); text != "" {
+		parsed, err := strconv.ParseBool(text)
+		if err != nil {
+			errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid import query", nil, errorHandling.Detail{Field: "dryRun", Message: "dryRun must be true or false"})
+			return
+		}
+		dryRun = parsed
+	}
+
+	// Read the whole file, it is checked before anything is saved
+	content, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid import file", nil, errorHandling.Detail{Message: fmt.Sprintf("file must not be larger than %v MB", maxImportSize>>20)})
+		return
+	}
+	rows, err := readSheet(content, maxImportRows+1)
+	if errors.Is(err, errTooManyRows) {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid import file", nil, errorHandling.Detail{Message: fmt.Sprintf("file must not have more than %v rows", maxImportRows)})
+		return
+	}
+	if err != nil {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid import file", nil, errorHandling.Detail{Message: err.Error()})
+		return
+	}
+	if len(rows) == 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid import file", nil, errorHandling.Detail{Message: "file must start with a header row"})
+		return
+	}
+	columns, details := mapImportColumns(r, rows[0].cells)
+	if len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid import columns", nil, details...)
+		return
+	}
+
+	// Archived students are loaded too, their phones are still taken
+	students, _, err := studentHandler.Students.List(r.Context(), models.StudentQuery{IncludeArchived: true})
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve students", err)
+		return
+	}
+	byPhone := map[string]*models.Student{}
+	for index := range students {
+		byPhone[students[index].Phone] = &students[index]
+	}
+
+	// Empty rows are skipped
+	var dataRows []sheetRow
+	for _, row := range rows[1:] {
+		if slices.ContainsFunc(row.cells, func(cell string) bool { return strings.TrimSpace(cell) != "" }) {
+			dataRows = append(dataRows, row)
+		}
+	}
+	report := &models.StudentImport{DryRun: dryRun, Rows: make([]models.StudentImportRow, len(dataRows))}
+	var changes []importChange
+	seen := map[string]int{}
+	for index, row := range dataRows {
+		report.Rows[index].Row = row.line
+		change := planImportRow(r, &report.Rows[index], row, columns, byPhone, seen)
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+
+	if !dryRun {
+		for _, change := range changes {
+			err := studentHandler.saveImportChange(r, change)
+			if errors.Is(err, storage.ErrDuplicate) {
+				change.report.Action, change.report.StudentId, change.report.Fields = models.ImportReject, nil, nil
+				change.report.Errors = append(change.report.Errors, models.ImportError{Field: "phone", Message: "phone must be unique"})
+				continue
+			}
+			if err != nil {
+				errorHandling.ThrowDbError(w, r, fmt.Sprintf("Failed to save the student of row %v", change.report.Row), err)
+				return
+			}
+		}
+	}
+
+	for _, row := range report.Rows {
+		switch row.Action {
+		case models.ImportCreate:
+			report.Created++
+		case models.ImportUpdate:
+			report.Updated++
+		case models.ImportUnchanged:
+			report.Unchanged++
+		case models.ImportReject:
+			report.Rejected++
+		}
+	}
+
+	log.Printf("Imported students (dry run: %v): %v created, %v updated, %v unchanged, %v rejected\n", dryRun, report.Created, report.Updated, report.Unchanged, report.Rejected)
+	writeJSON(w, http.StatusOK, report)
+}
+
+// Find the column of every student field in the header row
+func mapImportColumns(r *http.Request, header []string) (map[string]int, []errorHandling.Detail) {
+	columns := map[string]int{}
+	var details []errorHandling.Detail
+
+	for _, field := range importFields {
+		name := r.URL.Query().Get(field)
+		mapped := name != ""
+		if !mapped {
+			name = field
+		}
+		index := slices.IndexFunc(header, func(cell string) bool {
+			return strings.EqualFold(strings.TrimSpace(cell), name)
+		})
+		if index >= 0 {
+			columns[field] = index
+			continue
+		}
+		// Optional fields may be left out of the file, but not a column named in the query
+		if mapped || field == "fullname" || field == "phone" {
+			details = append(details, errorHandling.Detail{Field: field, Message: fmt.Sprintf("column %q is not in the header row", name)})
+		}
+	}
+
+	return columns, details
+}
+
+// Check one row and plan what is saved for it; returns nil when nothing is saved
+func planImportRow(r *http.Request, report *models.StudentImportRow, row sheetRow, columns map[string]int, byPhone map[string]*models.Student, seen map[string]int) *importChange {
+	cell := func(field string) string {
+		index, found := columns[field]
+		if !found || index >= len(row.cells) {
+			return ""
+		}
+		return unescapeCSVText(strings.TrimSpace(row.cells[index]))
+	}
+
+	// Same checks as for a student created with the API
+	student := &models.Student{Fullname: cell("fullname"), Phone: cell("phone")}
+	report.Fullname, report.Phone = student.Fullname, student.Phone
+	reject := func(field string, message string) {
+		report.Errors = append(report.Errors, models.ImportError{Field: field, Message: message})
+	}
+	for _, detail := range validateStudent(student) {
+		reject(detail.Field, detail.Message)
+	}
+
+	// Empty optional cells do not change anything
+	values := map[string]interface{}{"fullname": student.Fullname}
+	if comments := cell("comments"); comments != "" {
+		values["comments"] = comments
+	}
+	for _, field := range []string{"startDate", "lastDate"} {
+		if text := cell(field); text != "" {
+			date, err := parseImportDate(text)
+			if err != nil {
+				reject(field, field+" must be a YYYY-MM-DD date, an RFC3339 time or a spreadsheet date")
+				continue
+			}
+			values[field] = date
+		}
+	}
+
+	existing := byPhone[student.Phone]
+	if student.Phone != "" {
+		if firstRow, found := seen[student.Phone]; found {
+			reject("phone", fmt.Sprintf("phone is already used in row %v", firstRow))
+		} else {
+			seen[student.Phone] = row.line
+		}
+	}
+	if existing != nil && existing.DeletedAt != nil {
+		reject("phone", "student with this phone is archived, restore it first")
+	}
+
+	// Fields set on a new student or changed on the existing one
+	fields := map[string]interface{}{}
+	for _, field := range importFields {
+		value, found := values[field]
+		if !found || (existing != nil && !importFieldChanged(existing, field, value)) {
+			continue
+		}
+		fields[field] = value
+		report.Fields = append(report.Fields, field)
+		// Creating a student allows its name and phone, the other fields need their own permissions
+		permission := studentFieldPermissions[field]
+		if (existing != nil || (field != "fullname" && field != "phone")) && !auth.Can(r, permission) {
+			reject(field, "missing permission "+string(permission))
+		}
+	}
+
+	if len(report.Errors) > 0 {
+		report.Action, report.Fields = models.ImportReject, nil
+		return nil
+	}
+	if existing == nil {
+		report.Action, report.Fields = models.ImportCreate, nil
+		return &importChange{report: report, fields: fields}
+	}
+	report.StudentId = &existing.Id
+	if len(fields) == 0 {
+		report.Action = models.ImportUnchanged
+		return nil
+	}
+	report.Action = models.ImportUpdate
+	return &importChange{report: report, student: existing, fields: fields}
+}
+
+// Compare the value of the import file with the field of the existing student
+func importFieldChanged(student *models.Student, field string, value interface{}) bool {
+	switch field {
+	case "fullname":
+		return student.Fullname != value
+	case "comments":
+		return student.Comments == nil || *student.Comments != value
+	case "startDate":
+		return student.StartDate == nil || !student.StartDate.Equal(value.(time.Time))
+	case "lastDate":
+		return student.LastDate == nil || !student.LastDate.Equal(value.(time.Time))
+	}
+
+	return false
+}
+
+// Parse a date of the import file; spreadsheets keep dates as the number of days since December 30, 1899
+func parseImportDate(text string) (time.Time, error) {
+	date, err := parseTimeParam(text)
+	if err == nil {
+		return date, nil
+	}
+
+	days, numberErr := strconv.Atoi(text)
+	if numberErr != nil || days < 1 || days > 2958465 {
+		return time.Time{}, err
+	}
+	return time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC).AddDate(0, 0, days), nil
+}
+
+// Save one planned change and record it in the audit log
+func (studentHandler *StudentHandler) saveImportChange(r *http.Request, change importChange) error {
+	if change.student == nil {
+		student := &models.Student{Id: primitive.NewObjectID()}
+		student.Fullname, _ = change.fields["fullname"].(string)
+		student.Phone = change.report.Phone
+		if comments, found := change.fields["comments"].(string); found {
+			student.Comments = &comments
+		}
+		if startDate, found := change.fields["startDate"].(time.Time); found {
+			student.StartDate = &startDate
+		}
+		if lastDate, found := change.fields["lastDate"].(time.Time); found {
+			student.LastDate = &lastDate
+		}
+
+		err := studentHandler.Students.Create(r.Context(), student)
+		if err != nil {
+			return err
+		}
+		change.report.StudentId = &student.Id
+		recordAudit(r, studentHandler.Audit, auditStudent, student.Id, models.AuditCreate, nil, student)
+		return nil
+	}
+
+	err := studentHandler.Students.Update(r.Context(), change.student.Id, change.fields)
+	if err != nil {
+		return err
+	}
+	after, err := studentHandler.Students.Get(r.Context(), change.student.Id)
+	if err == nil {
+		recordAudit(r, studentHandler.Audit, auditStudent, change.student.Id, models.AuditUpdate, change.student, after)
+	} else {
+		log.Printf("Failed to read updated student %v for the audit log: %v", change.student.Id.Hex(), err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+	"github.com/DanVerh/artschool-admin/backend/api/notifications"
+	"github.com/DanVerh/artschool-admin/backend/api/storage"
+)
+
+// Delivery statuses the notifications list can be filtered by
+var notificationStatuses = []string{models.NotificationPending, models.NotificationSending, models.NotificationSent, models.NotificationFailed}
+
+// Limits of the notifications list
+const (
+	defaultNotificationLimit = 100
+	maxNotificationLimit     = 1000
+)
+
+// Create struct (class) for NotificationHandler to read the sent notifications and send the reminders
+type NotificationHandler struct {
+	Notifications storage.NotificationRepository
+	// Nil when notifications are off
+	Reminders *notifications.Reminders
+}
+
+// GET for notifications, newest first
+// Filters: studentId, status, limit
+func (notificationHandler *NotificationHandler) List(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	query := models.NotificationQuery{Status: values.Get("status"), Limit: defaultNotificationLimit}
+	var details []errorHandling.Detail
+
+	if studentId := values.Get("studentId"); studentId != "" {
+		objectID, err := primitive.ObjectIDFromHex(studentId)
+		if err != nil {
+			details = append(details, errorHandling.Detail{Field: "studentId", Message: "must be a 24 characters hex ObjectId"})
+		}
+		query.StudentId = objectID
+	}
+	if query.Status != "" && !slices.Contains(notificationStatuses, query.Status) {
+		details = append(details, errorHandling.Detail{Field: "status", Message: "status must be one of " + strings.Join(notificationStatuses, ", ")})
+	}
+	if limit := values.Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed < 1 || parsed > maxNotificationLimit {
+			details = append(details, errorHandling.Detail{Field: "limit", Message: "limit must be between 1 and " + strconv.Itoa(maxNotificationLimit)})
+		}
+		query.Limit = parsed
+	}
+	if len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid notification filters", nil, details...)
+		return
+	}
+
+	list, err := notificationHandler.Notifications.List(r.Context(), query)
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve notifications from the database", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+// POST for sending the reminders of a day now, without waiting for the job
+// The day is the date parameter (YYYY-MM-DD), tomorrow by default; reminders already sent are not sent again
+func (notificationHandler *NotificationHandler) SendReminders(w http.ResponseWriter, r *http.Request) {
+	if notificationHandler.Reminders == nil {
+		errorHandling.ThrowError(w, r, errorHandling.NotificationsOff, "Notifications are off, set the notification sender in the configuration", nil)
+		return
+	}
+
+	now := time.Now()
+	day := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	if date := r.URL.Query().Get("date"); date != "" {
+		parsed, err := time.Parse(time.DateOnly, date)
+		if err != nil {
+			errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid date", nil, errorHandling.Detail{Field: "date", Message: "must be a YYYY-MM-DD date"})
+			return
+		}
+		day = parsed
+	}
+
+	run, err := notificationHandler.Reminders.Run(r.Context(), day)
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to send the reminders", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, run)
+}
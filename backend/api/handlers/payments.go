@@ -0,0 +1,426 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/auth"
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+	"github.com/DanVerh/artschool-admin/backend/api/storage"
+)
+
+// Accepted payment methods
+var paymentMethods = []string{"cash", "card", "transfer"}
+
+// ISO 4217 currency code
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// Refund or correction that does not fit the net amount of the payment
+var errInvalidAdjustment = errors.New("invalid adjustment amount")
+
+// Limits of the payments list
+const (
+	defaultPaymentsLimit = 100
+	maxPaymentsLimit     = 1000
+)
+
+// Create struct (class) for PaymentHandler to keep the payments ledger
+type PaymentHandler struct {
+	Payments      storage.PaymentRepository
+	Students      storage.StudentRepository
+	Subscriptions storage.SubscriptionRepository
+	Transactions  storage.Transactor
+}
+
+// Body of the payment request
+type paymentRequest struct {
+	Method         string              `json:"method"`
+	Amount         int64               `json:"amount"`
+	Currency       string              `json:"currency"`
+	StudentId      *primitive.ObjectID `json:"studentId"`
+	SubscriptionId *primitive.ObjectID `json:"subscriptionId"`
+	Note           *string             `json:"note"`
+}
+
+// Body of the refund and correction requests
+type adjustmentRequest struct {
+	// Refunds take a positive amount, the whole rest of the payment when not set; corrections take a signed one
+	Amount *int64  `json:"amount"`
+	Method *string `json:"method"`
+	Note   *string `json:"note"`
+}
+
+// Totals of one payment method and currency in the reconciliation report
+type reconciliationLine struct {
+	Method      string `json:"method"`
+	Currency    string `json:"currency"`
+	Payments    int64  `json:"payments"`
+	Refunds     int64  `json:"refunds"`
+	Corrections int64  `json:"corrections"`
+	Total       int64  `json:"total"`
+	Count       int    `json:"count"`
+}
+
+// Reconciliation report of one day
+type reconciliationReport struct {
+	Date    string               `json:"date"`
+	From    time.Time            `json:"from"`
+	To      time.Time            `json:"to"`
+	Methods []reconciliationLine `json:"methods"`
+}
+
+// POST for recording a payment
+func (paymentHandler *PaymentHandler) Create(w http.ResponseWriter, r *http.Request) {
+	request := paymentRequest{}
+	jsonDecoder := json.NewDecoder(r.Body)
+	err := jsonDecoder.Decode(&request)
+	if err != nil {
+		errorHandling.ThrowError(w, r, errorHandling.InvalidJSON, "Invalid JSON", nil)
+		return
+	}
+
+	var details []errorHandling.Detail
+	if !slices.Contains(paymentMethods, request.Method) {
+		details = append(details, errorHandling.Detail{Field: "method", Message: "method must be one of " + strings.Join(paymentMethods, ", ")})
+	}
+	if request.Amount <= 0 {
+		details = append(details, errorHandling.Detail{Field: "amount", Message: "amount must be positive, in the smallest currency unit"})
+	}
+	if !currencyPattern.MatchString(request.Currency) {
+		details = append(details, errorHandling.Detail{Field: "currency", Message: "currency must be an ISO 4217 code such as UAH"})
+	}
+	if len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid payment fields", nil, details...)
+		return
+	}
+
+	// The pack tells the student; a student given together with a pack must own it
+	if request.SubscriptionId != nil {
+		subscription, err := paymentHandler.Subscriptions.Get(r.Context(), *request.SubscriptionId)
+		if errors.Is(err, storage.ErrNotFound) || (err == nil && request.StudentId != nil && *request.StudentId != subscription.StudentId) {
+			errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid payment fields", nil, errorHandling.Detail{Field: "subscriptionId", Message: "subscription pack of the student not found"})
+			return
+		}
+		if err != nil {
+			errorHandling.ThrowDbError(w, r, "Failed to retrieve subscription", err)
+			return
+		}
+		request.StudentId = &subscription.StudentId
+	} else if request.StudentId != nil {
+		_, err := paymentHandler.Students.Get(r.Context(), *request.StudentId)
+		if errors.Is(err, storage.ErrNotFound) {
+			errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid payment fields", nil, errorHandling.Detail{Field: "studentId", Message: "student not found"})
+			return
+		}
+		if err != nil {
+			errorHandling.ThrowDbError(w, r, "Failed to retrieve student", err)
+			return
+		}
+	}
+
+	payment := newPayment(r, models.PaymentKindPayment, request.Method, request.Amount, request.Currency, request.Note)
+	payment.StudentId, payment.SubscriptionId = request.StudentId, request.SubscriptionId
+
+	paymentHandler.save(w, r, payment)
+}
+
+// POST for refunding a payment
+func (paymentHandler *PaymentHandler) Refund(w http.ResponseWriter, r *http.Request) {
+	payment, request, ok := paymentHandler.adjusted(w, r)
+	if !ok {
+		return
+	}
+
+	paymentHandler.saveAdjustment(w, r, payment, models.PaymentKindRefund, request, func(net int64) (int64, *errorHandling.Detail) {
+		amount := net
+		if request.Amount != nil {
+			amount = *request.Amount
+		}
+		if amount <= 0 || amount > net {
+			return 0, &errorHandling.Detail{Field: "amount", Message: "amount must be positive and at most " + strconv.FormatInt(net, 10)}
+		}
+		return -amount, nil
+	})
+}
+
+// POST for correcting a payment, e.g. a typo in the amount
+func (paymentHandler *PaymentHandler) Correct(w http.ResponseWriter, r *http.Request) {
+	payment, request, ok := paymentHandler.adjusted(w, r)
+	if !ok {
+		return
+	}
+
+	var details []errorHandling.Detail
+	if request.Amount == nil || *request.Amount == 0 {
+		details = append(details, errorHandling.Detail{Field: "amount", Message: "amount must be a non-zero difference to the payment"})
+	}
+	if request.Note == nil || *request.Note == "" {
+		details = append(details, errorHandling.Detail{Field: "note", Message: "note must explain the correction"})
+	}
+	if len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid correction", nil, details...)
+		return
+	}
+
+	paymentHandler.saveAdjustment(w, r, payment, models.PaymentKindCorrection, request, func(net int64) (int64, *errorHandling.Detail) {
+		if net+*request.Amount < 0 {
+			return 0, &errorHandling.Detail{Field: "amount", Message: "amount must be at least -" + strconv.FormatInt(net, 10) + ", the payment can not go below zero"}
+		}
+		return *request.Amount, nil
+	})
+}
+
+// GET for one ledger entry by ID
+func (paymentHandler *PaymentHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	objectID, ok := parseObjectId(w, r, "id")
+	if !ok {
+		return
+	}
+
+	payment, err := paymentHandler.Payments.Get(r.Context(), objectID)
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to retrieve payment")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, payment)
+}
+
+// GET for ledger entries, newest first
+// Filters: studentId, kind, method, from and to (RFC3339 or YYYY-MM-DD, to is exclusive), limit
+func (paymentHandler *PaymentHandler) List(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	query := models.PaymentQuery{
+		Kind:   values.Get("kind"),
+		Method: values.Get("method"),
+		Limit:  defaultPaymentsLimit,
+	}
+	var details []errorHandling.Detail
+
+	if studentId := values.Get("studentId"); studentId != "" {
+		objectID, err := primitive.ObjectIDFromHex(studentId)
+		if err != nil {
+			details = append(details, errorHandling.Detail{Field: "studentId", Message: "must be a 24 characters hex ObjectId"})
+		}
+		query.StudentId = objectID
+	}
+	kinds := []string{models.PaymentKindPayment, models.PaymentKindRefund, models.PaymentKindCorrection}
+	if query.Kind != "" && !slices.Contains(kinds, query.Kind) {
+		details = append(details, errorHandling.Detail{Field: "kind", Message: "kind must be one of " + strings.Join(kinds, ", ")})
+	}
+	if query.Method != "" && !slices.Contains(paymentMethods, query.Method) {
+		details = append(details, errorHandling.Detail{Field: "method", Message: "method must be one of " + strings.Join(paymentMethods, ", ")})
+	}
+	for _, filter := range []struct {
+		name  string
+		value *time.Time
+	}{{"from", &query.From}, {"to", &query.To}} {
+		text := values.Get(filter.name)
+		if text == "" {
+			continue
+		}
+		parsed, err := parseTimeParam(text)
+		if err != nil {
+			details = append(details, errorHandling.Detail{Field: filter.name, Message: "must be an RFC3339 time or a YYYY-MM-DD date"})
+		}
+		*filter.value = parsed
+	}
+	if limit := values.Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed < 1 || parsed > maxPaymentsLimit {
+			details = append(details, errorHandling.Detail{Field: "limit", Message: "limit must be between 1 and " + strconv.Itoa(maxPaymentsLimit)})
+		}
+		query.Limit = parsed
+	}
+	if len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid payment filters", nil, details...)
+		return
+	}
+
+	payments, err := paymentHandler.Payments.List(r.Context(), query)
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve payments from the database", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, payments)
+}
+
+// GET for the reconciliation report of one day (date=YYYY-MM-DD, today by default):
+// sums of payments, refunds and corrections for every payment method and currency, to compare with the cash desk and the card terminal
+func (paymentHandler *PaymentHandler) Reconciliation(w http.ResponseWriter, r *http.Request) {
+	from := time.Now().UTC().Truncate(24 * time.Hour)
+	if date := r.URL.Query().Get("date"); date != "" {
+		parsed, err := time.Parse(time.DateOnly, date)
+		if err != nil {
+			errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid reconciliation date", nil, errorHandling.Detail{Field: "date", Message: "date must be YYYY-MM-DD"})
+			return
+		}
+		from = parsed
+	}
+	to := from.AddDate(0, 0, 1)
+
+	payments, err := paymentHandler.Payments.List(r.Context(), models.PaymentQuery{From: from, To: to})
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve payments from the database", err)
+		return
+	}
+
+	report := reconciliationReport{Date: from.Format(time.DateOnly), From: from, To: to, Methods: []reconciliationLine{}}
+	lines := map[[2]string]*reconciliationLine{}
+	for _, payment := range payments {
+		key := [2]string{payment.Method, payment.Currency}
+		line, found := lines[key]
+		if !found {
+			line = &reconciliationLine{Method: payment.Method, Currency: payment.Currency}
+			lines[key] = line
+		}
+		switch payment.Kind {
+		case models.PaymentKindPayment:
+			line.Payments += payment.Amount
+		case models.PaymentKindRefund:
+			line.Refunds += payment.Amount
+		case models.PaymentKindCorrection:
+			line.Corrections += payment.Amount
+		}
+		line.Total += payment.Amount
+		line.Count++
+	}
+	for _, line := range lines {
+		report.Methods = append(report.Methods, *line)
+	}
+	slices.SortFunc(report.Methods, func(a reconciliationLine, b reconciliationLine) int {
+		if result := strings.Compare(a.Method, b.Method); result != 0 {
+			return result
+		}
+		return strings.Compare(a.Currency, b.Currency)
+	})
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// Read the payment of the URL and the body of a refund or correction; responds with an error and returns false if they are invalid
+func (paymentHandler *PaymentHandler) adjusted(w http.ResponseWriter, r *http.Request) (*models.Payment, adjustmentRequest, bool) {
+	request := adjustmentRequest{}
+	objectID, ok := parseObjectId(w, r, "id")
+	if !ok {
+		return nil, request, false
+	}
+
+	// The body is optional for refunds
+	jsonDecoder := json.NewDecoder(r.Body)
+	err := jsonDecoder.Decode(&request)
+	if err != nil && !errors.Is(err, io.EOF) {
+		errorHandling.ThrowError(w, r, errorHandling.InvalidJSON, "Invalid JSON", nil)
+		return nil, request, false
+	}
+	if request.Method != nil && !slices.Contains(paymentMethods, *request.Method) {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid payment fields", nil, errorHandling.Detail{Field: "method", Message: "method must be one of " + strings.Join(paymentMethods, ", ")})
+		return nil, request, false
+	}
+
+	payment, err := paymentHandler.Payments.Get(r.Context(), objectID)
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to retrieve payment")
+		return nil, request, false
+	}
+	// Only payments are refunded or corrected, so the ledger never has chains of entries
+	if payment.Kind != models.PaymentKindPayment {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Only payments can be refunded or corrected", nil, errorHandling.Detail{Field: "id", Message: "entry is a " + payment.Kind})
+		return nil, request, false
+	}
+
+	return payment, request, true
+}
+
+// Build a ledger entry recorded by the user of the request
+func newPayment(r *http.Request, kind string, method string, amount int64, currency string, note *string) *models.Payment {
+	payment := &models.Payment{
+		Id:        primitive.NewObjectID(),
+		Kind:      kind,
+		Method:    method,
+		Amount:    amount,
+		Currency:  currency,
+		Note:      note,
+		CreatedAt: time.Now().UTC(),
+	}
+	if user := auth.UserFromContext(r.Context()); user != nil {
+		payment.RecordedBy = user.Username
+	}
+
+	return payment
+}
+
+// Build a refund or correction of the payment; by default the money goes back the way it came
+func adjustment(r *http.Request, payment *models.Payment, kind string, request adjustmentRequest, amount int64) *models.Payment {
+	method := payment.Method
+	if request.Method != nil {
+		method = *request.Method
+	}
+
+	entry := newPayment(r, kind, method, amount, payment.Currency, request.Note)
+	entry.StudentId, entry.SubscriptionId, entry.RelatedTo = payment.StudentId, payment.SubscriptionId, &payment.Id
+	return entry
+}
+
+// Save a refund or a correction of the payment. Earlier refunds and corrections change the net amount
+// of the payment, which amountOf turns into the signed amount of the entry or a detail of the error.
+// The payment is locked first, so that two adjustments at once can't both see the same net amount
+func (paymentHandler *PaymentHandler) saveAdjustment(w http.ResponseWriter, r *http.Request, payment *models.Payment, kind string, request adjustmentRequest,
+	amountOf func(net int64) (int64, *errorHandling.Detail)) {
+	var entry *models.Payment
+	var detail *errorHandling.Detail
+	err := paymentHandler.Transactions.WithTransaction(r.Context(), func(ctx context.Context) error {
+		err := paymentHandler.Payments.Lock(ctx, payment.Id)
+		if err != nil {
+			return err
+		}
+		adjustments, err := paymentHandler.Payments.List(ctx, models.PaymentQuery{RelatedTo: payment.Id})
+		if err != nil {
+			return err
+		}
+		net := payment.Amount
+		for _, earlier := range adjustments {
+			net += earlier.Amount
+		}
+
+		amount, invalid := amountOf(net)
+		if invalid != nil {
+			detail = invalid
+			return errInvalidAdjustment
+		}
+		entry = adjustment(r, payment, kind, request, amount)
+		return paymentHandler.Payments.Create(ctx, entry)
+	})
+	if errors.Is(err, errInvalidAdjustment) {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid "+kind, nil, *detail)
+		return
+	}
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to save the "+kind)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, entry)
+}
+
+func (paymentHandler *PaymentHandler) save(w http.ResponseWriter, r *http.Request, payment *models.Payment) {
+	err := paymentHandler.Payments.Create(r.Context(), payment)
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to insert the payment into the database", err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, payment)
+}
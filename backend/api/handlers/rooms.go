@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+	"github.com/DanVerh/artschool-admin/backend/api/storage"
+)
+
+// Create struct (class) for RoomHandler to handle requests
+type RoomHandler struct {
+	Rooms storage.RoomRepository
+}
+
+// POST for room creation
+func (roomHandler *RoomHandler) Create(w http.ResponseWriter, r *http.Request) {
+	room := &models.Room{}
+
+	jsonDecoder := json.NewDecoder(r.Body)
+	jsonDecoder.DisallowUnknownFields()
+	err := jsonDecoder.Decode(room)
+	if err != nil {
+		errorHandling.ThrowError(w, r, errorHandling.InvalidJSON, "Invalid JSON", nil)
+		return
+	}
+	if details := validateRoom(room); len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid room fields", nil, details...)
+		return
+	}
+
+	room.Id = primitive.NewObjectID()
+	err = roomHandler.Rooms.Create(r.Context(), room)
+	if errors.Is(err, storage.ErrDuplicate) {
+		errorHandling.ThrowError(w, r, errorHandling.Duplicate, "Room with this name already exists", nil, errorHandling.Detail{Field: "name", Message: "name must be unique"})
+		return
+	}
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to insert the room into the database", err)
+		return
+	}
+
+	log.Printf("Created room %v", room.Name)
+	writeJSON(w, http.StatusCreated, room)
+}
+
+// GET for rooms list
+func (roomHandler *RoomHandler) List(w http.ResponseWriter, r *http.Request) {
+	rooms, err := roomHandler.Rooms.List(r.Context())
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve rooms from the database", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rooms)
+}
+
+// GET for one room by ID
+func (roomHandler *RoomHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	objectID, ok := parseObjectId(w, r, "id")
+	if !ok {
+		return
+	}
+
+	room, err := roomHandler.Rooms.Get(r.Context(), objectID)
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to retrieve document")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, room)
+}
+
+// PUT for room update; fields missing in the body keep their values.
+// A smaller capacity applies to new bookings only, days already planned are not checked again
+func (roomHandler *RoomHandler) UpdateByID(w http.ResponseWriter, r *http.Request) {
+	objectID, ok := parseObjectId(w, r, "id")
+	if !ok {
+		return
+	}
+
+	room, err := roomHandler.Rooms.Get(r.Context(), objectID)
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to retrieve document")
+		return
+	}
+
+	// Decode the body on top of the current room
+	jsonDecoder := json.NewDecoder(r.Body)
+	jsonDecoder.DisallowUnknownFields()
+	err = jsonDecoder.Decode(room)
+	if err != nil {
+		errorHandling.ThrowError(w, r, errorHandling.InvalidJSON, "Invalid JSON", nil)
+		return
+	}
+	room.Id = objectID
+	if details := validateRoom(room); len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid room fields", nil, details...)
+		return
+	}
+
+	err = roomHandler.Rooms.Update(r.Context(), room)
+	if errors.Is(err, storage.ErrDuplicate) {
+		errorHandling.ThrowError(w, r, errorHandling.Duplicate, "Room with this name already exists", nil, errorHandling.Detail{Field: "name", Message: "name must be unique"})
+		return
+	}
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to update room")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, room)
+}
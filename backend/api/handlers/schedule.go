@@ -1,35 +1,31 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"strings"
+	"slices"
+
+	"github.com/go-chi/chi/v5"
 
-	"github.com/DanVerh/artschool-admin/backend/api/db"
 	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+	"github.com/DanVerh/artschool-admin/backend/api/storage"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-// Create struct (class) for StudentHandler to handle requests
-type ScheduleHandler struct{}
-
-// Create struct (class) for Classes that will be added to Schedule
-type Class struct {
-	StudentId  primitive.ObjectID `json:"studentId" bson:"studentId"`
-	Time       string             `json:"time" bson:"time"`
-	Type       string             `json:"type" bson:"type"`
-	Attendence *bool              `json:"attendance" bson:"attendance"`
-}
-
-// Create struct (class) for Schedule
-type Schedule struct {
-	Id      primitive.ObjectID `json:"id" bson:"_id"`
-	Date    primitive.DateTime `bson:"date" json:"date"`
-	Classes []Class            `bson:"classes" json:"classes"`
+// Create struct (class) for ScheduleHandler to handle requests
+type ScheduleHandler struct {
+	Schedules storage.ScheduleRepository
+	Students  storage.StudentRepository
+	Teachers  storage.TeacherRepository
+	Rooms     storage.RoomRepository
+	Audit     storage.AuditRepository
+	// Removed classes, kept for the calendar feeds
+	Cancellations storage.CancellationRepository
 }
 
 // Define all methods of Schedule as handlers for routes
@@ -37,234 +33,331 @@ type Schedule struct {
 // POST for schedule creation
 func (scheduleHandler *ScheduleHandler) Create(w http.ResponseWriter, r *http.Request) {
 	// Create Schedule object
-	schedule := &Schedule{}
+	schedule := &models.Schedule{}
 
 	// Check if the method is POST; return 405 in case of error
 	if r.Method != http.MethodPost {
-		errorHandling.ThrowError(w, http.StatusMethodNotAllowed, "Invalid request method. Needs to be POST", nil)
+		errorHandling.ThrowError(w, r, errorHandling.MethodNotAllowed, "Invalid request method. Needs to be POST", nil)
 		return
 	}
 
-	// Parse JSON request body to Schedule struct
+	// Parse JSON request body to Schedule struct; the date is read as text to keep its offset
+	request := struct {
+		*models.Schedule
+		Date string `json:"date"`
+	}{Schedule: schedule}
 	jsonDecoder := json.NewDecoder(r.Body)
-	err := jsonDecoder.Decode(&schedule)
+	err := jsonDecoder.Decode(&request)
 	// Check if parsing is correct; return 400 in case of error
 	if err != nil {
-		errorHandling.ThrowError(w, http.StatusBadRequest, "Invalid JSON", nil)
+		errorHandling.ThrowError(w, r, errorHandling.InvalidJSON, "Invalid JSON", nil)
 		return
 	}
 
-	// Check if classes array is not empty; return 400 in case of error
-	if len(schedule.Classes) == 0 {
-		errorHandling.ThrowError(w, http.StatusBadRequest, "No classes found for schedule creation", nil)
+	// Schedules are stored at midnight UTC of their day, like the by-date lookup and the filters take it,
+	// so the unique date index allows one schedule per day
+	if request.Date != "" {
+		day, err := parseDay(request.Date)
+		if err != nil {
+			errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid schedule fields", nil, errorHandling.Detail{Field: "date", Message: "date must be YYYY-MM-DD or an RFC3339 time"})
+			return
+		}
+		schedule.Date = primitive.NewDateTimeFromTime(day)
+	}
+
+	// Check if date is set, classes array is not empty and every class is valid; return 400 in case of error
+	if details := validateSchedule(schedule); len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid schedule fields", nil, details...)
 		return
 	}
 
-	// Create primitive object id in mongo for schedule
-	schedule.Id = primitive.NewObjectID()
+	// Check the students, teachers and rooms of the classes
+	var details []errorHandling.Detail
+	for index := range schedule.Classes {
+		schedule.Classes[index].StudentDeleted = false
+		classDetails, err := checkReferences(r.Context(), scheduleHandler.Students, scheduleHandler.Teachers, scheduleHandler.Rooms, &schedule.Classes[index], fmt.Sprintf("classes[%d].", index))
+		if err != nil {
+			errorHandling.ThrowDbError(w, r, "Failed to retrieve student, teacher or room", err)
+			return
+		}
+		details = append(details, classDetails...)
+	}
+	if len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid schedule fields", nil, details...)
+		return
+	}
 
-	// Connect to DB
-	db := db.DbConnect()
-	// Disconnect from the DB
-	defer db.DbDisconnect()
-	collection := db.Client.Database("artschool-admin").Collection("schedule")
+	// Check that no room, student or teacher is booked twice; return 409 with the conflicts
+	details, err = conflictDetails(r.Context(), scheduleHandler.Rooms, schedule.Classes, -1)
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve rooms", err)
+		return
+	}
+	if len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ScheduleConflict, "Classes of the schedule conflict", nil, details...)
+		return
+	}
 
-	// Insert schedule object to schedule collection in mongo
-	_, err = collection.InsertOne(nil, schedule)
+	// Create primitive object id in mongo for schedule and its classes
+	schedule.Id = primitive.NewObjectID()
+	for index := range schedule.Classes {
+		schedule.Classes[index].Id, schedule.Classes[index].SubscriptionId = primitive.NewObjectID(), nil
+	}
+
+	// Insert schedule object to schedule collection
+	err = scheduleHandler.Schedules.Create(r.Context(), schedule)
+	if errors.Is(err, storage.ErrDuplicate) {
+		errorHandling.ThrowError(w, r, errorHandling.Duplicate, "Schedule for this date already exists", nil, errorHandling.Detail{Field: "date", Message: "date must be unique"})
+		return
+	}
 	if err != nil {
-		errorHandling.ThrowError(w, http.StatusInternalServerError, "Failed to insert the schedule into the database", &err)
+		errorHandling.ThrowDbError(w, r, "Failed to insert the schedule into the database", err)
 		return
 	}
 
 	// Log the created schedule
 	log.Printf("Created schedule")
+	recordAudit(r, scheduleHandler.Audit, auditSchedule, schedule.Id, models.AuditCreate, nil, schedule)
 
-	// Respond with the created student data
-	w.WriteHeader(http.StatusCreated)
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(schedule)
+	// Respond with the created schedule data
+	writeJSON(w, http.StatusCreated, schedule)
 }
 
-// GET for schedules list
+// GET for schedules list, ordered by date
+// Filters: date, or from and to (both included), as YYYY-MM-DD or RFC3339;
+// with ?teacherId= returns only the days of the teacher and only the classes of the teacher in them
 func (scheduleHandler *ScheduleHandler) List(w http.ResponseWriter, r *http.Request) {
 	// Check if the method is GET; return 405 in case of error
 	if r.Method != http.MethodGet {
-		errorHandling.ThrowError(w, http.StatusMethodNotAllowed, "Invalid request method. Needs to be GET", nil)
+		errorHandling.ThrowError(w, r, errorHandling.MethodNotAllowed, "Invalid request method. Needs to be GET", nil)
 		return
 	}
 
-	// Connect to DB
-	db := db.DbConnect()
-	defer db.DbDisconnect()
-	collection := db.Client.Database("artschool-admin").Collection("schedule")
+	query, details := parseScheduleQuery(r)
+	if len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid schedule filters", nil, details...)
+		return
+	}
 
-	// Retrieve all documents without context
-	cursor, err := collection.Find(nil, bson.M{})
+	// Retrieve the schedules
+	schedules, err := scheduleHandler.Schedules.List(r.Context(), query)
 	if err != nil {
-		errorHandling.ThrowError(w, http.StatusInternalServerError, "Failed to retrieve documents from the database", &err)
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve documents from the database", err)
 		return
 	}
-	defer cursor.Close(nil)
 
-	// Prepare a slice to hold the documents
-	var schedules []Schedule
+	// Keep only the classes of the teacher
+	if query.TeacherId != nil {
+		for index := range schedules {
+			schedules[index].Classes = slices.DeleteFunc(schedules[index].Classes, func(class models.Class) bool {
+				return class.TeacherId == nil || *class.TeacherId != *query.TeacherId
+			})
+		}
+	}
 
-	// Iterate through the cursor and decode each document into a Schedule struct
-	for cursor.Next(nil) {
-		var schedule Schedule
-		if err := cursor.Decode(&schedule); err != nil {
-			errorHandling.ThrowError(w, http.StatusInternalServerError, "Failed to decode document", &err)
-			return
+	// Respond with the list of schedules as JSON
+	writeJSON(w, http.StatusOK, schedules)
+}
+
+// Read the schedule filters from the query string
+func parseScheduleQuery(r *http.Request) (models.ScheduleQuery, []errorHandling.Detail) {
+	values := r.URL.Query()
+	query := models.ScheduleQuery{}
+	var details []errorHandling.Detail
+
+	if teacherId := values.Get("teacherId"); teacherId != "" {
+		objectID, err := primitive.ObjectIDFromHex(teacherId)
+		if err != nil {
+			details = append(details, errorHandling.Detail{Field: "teacherId", Message: "must be a 24 characters hex ObjectId"})
+		}
+		query.TeacherId = &objectID
+	}
+
+	if values.Has("date") && (values.Has("from") || values.Has("to")) {
+		return query, append(details, errorHandling.Detail{Field: "date", Message: "date can not be used with from and to"})
+	}
+	for _, name := range []string{"date", "from", "to"} {
+		if !values.Has(name) {
+			continue
+		}
+		day, err := parseDay(values.Get(name))
+		if err != nil {
+			details = append(details, errorHandling.Detail{Field: name, Message: name + " must be a YYYY-MM-DD date or an RFC3339 time"})
+			continue
+		}
+		// To is excluded in the query, so it is the day after
+		switch name {
+		case "date":
+			query.From, query.To = day, day.AddDate(0, 0, 1)
+		case "from":
+			query.From = day
+		case "to":
+			query.To = day.AddDate(0, 0, 1)
 		}
-		schedules = append(schedules, schedule)
 	}
+	if len(details) == 0 && !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		details = append(details, errorHandling.Detail{Field: "to", Message: "to must not be before from"})
+	}
+
+	return query, details
+}
 
-	if err := cursor.Err(); err != nil {
-		errorHandling.ThrowError(w, http.StatusInternalServerError, "Error occurred during cursor iteration", &err)
+// GET for the schedule of one day; the day is YYYY-MM-DD or an RFC3339 time
+func (scheduleHandler *ScheduleHandler) GetByDate(w http.ResponseWriter, r *http.Request) {
+	day, err := parseDay(chi.URLParam(r, "date"))
+	if err != nil {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid date", nil, errorHandling.Detail{Field: "date", Message: "date must be a YYYY-MM-DD date or an RFC3339 time"})
 		return
 	}
 
-	// Respond with the list of schedules as JSON
-	w.WriteHeader(http.StatusOK)
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(schedules)
+	// A range of the whole day also finds schedules saved with a time other than midnight
+	schedules, err := scheduleHandler.Schedules.List(r.Context(), models.ScheduleQuery{From: day, To: day.AddDate(0, 0, 1)})
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve documents from the database", err)
+		return
+	}
+	if len(schedules) == 0 {
+		errorHandling.ThrowError(w, r, errorHandling.NotFound, "No schedule found for this date", nil)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, schedules[0])
 }
 
 // GET for one schedule by ID
 func (scheduleHandler *ScheduleHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	// Check if the method is GET; return 405 in case of error
 	if r.Method != http.MethodGet {
-		errorHandling.ThrowError(w, http.StatusMethodNotAllowed, "Invalid request method. Needs to be GET", nil)
+		errorHandling.ThrowError(w, r, errorHandling.MethodNotAllowed, "Invalid request method. Needs to be GET", nil)
 		return
 	}
 
 	// Extract the ObjectId from the URL path
-	id := strings.TrimPrefix(r.URL.Path, "/schedule/")
-	// Convert the string ID to a MongoDB ObjectId type
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		errorHandling.ThrowError(w, http.StatusBadRequest, "Invalid ObjectId format", nil)
+	objectID, ok := parseObjectId(w, r, "id")
+	if !ok {
 		return
 	}
-	// Create a filter to search for the document with this ObjectId
-	filter := bson.M{"_id": objectID}
-	var result bson.M
-
-	// Connect to DB
-	db := db.DbConnect()
-	// Disconnect from the DB
-	defer db.DbDisconnect()
-	// Define collection
-	collection := db.Client.Database("artschool-admin").Collection("schedule")
 
 	// Find the record with required id
-	err = collection.FindOne(nil, filter).Decode(&result)
+	schedule, err := scheduleHandler.Schedules.Get(r.Context(), objectID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			errorHandling.ThrowError(w, http.StatusNotFound, "No document found with the given ObjectId", nil)
-		} else {
-			errorHandling.ThrowError(w, http.StatusInternalServerError, "Failed to retrieve document", &err)
-		}
+		throwStorageError(w, r, err, "Failed to retrieve document")
 		return
 	}
 
-	// Set the response header to JSON and encode the result
-	w.WriteHeader(http.StatusOK)
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	// Respond with the schedule as JSON
+	writeJSON(w, http.StatusOK, schedule)
 }
 
 // PUT for schedule classes update
+// Adds the class to the schedule or replaces the class of the same student; the class keeps its id
 func (scheduleHandler *ScheduleHandler) UpdateByID(w http.ResponseWriter, r *http.Request) {
 	// Check if the method is PUT; return 405 in case of error
 	if r.Method != http.MethodPut {
-		errorHandling.ThrowError(w, http.StatusMethodNotAllowed, "Invalid request method. Needs to be PUT", nil)
+		errorHandling.ThrowError(w, r, errorHandling.MethodNotAllowed, "Invalid request method. Needs to be PUT", nil)
 		return
 	}
 
 	// Extract the ObjectId from the URL path
-	id := strings.TrimPrefix(r.URL.Path, "/schedule/")
-	// Convert the string ID to a MongoDB ObjectId type
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		errorHandling.ThrowError(w, http.StatusBadRequest, "Invalid ObjectId format", nil)
+	objectID, ok := parseObjectId(w, r, "id")
+	if !ok {
 		return
 	}
 
 	// GET CURRENT SCHEDULE
 
-	// Create a filter to search for the document with this ObjectId
-	filter := bson.M{"_id": objectID}
-	var currentSchedule Schedule
-
-	// Connect to DB
-	db := db.DbConnect()
-	// Disconnect from the DB
-	defer db.DbDisconnect()
-	// Define collection
-	collection := db.Client.Database("artschool-admin").Collection("schedule")
-
-	// Find the record with required id
-	err = collection.FindOne(nil, filter).Decode(&currentSchedule)
+	currentSchedule, err := scheduleHandler.Schedules.Get(r.Context(), objectID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			errorHandling.ThrowError(w, http.StatusNotFound, "No document found with the given ObjectId", nil)
-		} else {
-			errorHandling.ThrowError(w, http.StatusInternalServerError, "Failed to retrieve document", nil)
-		}
+		throwStorageError(w, r, err, "Failed to retrieve document")
 		return
 	}
 
-	// Get current schedule student ids
-	var currentStudentIds []primitive.ObjectID
-	for classIndex := range currentSchedule.Classes {
-		currentStudentIds = append(currentStudentIds, currentSchedule.Classes[classIndex].StudentId)
-	}
-
 	// UPDATE THE CURRENT SCHEDULE
 
-	// Decode request body to schedule object
-	updatedClass := Class{}
+	// Decode request body to class object
+	updatedClass := models.Class{}
 	jsonDecoder := json.NewDecoder(r.Body)
 	err = jsonDecoder.Decode(&updatedClass)
 	if err != nil {
-		errorHandling.ThrowError(w, http.StatusBadRequest, "Invalid request body", nil)
+		errorHandling.ThrowError(w, r, errorHandling.InvalidJSON, "Invalid request body", nil)
+		return
+	}
+	if details := validateClass(&updatedClass, ""); len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid class fields", nil, details...)
+		return
+	}
+	updatedClass.StudentDeleted = false
+	details, err := checkReferences(r.Context(), scheduleHandler.Students, scheduleHandler.Teachers, scheduleHandler.Rooms, &updatedClass, "")
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve student, teacher or room", err)
+		return
+	}
+	if len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid class fields", nil, details...)
 		return
 	}
 
 	// Check if class is already booked for this student
-	var studentClassExists bool
+	studentClassExists := false
 	var updatedClassIndex int
-	for index, scheduledStudentId := range currentStudentIds {
-		if scheduledStudentId == updatedClass.StudentId {
-			studentClassExists = true
-			updatedClassIndex = index
-			break
+	for index, class := range currentSchedule.Classes {
+		if class.StudentId != updatedClass.StudentId {
+			continue
 		}
-		studentClassExists = false
+		if studentClassExists {
+			errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid class fields", nil, errorHandling.Detail{Field: "studentId", Message: "student has several classes on this day, change them with PATCH /schedule/{id}/classes/{classId}"})
+			return
+		}
+		studentClassExists = true
+		updatedClassIndex = index
 	}
-	log.Println(studentClassExists, updatedClassIndex)
 
-	if !(studentClassExists) {
+	// Attendance and the used pack are kept as they are, they change only through the attendance endpoint
+	currentClass := models.Class{Id: primitive.NewObjectID()}
+	if studentClassExists {
+		currentClass = currentSchedule.Classes[updatedClassIndex]
+	}
+	if updatedClass.Attendence != nil && (currentClass.Attendence == nil || *currentClass.Attendence != *updatedClass.Attendence) {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid class fields", nil, errorHandling.Detail{Field: "attendance", Message: attendanceMessage})
+		return
+	}
+	updatedClass.Id, updatedClass.Attendence, updatedClass.SubscriptionId = currentClass.Id, currentClass.Attendence, currentClass.SubscriptionId
+
+	// Copy the current state for the audit log before changing the classes
+	before := *currentSchedule
+	before.Classes = slices.Clone(currentSchedule.Classes)
+
+	if !studentClassExists {
 		currentSchedule.Classes = append(currentSchedule.Classes, updatedClass)
+		updatedClassIndex = len(currentSchedule.Classes) - 1
 	} else {
 		currentSchedule.Classes[updatedClassIndex] = updatedClass
 	}
 
-	log.Println(currentSchedule)
-
-	// Find the record with required id
-	updateResult, err := collection.UpdateByID(nil, objectID, bson.M{"$set": currentSchedule})
+	// Check that the class does not overbook its room or teacher; return 409 with the conflicts
+	details, err = conflictDetails(r.Context(), scheduleHandler.Rooms, currentSchedule.Classes, updatedClassIndex)
 	if err != nil {
-		errorHandling.ThrowError(w, http.StatusInternalServerError, "Failed to update schedule", &err)
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve rooms", err)
 		return
 	}
-	if updateResult.MatchedCount == 0 {
-		errorHandling.ThrowError(w, http.StatusNotFound, "No record found with the provided ID", nil)
+	if len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ScheduleConflict, "Class conflicts with other classes of the day", nil, details...)
 		return
 	}
 
-	// Write the response with updated keys
+	// Save only this class, so attendance and classes saved meanwhile by other requests are kept
+	if studentClassExists {
+		err = scheduleHandler.Schedules.UpdateClass(r.Context(), objectID, &updatedClass, classPatchFields)
+	} else {
+		err = scheduleHandler.Schedules.AddClass(r.Context(), objectID, &updatedClass)
+	}
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to update schedule")
+		return
+	}
+	scheduleHandler.auditClassChange(r, &before)
+
+	// Write the response
 	response := fmt.Sprintf("Schedule updated successfully")
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(response))
@@ -274,39 +367,81 @@ func (scheduleHandler *ScheduleHandler) UpdateByID(w http.ResponseWriter, r *htt
 func (scheduleHandler *ScheduleHandler) DeleteByID(w http.ResponseWriter, r *http.Request) {
 	// Check if the method is Delete; return 405 in case of error
 	if r.Method != http.MethodDelete {
-		errorHandling.ThrowError(w, http.StatusMethodNotAllowed, "Invalid request method. Needs to be Delete", nil)
+		errorHandling.ThrowError(w, r, errorHandling.MethodNotAllowed, "Invalid request method. Needs to be Delete", nil)
 		return
 	}
 
 	// Extract the ObjectId from the URL path
-	id := strings.TrimPrefix(r.URL.Path, "/schedule/")
-	// Convert the string ID to a MongoDB ObjectId type
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		errorHandling.ThrowError(w, http.StatusBadRequest, "Invalid ObjectId format", nil)
+	objectID, ok := parseObjectId(w, r, "id")
+	if !ok {
 		return
 	}
 
-	// Connect to DB
-	db := db.DbConnect()
-	// Disconnect from the DB
-	defer db.DbDisconnect()
-	// Define collection
-	collection := db.Client.Database("artschool-admin").Collection("schedule")
-
-	// Delete record with mentioned id
-	deleteResult, err := collection.DeleteOne(nil, bson.M{"_id": objectID})
+	// Keep the deleted schedule for the audit log
+	before, err := scheduleHandler.Schedules.Get(r.Context(), objectID)
 	if err != nil {
-		errorHandling.ThrowError(w, http.StatusInternalServerError, "Failed to delete schedule", &err)
+		throwStorageError(w, r, err, "Failed to retrieve document")
 		return
 	}
-	if deleteResult.DeletedCount == 0 {
-		errorHandling.ThrowError(w, http.StatusInternalServerError, fmt.Sprintf("No schedule found with the provided ID: %v", id), nil)
+
+	// Delete record with mentioned id
+	err = scheduleHandler.Schedules.Delete(r.Context(), objectID)
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to delete schedule")
 		return
 	}
+	recordAudit(r, scheduleHandler.Audit, auditSchedule, objectID, models.AuditDelete, before, nil)
+	recordCancellations(r, scheduleHandler.Cancellations, before, before.Classes)
 
 	// Write the response with deleted schedule id
-	response := fmt.Sprintf("Deleted schedule by mentioned id: %v", id)
+	response := fmt.Sprintf("Deleted schedule by mentioned id: %v", objectID.Hex())
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(response))
 }
+
+// Check that the student of the class exists and is not archived, that the teacher exists, is active and teaches the type of the class,
+// and that the room exists.
+// Classes without a teacher or a room are valid; prefix locates the class inside the request body
+func checkReferences(ctx context.Context, students storage.StudentRepository, teachers storage.TeacherRepository, rooms storage.RoomRepository, class *models.Class, prefix string) ([]errorHandling.Detail, error) {
+	var details []errorHandling.Detail
+
+	// Classes of an archived or deleted student stay as history and can still be moved to another time or room
+	if !class.StudentDeleted {
+		student, err := students.Get(ctx, class.StudentId)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return nil, err
+		}
+		switch {
+		case err != nil:
+			details = append(details, errorHandling.Detail{Field: prefix + "studentId", Message: "no student found with this id"})
+		case student.DeletedAt != nil:
+			details = append(details, errorHandling.Detail{Field: prefix + "studentId", Message: "student is archived"})
+		}
+	}
+
+	if class.TeacherId != nil {
+		teacher, err := teachers.Get(ctx, *class.TeacherId)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return nil, err
+		}
+		switch {
+		case err != nil:
+			details = append(details, errorHandling.Detail{Field: prefix + "teacherId", Message: "no teacher found with this id"})
+		case !teacher.Active:
+			details = append(details, errorHandling.Detail{Field: prefix + "teacherId", Message: "teacher is not active"})
+		case !slices.Contains(teacher.ClassTypes, class.Type):
+			details = append(details, errorHandling.Detail{Field: prefix + "teacherId", Message: "teacher does not teach " + class.Type + " classes"})
+		}
+	}
+
+	if class.RoomId != nil {
+		_, err := rooms.Get(ctx, *class.RoomId)
+		if errors.Is(err, storage.ErrNotFound) {
+			details = append(details, errorHandling.Detail{Field: prefix + "roomId", Message: "no room found with this id"})
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	return details, nil
+}
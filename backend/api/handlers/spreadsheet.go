@@ -0,0 +1,445 @@
+package handler
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Readers and writers of spreadsheet files for the import and the exports. XLSX is handled with the standard library only:
+// just the values of the first sheet are read, formulas, styles and other sheets are ignored, and written files have one plain sheet
+
+// One row of a spreadsheet with its row number in the file
+type sheetRow struct {
+	line  int
+	cells []string
+}
+
+// Limits of the read files, so a small file can not fill the memory
+const (
+	// Largest unpacked part of an XLSX file
+	maxXLSXPart = 64 << 20
+	// Most cells in one row; rows are padded up to the last cell, so a far cell reference costs memory
+	maxSheetColumns = 1024
+	// Last column of Excel, XFD
+	maxXLSXColumn = 16383
+)
+
+// Returned when the file has more rows than the caller reads
+var errTooManyRows = errors.New("too many rows")
+
+// Read a CSV or an XLSX file with at most maxRows rows, the header included.
+// XLSX files are zip archives and are told apart by the zip signature
+func readSheet(content []byte, maxRows int) ([]sheetRow, error) {
+	if bytes.HasPrefix(content, []byte("PK\x03\x04")) {
+		return readXLSX(content, maxRows)
+	}
+
+	return readCSV(content, maxRows)
+}
+
+// Read a CSV file; the delimiter is a comma or, as Excel saves it in many locales, a semicolon
+func readCSV(content []byte, maxRows int) ([]sheetRow, error) {
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	firstLine, _, _ := bytes.Cut(content, []byte("\n"))
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+
+	var rows []sheetRow
+	for {
+		cells, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(rows) == maxRows {
+			return nil, errTooManyRows
+		}
+		if len(cells) > maxSheetColumns {
+			return nil, fmt.Errorf("invalid CSV: row %v has more than %v columns", line, maxSheetColumns)
+		}
+		rows = append(rows, sheetRow{line: line, cells: cells})
+	}
+
+	return rows, nil
+}
+
+// Parts of the XLSX files that are read
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RelationId string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelations struct {
+	Relations []struct {
+		Id     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// Text of a shared or inline string; rich text is split into runs
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (text xlsxText) String() string {
+	result := text.Text
+	for _, run := range text.Runs {
+		result += run.Text
+	}
+	return result
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Number int `xml:"r,attr"`
+		Cells  []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// Read the first sheet of an XLSX file
+func readXLSX(content []byte, maxRows int) ([]sheetRow, error) {
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid XLSX: %w", err)
+	}
+
+	workbook := xlsxWorkbook{}
+	if err := readXLSXPart(archive, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	if len(workbook.Sheets) == 0 {
+		return nil, errors.New("invalid XLSX: the workbook has no sheets")
+	}
+	relations := xlsxRelations{}
+	if err := readXLSXPart(archive, "xl/_rels/workbook.xml.rels", &relations); err != nil {
+		return nil, err
+	}
+	sheetPath := ""
+	for _, relation := range relations.Relations {
+		if relation.Id == workbook.Sheets[0].RelationId {
+			sheetPath = path.Join("xl", relation.Target)
+			if strings.HasPrefix(relation.Target, "/") {
+				sheetPath = strings.TrimPrefix(relation.Target, "/")
+			}
+		}
+	}
+	if sheetPath == "" {
+		return nil, errors.New("invalid XLSX: the first sheet is missing")
+	}
+
+	// Workbooks with numbers only have no shared strings
+	shared := xlsxSharedStrings{}
+	for _, file := range archive.File {
+		if file.Name == "xl/sharedStrings.xml" {
+			if err := readXLSXPart(archive, file.Name, &shared); err != nil {
+				return nil, err
+			}
+		}
+	}
+	worksheet := xlsxWorksheet{}
+	if err := readXLSXPart(archive, sheetPath, &worksheet); err != nil {
+		return nil, err
+	}
+
+	if len(worksheet.Rows) > maxRows {
+		return nil, errTooManyRows
+	}
+
+	var rows []sheetRow
+	line := 0
+	for _, xlsxRow := range worksheet.Rows {
+		// Row and cell references may be left out; then they follow the previous ones
+		line++
+		if xlsxRow.Number > 0 {
+			line = xlsxRow.Number
+		}
+		var cells []string
+		for _, cell := range xlsxRow.Cells {
+			column, err := xlsxColumn(cell.Ref)
+			if err != nil {
+				return nil, err
+			}
+			if column < 0 {
+				column = len(cells)
+			}
+			if column >= maxSheetColumns {
+				return nil, fmt.Errorf("invalid XLSX: row %v has more than %v columns", line, maxSheetColumns)
+			}
+			for len(cells) <= column {
+				cells = append(cells, "")
+			}
+
+			switch cell.Type {
+			case "s":
+				index, err := strconv.Atoi(cell.Value)
+				if err != nil || index < 0 || index >= len(shared.Items) {
+					return nil, fmt.Errorf("invalid XLSX: cell %v has an unknown shared string", cell.Ref)
+				}
+				cells[column] = shared.Items[index].String()
+			case "inlineStr":
+				cells[column] = cell.Inline.String()
+			case "b":
+				cells[column] = strings.ToUpper(strconv.FormatBool(cell.Value == "1"))
+			default:
+				cells[column] = cell.Value
+			}
+		}
+		rows = append(rows, sheetRow{line: line, cells: cells})
+	}
+
+	return rows, nil
+}
+
+// Decode one XML part of the XLSX archive
+func readXLSXPart(archive *zip.Reader, name string, value interface{}) error {
+	file, err := archive.Open(name)
+	if err != nil {
+		return fmt.Errorf("invalid XLSX: %v is missing", name)
+	}
+	defer file.Close()
+
+	err = xml.NewDecoder(io.LimitReader(file, maxXLSXPart)).Decode(value)
+	if err != nil {
+		return fmt.Errorf("invalid XLSX: %v: %w", name, err)
+	}
+
+	return nil
+}
+
+// Index of the column of a cell reference: A1 is 0, AB7 is 27; -1 without a reference.
+// References past XFD, the last column of Excel, are invalid
+func xlsxColumn(ref string) (int, error) {
+	column := 0
+	for _, char := range ref {
+		if char < 'A' || char > 'Z' {
+			break
+		}
+		column = column*26 + int(char-'A'+1)
+		if column-1 > maxXLSXColumn {
+			return 0, fmt.Errorf("invalid XLSX: cell %v is past the last column XFD", ref)
+		}
+	}
+
+	return column - 1, nil
+}
+
+// Formats of the exports
+const (
+	formatCSV  = "csv"
+	formatXLSX = "xlsx"
+)
+
+// Static parts of a written XLSX file with one sheet
+var xlsxParts = []struct {
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+// Create struct (class) for sheetWriter to stream a spreadsheet to the response row by row.
+// Nothing is sent before the first data row or Close, so an error before them can still be answered with JSON
+type sheetWriter struct {
+	w        http.ResponseWriter
+	format   string
+	filename string
+	header   []string
+	started  bool
+	rows     int
+	csv      *csv.Writer
+	archive  *zip.Writer
+	sheet    *bufio.Writer
+}
+
+func newSheetWriter(w http.ResponseWriter, format string, name string, header []string) *sheetWriter {
+	return &sheetWriter{w: w, format: format, filename: name + "." + format, header: header}
+}
+
+// Send the headers, the start of the file and the header row
+func (sheet *sheetWriter) start() error {
+	sheet.started = true
+	sheet.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", sheet.filename))
+
+	if sheet.format == formatCSV {
+		sheet.w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		sheet.w.WriteHeader(http.StatusOK)
+		// The byte order mark makes Excel read the file as UTF-8
+		sheet.w.Write([]byte("\xef\xbb\xbf"))
+		sheet.csv = csv.NewWriter(sheet.w)
+	} else {
+		sheet.w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		sheet.w.WriteHeader(http.StatusOK)
+		sheet.archive = zip.NewWriter(sheet.w)
+		for _, part := range xlsxParts {
+			file, err := sheet.archive.Create(part.name)
+			if err != nil {
+				return err
+			}
+			if _, err := io.WriteString(file, part.content); err != nil {
+				return err
+			}
+		}
+		file, err := sheet.archive.Create("xl/worksheets/sheet1.xml")
+		if err != nil {
+			return err
+		}
+		sheet.sheet = bufio.NewWriter(file)
+		sheet.sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	}
+
+	header := make([]interface{}, len(sheet.header))
+	for index, name := range sheet.header {
+		header[index] = name
+	}
+	return sheet.writeRow(header)
+}
+
+// WriteRow writes one row; cells are strings, ints or nil for empty cells
+func (sheet *sheetWriter) WriteRow(cells ...interface{}) error {
+	if !sheet.started {
+		if err := sheet.start(); err != nil {
+			return err
+		}
+	}
+
+	return sheet.writeRow(cells)
+}
+
+func (sheet *sheetWriter) writeRow(cells []interface{}) error {
+	sheet.rows++
+	if sheet.format == formatCSV {
+		record := make([]string, len(cells))
+		for index, cell := range cells {
+			switch value := cell.(type) {
+			case string:
+				record[index] = csvText(value)
+			case int:
+				record[index] = strconv.Itoa(value)
+			}
+		}
+		return sheet.csv.Write(record)
+	}
+
+	fmt.Fprintf(sheet.sheet, `<row r="%d">`, sheet.rows)
+	for index, cell := range cells {
+		ref := xlsxColumnName(index) + strconv.Itoa(sheet.rows)
+		switch value := cell.(type) {
+		case string:
+			// Inline strings are never read as formulas
+			fmt.Fprintf(sheet.sheet, `<c r="%v" t="inlineStr"><is><t xml:space="preserve">`, ref)
+			xml.EscapeText(sheet.sheet, []byte(value))
+			sheet.sheet.WriteString(`</t></is></c>`)
+		case int:
+			fmt.Fprintf(sheet.sheet, `<c r="%v"><v>%d</v></c>`, ref, value)
+		}
+	}
+	_, err := sheet.sheet.WriteString(`</row>`)
+	return err
+}
+
+// Close writes the rest of the file; a file without data rows has only the header row
+func (sheet *sheetWriter) Close() error {
+	if !sheet.started {
+		if err := sheet.start(); err != nil {
+			return err
+		}
+	}
+
+	if sheet.format == formatCSV {
+		sheet.csv.Flush()
+		return sheet.csv.Error()
+	}
+	sheet.sheet.WriteString(`</sheetData></worksheet>`)
+	if err := sheet.sheet.Flush(); err != nil {
+		return err
+	}
+	return sheet.archive.Close()
+}
+
+// Keep spreadsheet apps from running text as a formula; numbers like phones with + stay as they are
+func csvText(text string) string {
+	if text == "" {
+		return text
+	}
+	switch text[0] {
+	case '=', '@', '\t', '\r':
+		return "'" + text
+	case '+', '-':
+		if _, err := strconv.ParseFloat(text, 64); err != nil {
+			return "'" + text
+		}
+	}
+
+	return text
+}
+
+// Remove the ' that csvText puts in front of text, so an exported file imports unchanged
+func unescapeCSVText(text string) string {
+	if len(text) < 2 || text[0] != '\'' {
+		return text
+	}
+	switch text[1] {
+	case '=', '@', '\t', '\r', '+', '-':
+		return text[1:]
+	}
+
+	return text
+}
+
+// Name of the column with the index: 0 is A, 27 is AB
+func xlsxColumnName(index int) string {
+	name := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = string(rune('A'+(index-1)%26)) + name
+	}
+
+	return name
+}
@@ -1,46 +1,72 @@
-// TODO add id return in GET request
-
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"time"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
-	"github.com/DanVerh/artschool-admin/backend/api/db"
+	"github.com/DanVerh/artschool-admin/backend/api/auth"
+	"github.com/DanVerh/artschool-admin/backend/api/config"
 	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+	"github.com/DanVerh/artschool-admin/backend/api/storage"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Errors of archiving and restoring students
+var (
+	errStudentHasClasses  = errors.New("student has future classes")
+	errStudentArchived    = errors.New("student is archived")
+	errStudentNotArchived = errors.New("student is not archived")
 )
 
 // Create struct (class) for StudentHandler to handle requests
-type StudentHandler struct{}
-
-// Create struct (class) for Student
-type Student struct{
-	Id 			 primitive.ObjectID	`json:"id" bson:"_id"`
-    Fullname     string    `json:"fullname" bson:"fullname"`
-    Phone        string    `json:"phone" bson:"phone"`
-    Subscription *int       `json:"subscription" bson:"subscription"`
-    StartDate    *time.Time `json:"startDate" bson:"startDate"`
-    LastDate     *time.Time `json:"lastDate" bson:"lastDate"`
-    Comments     *string    `json:"comments" bson:"comments"`
+type StudentHandler struct {
+	Students     storage.StudentRepository
+	Schedules    storage.ScheduleRepository
+	Audit        storage.AuditRepository
+	Transactions storage.Transactor
+	// Classes removed by the cascade policy, kept for the calendar feeds
+	Cancellations storage.CancellationRepository
+	// What happens to the future classes of a deleted student, one of the config.*StudentDelete policies
+	DeletePolicy string
+}
+
+// Permission required to change each student field
+var studentFieldPermissions = map[string]auth.Permission{
+	"fullname":  auth.StudentsUpdateContacts,
+	"phone":     auth.StudentsUpdateContacts,
+	"startDate": auth.StudentsUpdateSubscription,
+	"lastDate":  auth.StudentsUpdateSubscription,
+	"comments":  auth.StudentsUpdateComments,
 }
 
+// Fields the students list can be sorted by
+var studentSortFields = []string{"fullname", "startDate", "lastDate", "subscription"}
+
+// Limits of the students page
+const (
+	defaultStudentsLimit = 50
+	maxStudentsLimit     = 500
+)
+
 // Define all methods of Student as handlers for routes
 
 // POST for student creation
 func (studentHandler *StudentHandler) Create(w http.ResponseWriter, r *http.Request) {
 	// Create Student object
-	student := &Student{}
+	student := &models.Student{}
 
 	// Check if the method is POST; return 405 in case of error
 	if r.Method != http.MethodPost {
-		errorHandling.ThrowError(w, http.StatusMethodNotAllowed, "Invalid request method. Needs to be POST", nil)
+		errorHandling.ThrowError(w, r, errorHandling.MethodNotAllowed, "Invalid request method. Needs to be POST", nil)
 		return
 	}
 
@@ -49,228 +75,452 @@ func (studentHandler *StudentHandler) Create(w http.ResponseWriter, r *http.Requ
 	err := jsonDecoder.Decode(student)
 	// Check if parsing is correct; return 400 in case of error
 	if err != nil {
-		errorHandling.ThrowError(w, http.StatusBadRequest, "Invalid JSON", nil)
+		errorHandling.ThrowError(w, r, errorHandling.InvalidJSON, "Invalid JSON", nil)
 		return
 	}
-	// Check if fullname and phone fields are passed in request
-	if student.Fullname == "" || student.Phone == "" {
-		errorHandling.ThrowError(w, http.StatusBadRequest, "Missing fullname or phone field", nil)
+	// Check if fullname and phone fields are passed in request and valid
+	if details := validateStudent(student); len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid student fields", nil, details...)
 		return
 	}
 
 	// Define default properties of new student
-	student.Id, student.Subscription, student.StartDate, student.LastDate, student.Comments = primitive.NewObjectID(),nil, nil, nil, nil
-
-	// Connect to DB
-	db := db.DbConnect()
-	// Disconnect from the DB
-	defer db.DbDisconnect()
-	collection := db.Client.Database("artschool-admin").Collection("students")
-	
-	_, err = collection.InsertOne(nil, student)
+	student.Id, student.Subscription, student.StartDate, student.LastDate, student.Comments = primitive.NewObjectID(), nil, nil, nil, nil
+
+	err = studentHandler.Students.Create(r.Context(), student)
+	if errors.Is(err, storage.ErrDuplicate) {
+		errorHandling.ThrowError(w, r, errorHandling.Duplicate, "Student with this phone already exists", nil, errorHandling.Detail{Field: "phone", Message: "phone must be unique"})
+		return
+	}
 	if err != nil {
-		errorHandling.ThrowError(w, http.StatusInternalServerError, "Failed to insert the student into the database", &err)
+		errorHandling.ThrowDbError(w, r, "Failed to insert the student into the database", err)
 		return
 	}
 
 	// Log the created student
 	log.Printf("Created student: %v, %v\n", student.Fullname, student.Phone)
+	recordAudit(r, studentHandler.Audit, auditStudent, student.Id, models.AuditCreate, nil, student)
 
 	// Respond with the created student data
-	w.WriteHeader(http.StatusCreated)
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(student)
+	writeJSON(w, http.StatusCreated, student)
 }
 
-
 // GET for students list
 func (studentHandler *StudentHandler) List(w http.ResponseWriter, r *http.Request) {
 	// Check if the method is GET; return 405 in case of error
 	if r.Method != http.MethodGet {
-		errorHandling.ThrowError(w, http.StatusMethodNotAllowed, "Invalid request method. Needs to be GET", nil)
+		errorHandling.ThrowError(w, r, errorHandling.MethodNotAllowed, "Invalid request method. Needs to be GET", nil)
 		return
 	}
 
-	// Connect to DB
-	db := db.DbConnect()
-	defer db.DbDisconnect()
-	collection := db.Client.Database("artschool-admin").Collection("students")
+	// Read filters, sorting and page from the query string
+	query, details := parseStudentQuery(r)
+	if len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid students query", nil, details...)
+		return
+	}
 
-	// Retrieve all documents without context
-	cursor, err := collection.Find(nil, bson.M{})
+	// Retrieve one page of students
+	students, total, err := studentHandler.Students.List(r.Context(), query)
 	if err != nil {
-		errorHandling.ThrowError(w, http.StatusInternalServerError, "Failed to retrieve documents from the database", &err)
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve documents from the database", err)
 		return
 	}
-	defer cursor.Close(nil)
 
-	// Prepare a slice to hold the documents
-	var students []Student
+	page := models.StudentPage{
+		Students: students,
+		Total:    total,
+		Limit:    query.Limit,
+		Offset:   query.Offset,
+		Next:     nextPage(r, query.Offset, len(students), total),
+	}
 
-	// Iterate through the cursor and decode each document into a Student struct
-	for cursor.Next(nil) {
-		var student Student
-		if err := cursor.Decode(&student); err != nil {
-			errorHandling.ThrowError(w, http.StatusInternalServerError, "Failed to decode document", &err)
-			return
+	// Respond with the page of students as JSON
+	writeJSON(w, http.StatusOK, page)
+}
+
+// Read the students query from the query string
+// Filters: subscription (number or "null"), lastDateBefore, lastDateAfter, comments, includeArchived
+// Sorting: sort by one of studentSortFields, "-" prefix for descending order
+// Page: limit and offset
+func parseStudentQuery(r *http.Request) (models.StudentQuery, []errorHandling.Detail) {
+	values := r.URL.Query()
+	query := models.StudentQuery{
+		Comments: values.Get("comments"),
+	}
+	var details []errorHandling.Detail
+
+	if subscription := values.Get("subscription"); subscription == "null" {
+		query.NullSubscription = true
+	} else if subscription != "" {
+		parsed, err := strconv.Atoi(subscription)
+		if err != nil {
+			details = append(details, errorHandling.Detail{Field: "subscription", Message: "subscription must be a number or null"})
 		}
-		students = append(students, student)
+		query.Subscription = &parsed
 	}
 
-	if err := cursor.Err(); err != nil {
-		errorHandling.ThrowError(w, http.StatusInternalServerError, "Error occurred during cursor iteration", &err)
-		return
+	for _, filter := range []struct {
+		name  string
+		value *time.Time
+	}{{"lastDateBefore", &query.LastDateBefore}, {"lastDateAfter", &query.LastDateAfter}} {
+		text := values.Get(filter.name)
+		if text == "" {
+			continue
+		}
+		parsed, err := parseTimeParam(text)
+		if err != nil {
+			details = append(details, errorHandling.Detail{Field: filter.name, Message: "must be an RFC3339 time or a YYYY-MM-DD date"})
+		}
+		*filter.value = parsed
 	}
 
-	// Respond with the list of students as JSON
-	w.WriteHeader(http.StatusOK)
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(students)
+	var archivedDetails []errorHandling.Detail
+	query.IncludeArchived, archivedDetails = parseIncludeArchived(r)
+	details = append(details, archivedDetails...)
+
+	if sort := values.Get("sort"); sort != "" {
+		query.Sort, query.Descending = strings.CutPrefix(sort, "-")
+		if !slices.Contains(studentSortFields, query.Sort) {
+			details = append(details, errorHandling.Detail{Field: "sort", Message: "sort must be one of " + strings.Join(studentSortFields, ", ") + ", optionally prefixed with -"})
+		}
+	}
+
+	var pageDetails []errorHandling.Detail
+	query.Limit, query.Offset, pageDetails = parsePage(r, defaultStudentsLimit, maxStudentsLimit)
+	details = append(details, pageDetails...)
+
+	return query, details
 }
 
+// Read the includeArchived flag; archived students are hidden by default
+func parseIncludeArchived(r *http.Request) (bool, []errorHandling.Detail) {
+	text := r.URL.Query().Get("includeArchived")
+	if text == "" {
+		return false, nil
+	}
+	includeArchived, err := strconv.ParseBool(text)
+	if err != nil {
+		return false, []errorHandling.Detail{{Field: "includeArchived", Message: "includeArchived must be true or false"}}
+	}
+
+	return includeArchived, nil
+}
 
 // GET for one student by ID
 func (studentHandler *StudentHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	// Check if the method is GET; return 405 in case of error
 	if r.Method != http.MethodGet {
-		errorHandling.ThrowError(w, http.StatusMethodNotAllowed, "Invalid request method. Needs to be GET", nil)
+		errorHandling.ThrowError(w, r, errorHandling.MethodNotAllowed, "Invalid request method. Needs to be GET", nil)
 		return
 	}
 
 	// Extract the ObjectId from the URL path
-    id := strings.TrimPrefix(r.URL.Path, "/students/")
-    // Convert the string ID to a MongoDB ObjectId type
-    objectID, err := primitive.ObjectIDFromHex(id)
-    if err != nil {
-		errorHandling.ThrowError(w, http.StatusBadRequest, "Invalid ObjectId format", nil)
-		return
-    }
-	// Create a filter to search for the document with this ObjectId
-	filter := bson.M{"_id": objectID}
-	var result bson.M
-
-	// Connect to DB
-	db := db.DbConnect()
-	// Disconnect from the DB
-	defer db.DbDisconnect()
-	// Define collection
-	collection := db.Client.Database("artschool-admin").Collection("students")
-
-	// Find the record with required id
-    err = collection.FindOne(nil, filter).Decode(&result)
-    if err != nil {
-        if err == mongo.ErrNoDocuments {
-			errorHandling.ThrowError(w, http.StatusNotFound, "No document found with the given ObjectId", nil)
-        } else {
-			errorHandling.ThrowError(w, http.StatusInternalServerError, "Failed to retrieve document", &err)
-        }
-        return
-    }
-
-    // Set the response header to JSON and encode the result
-	w.WriteHeader(http.StatusOK)
-    w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(result)
-}
+	objectID, ok := parseObjectId(w, r, "id")
+	if !ok {
+		return
+	}
+
+	includeArchived, details := parseIncludeArchived(r)
+	if len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid student query", nil, details...)
+		return
+	}
+
+	// Find the record with required id; archived students are found only with includeArchived
+	student, err := studentHandler.Students.Get(r.Context(), objectID)
+	if err == nil && student.DeletedAt != nil && !includeArchived {
+		err = storage.ErrNotFound
+	}
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to retrieve document")
+		return
+	}
 
+	// Respond with the student as JSON
+	writeJSON(w, http.StatusOK, student)
+}
 
 // PUT for one student by ID
 func (studentHandler *StudentHandler) UpdateByID(w http.ResponseWriter, r *http.Request) {
 	// Check if the method is PUT; return 405 in case of error
 	if r.Method != http.MethodPut {
-		errorHandling.ThrowError(w, http.StatusMethodNotAllowed, "Invalid request method. Needs to be PUT", nil)
+		errorHandling.ThrowError(w, r, errorHandling.MethodNotAllowed, "Invalid request method. Needs to be PUT", nil)
 		return
 	}
 
 	// Extract the ObjectId from the URL path
-	id := strings.TrimPrefix(r.URL.Path, "/students/")
-	// Convert the string ID to a MongoDB ObjectId type
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		errorHandling.ThrowError(w, http.StatusBadRequest, "Invalid ObjectId format", nil)
+	objectID, ok := parseObjectId(w, r, "id")
+	if !ok {
 		return
 	}
 
-	var updateBody bson.M
-    jsonDecoder := json.NewDecoder(r.Body)
-    err = jsonDecoder.Decode(&updateBody)
-    if err != nil {
-		errorHandling.ThrowError(w, http.StatusBadRequest, "Invalid request body", nil)
+	var updateBody map[string]interface{}
+	jsonDecoder := json.NewDecoder(r.Body)
+	err := jsonDecoder.Decode(&updateBody)
+	if err != nil {
+		errorHandling.ThrowError(w, r, errorHandling.InvalidJSON, "Invalid request body", nil)
 		return
-    }
+	}
 
-	// Connect to DB
-	db := db.DbConnect()
-	// Disconnect from the DB
-	defer db.DbDisconnect()
-	// Define collection
-	collection := db.Client.Database("artschool-admin").Collection("students")
+	// Check if only known student fields are updated with valid values
+	if details := validateStudentUpdate(updateBody); len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid student fields", nil, details...)
+		return
+	}
 
-	// Check if any student field is updated and save these fields to slice
+	// Check if the user may change every updated field and save these fields to slice
 	var updateKeys []string
 	for updateKey := range updateBody {
-		if _, found := map[string]bool{"fullname": true, "phone": true, "subscription": true, "startDate": true, "lastDate": true, "comments": true}[updateKey]; !found {
-			errorHandling.ThrowError(w, http.StatusBadRequest, "No student field is updated", nil)
+		if permission := studentFieldPermissions[updateKey]; !auth.Can(r, permission) {
+			auth.ThrowForbidden(w, r, updateKey, permission)
 			return
 		}
 		updateKeys = append(updateKeys, updateKey)
 	}
 
-	// Find the record with required id
-	updateResult, err := collection.UpdateByID(nil, objectID, bson.M{"$set": updateBody})
+	// Keep the current state of the student for the audit log
+	before, err := studentHandler.Students.Get(r.Context(), objectID)
 	if err != nil {
-		errorHandling.ThrowError(w, http.StatusInternalServerError, "Failed to update student", &err)
+		throwStorageError(w, r, err, "Failed to retrieve document")
 		return
 	}
-	if updateResult.MatchedCount == 0 {
-		errorHandling.ThrowError(w, http.StatusNotFound, "No record found with the provided ID", nil)
+	if before.DeletedAt != nil {
+		errorHandling.ThrowError(w, r, errorHandling.StudentArchived, "Student is archived, restore it first", nil)
 		return
 	}
 
+	// Update the record with required id
+	err = studentHandler.Students.Update(r.Context(), objectID, updateBody)
+	if errors.Is(err, storage.ErrDuplicate) {
+		errorHandling.ThrowError(w, r, errorHandling.Duplicate, "Student with this phone already exists", nil, errorHandling.Detail{Field: "phone", Message: "phone must be unique"})
+		return
+	}
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to update student")
+		return
+	}
+	after, err := studentHandler.Students.Get(r.Context(), objectID)
+	if err == nil {
+		recordAudit(r, studentHandler.Audit, auditStudent, objectID, models.AuditUpdate, before, after)
+	} else {
+		log.Printf("Failed to read updated student %v for the audit log: %v", objectID.Hex(), err)
+	}
+
 	// Write the response with updated keys
-	response := fmt.Sprintf("Student with id %v fields updated successfully: %v",id, updateKeys)
-    w.WriteHeader(http.StatusOK)
-    w.Write([]byte(response))
+	response := fmt.Sprintf("Student with id %v fields updated successfully: %v", objectID.Hex(), updateKeys)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(response))
 }
 
-
-// DELETE for one student by ID
+// DELETE for one student by ID; the student is archived with deletedAt and can be restored
+// Past classes of the student stay as history with studentDeleted set; future classes follow the delete policy:
+// reject returns 409 with the classes, cascade removes them and keep marks them like the past ones
 func (studentHandler *StudentHandler) DeleteByID(w http.ResponseWriter, r *http.Request) {
 	// Check if the method is Delete; return 405 in case of error
 	if r.Method != http.MethodDelete {
-		errorHandling.ThrowError(w, http.StatusMethodNotAllowed, "Invalid request method. Needs to be Delete", nil)
+		errorHandling.ThrowError(w, r, errorHandling.MethodNotAllowed, "Invalid request method. Needs to be Delete", nil)
 		return
 	}
 
 	// Extract the ObjectId from the URL path
-	id := strings.TrimPrefix(r.URL.Path, "/students/")
-	// Convert the string ID to a MongoDB ObjectId type
-	objectID, err := primitive.ObjectIDFromHex(id)
+	objectID, ok := parseObjectId(w, r, "id")
+	if !ok {
+		return
+	}
+
+	// Keep the student and the changed schedules for the audit log
+	var before, after *models.Student
+	var schedulesBefore, schedulesAfter []models.Schedule
+	var futureClasses []errorHandling.Detail
+	// Removed classes of every changed schedule
+	var removed []models.Schedule
+
+	// Archive the student and handle the classes of the student in one transaction
+	err := studentHandler.Transactions.WithTransaction(r.Context(), func(ctx context.Context) error {
+		var err error
+		before, err = studentHandler.Students.Get(ctx, objectID)
+		if err != nil {
+			return err
+		}
+		if before.DeletedAt != nil {
+			return errStudentArchived
+		}
+		schedulesBefore, err = studentHandler.Schedules.ListByStudent(ctx, objectID)
+		if err != nil {
+			return err
+		}
+
+		// Classes from today on are future classes, unless their attendance is already marked
+		today := dayStart(time.Now())
+		futureClasses, schedulesAfter, removed = nil, nil, nil
+		for _, schedule := range schedulesBefore {
+			future := !schedule.Date.Time().Before(today)
+			changed := schedule
+			changed.Classes = []models.Class{}
+			cancelled := schedule
+			cancelled.Classes = nil
+			for _, class := range schedule.Classes {
+				if class.StudentId == objectID && future && class.Attendence == nil {
+					futureClasses = append(futureClasses, errorHandling.Detail{Field: "classes", Message: fmt.Sprintf("class on %v at %v", schedule.Date.Time().UTC().Format(time.DateOnly), class.Time)})
+					if studentHandler.DeletePolicy == config.CascadeStudentDelete {
+						cancelled.Classes = append(cancelled.Classes, class)
+						continue
+					}
+				}
+				if class.StudentId == objectID {
+					class.StudentDeleted = true
+				}
+				changed.Classes = append(changed.Classes, class)
+			}
+			schedulesAfter = append(schedulesAfter, changed)
+			if len(cancelled.Classes) > 0 {
+				removed = append(removed, cancelled)
+			}
+		}
+		if len(futureClasses) > 0 && studentHandler.DeletePolicy == config.RejectStudentDelete {
+			return errStudentHasClasses
+		}
+
+		err = studentHandler.updateSchedules(ctx, schedulesAfter)
+		if err != nil {
+			return err
+		}
+		err = studentHandler.Students.Update(ctx, objectID, map[string]interface{}{"deletedAt": time.Now().UTC()})
+		if err != nil {
+			return err
+		}
+		after, err = studentHandler.Students.Get(ctx, objectID)
+		return err
+	})
+	if errors.Is(err, errStudentArchived) {
+		errorHandling.ThrowError(w, r, errorHandling.StudentArchived, "Student is already archived", nil)
+		return
+	}
+	if errors.Is(err, errStudentHasClasses) {
+		errorHandling.ThrowError(w, r, errorHandling.StudentHasClasses, "Student has future classes, remove them before deleting the student", nil, futureClasses...)
+		return
+	}
 	if err != nil {
-		errorHandling.ThrowError(w, http.StatusBadRequest, "Invalid ObjectId format", nil)
+		throwStorageError(w, r, err, "Failed to archive student")
 		return
 	}
+	studentHandler.auditSchedules(r, schedulesBefore, schedulesAfter)
+	for index := range removed {
+		recordCancellations(r, studentHandler.Cancellations, &removed[index], removed[index].Classes)
+	}
+	recordAudit(r, studentHandler.Audit, auditStudent, objectID, models.AuditUpdate, before, after)
+
+	// Write the response with archived student id
+	response := fmt.Sprintf("Archived student by mentioned id: %v", objectID.Hex())
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(response))
+}
 
-	// Connect to DB
-	db := db.DbConnect()
-	// Disconnect from the DB
-	defer db.DbDisconnect()
-	// Define collection
-	collection := db.Client.Database("artschool-admin").Collection("students")
+// POST for restoring an archived student; the studentDeleted marks are removed from the classes left
+func (studentHandler *StudentHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	objectID, ok := parseObjectId(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var before, after *models.Student
+	var schedulesBefore, schedulesAfter []models.Schedule
+
+	err := studentHandler.Transactions.WithTransaction(r.Context(), func(ctx context.Context) error {
+		var err error
+		before, err = studentHandler.Students.Get(ctx, objectID)
+		if err != nil {
+			return err
+		}
+		if before.DeletedAt == nil {
+			return errStudentNotArchived
+		}
+		schedulesBefore, err = studentHandler.Schedules.ListByStudent(ctx, objectID)
+		if err != nil {
+			return err
+		}
 
-	// Delete record with mentioned id
-	deleteResult, err := collection.DeleteOne(nil, bson.M{"_id": objectID})
+		schedulesAfter = nil
+		for _, schedule := range schedulesBefore {
+			changed := schedule
+			changed.Classes = slices.Clone(schedule.Classes)
+			for index := range changed.Classes {
+				if changed.Classes[index].StudentId == objectID {
+					changed.Classes[index].StudentDeleted = false
+				}
+			}
+			schedulesAfter = append(schedulesAfter, changed)
+		}
+
+		err = studentHandler.updateSchedules(ctx, schedulesAfter)
+		if err != nil {
+			return err
+		}
+		err = studentHandler.Students.Update(ctx, objectID, map[string]interface{}{"deletedAt": nil})
+		if err != nil {
+			return err
+		}
+		after, err = studentHandler.Students.Get(ctx, objectID)
+		return err
+	})
+	if errors.Is(err, errStudentNotArchived) {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Student is not archived", nil, errorHandling.Detail{Field: "deletedAt", Message: "only archived students can be restored"})
+		return
+	}
 	if err != nil {
-		errorHandling.ThrowError(w, http.StatusInternalServerError, "Failed to delete schedule", &err)
+		throwStorageError(w, r, err, "Failed to restore student")
 		return
-	} 
-	if deleteResult.DeletedCount == 0 {
-		errorHandling.ThrowError(w, http.StatusInternalServerError, fmt.Sprintf("No student found with the provided ID: %v", id), nil)
+	}
+	studentHandler.auditSchedules(r, schedulesBefore, schedulesAfter)
+	recordAudit(r, studentHandler.Audit, auditStudent, objectID, models.AuditUpdate, before, after)
+
+	writeJSON(w, http.StatusOK, after)
+}
+
+// POST for deleting an archived student for good; classes, packs and payments of the student stay as history
+func (studentHandler *StudentHandler) Purge(w http.ResponseWriter, r *http.Request) {
+	objectID, ok := parseObjectId(w, r, "id")
+	if !ok {
 		return
 	}
 
-	// Write the response with deleted student id
-	response := fmt.Sprintf("Deleted student by mentioned id: %v", id)
-	w.WriteHeader(http.StatusOK)
-    w.Write([]byte(response))
+	// Keep the deleted student for the audit log
+	before, err := studentHandler.Students.Get(r.Context(), objectID)
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to retrieve document")
+		return
+	}
+	if before.DeletedAt == nil {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Student is not archived", nil, errorHandling.Detail{Field: "deletedAt", Message: "archive the student with DELETE /students/{id} first"})
+		return
+	}
+
+	err = studentHandler.Students.Delete(r.Context(), objectID)
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to purge student")
+		return
+	}
+	recordAudit(r, studentHandler.Audit, auditStudent, objectID, models.AuditDelete, before, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Save the changed schedules of the student
+func (studentHandler *StudentHandler) updateSchedules(ctx context.Context, schedules []models.Schedule) error {
+	for index := range schedules {
+		err := studentHandler.Schedules.Update(ctx, &schedules[index])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Log the changed schedules of the student; before and after have the same order
+func (studentHandler *StudentHandler) auditSchedules(r *http.Request, before []models.Schedule, after []models.Schedule) {
+	for index := range after {
+		recordAudit(r, studentHandler.Audit, auditSchedule, after[index].Id, models.AuditUpdate, &before[index], &after[index])
+	}
 }
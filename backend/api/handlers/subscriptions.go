@@ -0,0 +1,304 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+	"github.com/DanVerh/artschool-admin/backend/api/storage"
+)
+
+// Create struct (class) for SubscriptionHandler to sell subscription packs.
+// The subscription field of the student is the number of classes left in the active pack:
+// the oldest pack that is not used up and not expired
+type SubscriptionHandler struct {
+	Students      storage.StudentRepository
+	Subscriptions storage.SubscriptionRepository
+	Audit         storage.AuditRepository
+	Transactions  storage.Transactor
+	// How long a pack can be used when the request does not set expiresAt
+	Validity time.Duration
+}
+
+// Body of the sale and renewal requests; renewal takes size and price from the renewed pack when they are not set
+type subscriptionRequest struct {
+	Size         *int       `json:"size"`
+	Price        *int64     `json:"price"`
+	PurchaseDate *time.Time `json:"purchaseDate"`
+	ExpiresAt    *time.Time `json:"expiresAt"`
+}
+
+// POST for selling a new pack to the student
+func (subscriptionHandler *SubscriptionHandler) Create(w http.ResponseWriter, r *http.Request) {
+	studentId, ok := parseObjectId(w, r, "id")
+	if !ok {
+		return
+	}
+
+	request := subscriptionRequest{}
+	jsonDecoder := json.NewDecoder(r.Body)
+	err := jsonDecoder.Decode(&request)
+	if err != nil {
+		errorHandling.ThrowError(w, r, errorHandling.InvalidJSON, "Invalid JSON", nil)
+		return
+	}
+
+	var details []errorHandling.Detail
+	if request.Size == nil {
+		details = append(details, errorHandling.Detail{Field: "size", Message: "size is required"})
+	}
+	if request.Price == nil {
+		details = append(details, errorHandling.Detail{Field: "price", Message: "price is required"})
+	}
+	if len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid subscription fields", nil, details...)
+		return
+	}
+
+	subscriptionHandler.sell(w, r, studentId, request, nil)
+}
+
+// POST for renewing a pack: sells a new pack of the same size
+func (subscriptionHandler *SubscriptionHandler) Renew(w http.ResponseWriter, r *http.Request) {
+	studentId, ok := parseObjectId(w, r, "id")
+	if !ok {
+		return
+	}
+	subscriptionId, ok := parseObjectId(w, r, "subscriptionId")
+	if !ok {
+		return
+	}
+
+	// The body is optional
+	request := subscriptionRequest{}
+	jsonDecoder := json.NewDecoder(r.Body)
+	err := jsonDecoder.Decode(&request)
+	if err != nil && !errors.Is(err, io.EOF) {
+		errorHandling.ThrowError(w, r, errorHandling.InvalidJSON, "Invalid JSON", nil)
+		return
+	}
+
+	renewed, err := subscriptionHandler.Subscriptions.Get(r.Context(), subscriptionId)
+	if err == nil && renewed.StudentId != studentId {
+		err = storage.ErrNotFound
+	}
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to retrieve subscription")
+		return
+	}
+	if request.Size == nil {
+		request.Size = &renewed.Size
+	}
+	if request.Price == nil {
+		request.Price = &renewed.Price
+	}
+
+	subscriptionHandler.sell(w, r, studentId, request, &renewed.Id)
+}
+
+// Save the new pack and update the subscription of the student in one transaction
+func (subscriptionHandler *SubscriptionHandler) sell(w http.ResponseWriter, r *http.Request, studentId primitive.ObjectID, request subscriptionRequest, renewedFrom *primitive.ObjectID) {
+	subscription := &models.Subscription{
+		Id:           primitive.NewObjectID(),
+		StudentId:    studentId,
+		Size:         *request.Size,
+		Price:        *request.Price,
+		PurchaseDate: time.Now().UTC(),
+		RenewedFrom:  renewedFrom,
+	}
+	if request.PurchaseDate != nil {
+		subscription.PurchaseDate = request.PurchaseDate.UTC()
+	}
+	subscription.ExpiresAt = subscription.PurchaseDate.Add(subscriptionHandler.Validity)
+	if request.ExpiresAt != nil {
+		subscription.ExpiresAt = request.ExpiresAt.UTC()
+	}
+	if details := validateSubscription(subscription); len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid subscription fields", nil, details...)
+		return
+	}
+
+	var before, after *models.Student
+	err := subscriptionHandler.Transactions.WithTransaction(r.Context(), func(ctx context.Context) error {
+		student, err := subscriptionHandler.Students.Get(ctx, studentId)
+		if err != nil {
+			return err
+		}
+		if student.DeletedAt != nil {
+			return errStudentArchived
+		}
+		err = subscriptionHandler.Subscriptions.Create(ctx, subscription)
+		if err != nil {
+			return err
+		}
+
+		before, after, err = syncSubscription(ctx, subscriptionHandler.Students, subscriptionHandler.Subscriptions, studentId)
+		return err
+	})
+	if errors.Is(err, errStudentArchived) {
+		errorHandling.ThrowError(w, r, errorHandling.StudentArchived, "Student is archived, restore it first", nil)
+		return
+	}
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to save the subscription")
+		return
+	}
+
+	if before.Subscription != after.Subscription {
+		recordAudit(r, subscriptionHandler.Audit, auditStudent, studentId, models.AuditUpdate, before, after)
+	}
+
+	writeJSON(w, http.StatusCreated, subscription)
+}
+
+// GET for all packs of the student, ordered by purchase date
+func (subscriptionHandler *SubscriptionHandler) List(w http.ResponseWriter, r *http.Request) {
+	studentId, ok := parseObjectId(w, r, "id")
+	if !ok {
+		return
+	}
+
+	_, err := subscriptionHandler.Students.Get(r.Context(), studentId)
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to retrieve document")
+		return
+	}
+
+	subscriptions, err := subscriptionHandler.Subscriptions.ListByStudent(r.Context(), studentId)
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve subscriptions from the database", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, subscriptions)
+}
+
+// Return the oldest pack usable on date, nil if there is none
+func activeSubscription(subscriptions []models.Subscription, date time.Time) *models.Subscription {
+	for index := range subscriptions {
+		if subscriptions[index].Usable(date) {
+			return &subscriptions[index]
+		}
+	}
+
+	return nil
+}
+
+// Set the subscription of the student to the classes left in the active pack.
+// Returns the student before and after; they are the same value when nothing changed
+func syncSubscription(ctx context.Context, students storage.StudentRepository, subscriptions storage.SubscriptionRepository, studentId primitive.ObjectID) (*models.Student, *models.Student, error) {
+	student, err := students.Get(ctx, studentId)
+	if err != nil {
+		return nil, nil, err
+	}
+	packs, err := subscriptions.ListByStudent(ctx, studentId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var value interface{}
+	var left *int
+	if active := activeSubscription(packs, time.Now()); active != nil {
+		remaining := active.Remaining()
+		value, left = int32(remaining), &remaining
+	}
+	if (left == nil && student.Subscription == nil) || (left != nil && student.Subscription != nil && *left == *student.Subscription) {
+		return student, student, nil
+	}
+
+	err = students.Update(ctx, studentId, map[string]interface{}{"subscription": value})
+	if err != nil {
+		return nil, nil, err
+	}
+	updated, err := students.Get(ctx, studentId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return student, updated, nil
+}
+
+// Packs that expired are looked up this often
+const expiryCheckInterval = 5 * time.Minute
+
+// SubscriptionExpiry clears the subscription of the students whose active pack expired.
+// Sales and visits keep the field up to date, nothing else changes it when a pack runs out of time
+type SubscriptionExpiry struct {
+	Students      storage.StudentRepository
+	Subscriptions storage.SubscriptionRepository
+	Transactions  storage.Transactor
+	Now           func() time.Time
+}
+
+// Start syncs the students of all packs that expired so far, then of the packs that expired
+// since the previous check, every expiryCheckInterval until ctx is cancelled
+func (expiry *SubscriptionExpiry) Start(ctx context.Context) {
+	ticker := time.NewTicker(expiryCheckInterval)
+	defer ticker.Stop()
+
+	var from time.Time
+	for {
+		now := expiry.Now()
+		synced, err := expiry.Run(ctx, from, now)
+		if err != nil {
+			// The same packs are checked again on the next run
+			log.Printf("Failed to sync the subscriptions of expired packs: %v", err)
+		} else {
+			if synced > 0 {
+				log.Printf("Cleared the subscription of %v students with an expired pack", synced)
+			}
+			from = now
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run syncs the subscription of the students of the packs expiring from from, included, to to, excluded.
+// Returns the number of students whose subscription changed
+func (expiry *SubscriptionExpiry) Run(ctx context.Context, from time.Time, to time.Time) (int, error) {
+	packs, err := expiry.Subscriptions.ListExpiring(ctx, from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	synced := 0
+	seen := map[primitive.ObjectID]bool{}
+	for _, pack := range packs {
+		if seen[pack.StudentId] {
+			continue
+		}
+		seen[pack.StudentId] = true
+
+		var changed bool
+		err := expiry.Transactions.WithTransaction(ctx, func(ctx context.Context) error {
+			before, after, err := syncSubscription(ctx, expiry.Students, expiry.Subscriptions, pack.StudentId)
+			changed = before != after
+			return err
+		})
+		// The packs of deleted students stay behind
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return synced, err
+		}
+		if changed {
+			synced++
+		}
+	}
+
+	return synced, nil
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+	"github.com/DanVerh/artschool-admin/backend/api/storage"
+)
+
+// Create struct (class) for TeacherHandler to handle requests
+// Teachers are never deleted, they are deactivated with "active": false
+type TeacherHandler struct {
+	Teachers storage.TeacherRepository
+	Audit    storage.AuditRepository
+}
+
+// POST for teacher creation; new teachers are active unless the body says otherwise
+func (teacherHandler *TeacherHandler) Create(w http.ResponseWriter, r *http.Request) {
+	teacher := &models.Teacher{Active: true}
+
+	jsonDecoder := json.NewDecoder(r.Body)
+	jsonDecoder.DisallowUnknownFields()
+	err := jsonDecoder.Decode(teacher)
+	if err != nil {
+		errorHandling.ThrowError(w, r, errorHandling.InvalidJSON, "Invalid JSON", nil)
+		return
+	}
+	if details := validateTeacher(teacher); len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid teacher fields", nil, details...)
+		return
+	}
+
+	teacher.Id = primitive.NewObjectID()
+	err = teacherHandler.Teachers.Create(r.Context(), teacher)
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to insert the teacher into the database", err)
+		return
+	}
+
+	log.Printf("Created teacher %v", teacher.Fullname)
+	recordAudit(r, teacherHandler.Audit, auditTeacher, teacher.Id, models.AuditCreate, nil, teacher)
+
+	writeJSON(w, http.StatusCreated, teacher)
+}
+
+// GET for teachers list
+// Filters: active (true or false) and classType
+func (teacherHandler *TeacherHandler) List(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	query := models.TeacherQuery{ClassType: values.Get("classType")}
+	if active := values.Get("active"); active != "" {
+		parsed, err := strconv.ParseBool(active)
+		if err != nil {
+			errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid teacher filters", nil, errorHandling.Detail{Field: "active", Message: "active must be true or false"})
+			return
+		}
+		query.Active = &parsed
+	}
+
+	teachers, err := teacherHandler.Teachers.List(r.Context(), query)
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve teachers from the database", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, teachers)
+}
+
+// GET for one teacher by ID
+func (teacherHandler *TeacherHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	objectID, ok := parseObjectId(w, r, "id")
+	if !ok {
+		return
+	}
+
+	teacher, err := teacherHandler.Teachers.Get(r.Context(), objectID)
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to retrieve document")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, teacher)
+}
+
+// PUT for teacher update; fields missing in the body keep their values
+func (teacherHandler *TeacherHandler) UpdateByID(w http.ResponseWriter, r *http.Request) {
+	objectID, ok := parseObjectId(w, r, "id")
+	if !ok {
+		return
+	}
+
+	before, err := teacherHandler.Teachers.Get(r.Context(), objectID)
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to retrieve document")
+		return
+	}
+
+	// Decode the body on top of the current teacher
+	teacher := *before
+	jsonDecoder := json.NewDecoder(r.Body)
+	jsonDecoder.DisallowUnknownFields()
+	err = jsonDecoder.Decode(&teacher)
+	if err != nil {
+		errorHandling.ThrowError(w, r, errorHandling.InvalidJSON, "Invalid JSON", nil)
+		return
+	}
+	teacher.Id = objectID
+	if details := validateTeacher(&teacher); len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid teacher fields", nil, details...)
+		return
+	}
+
+	err = teacherHandler.Teachers.Update(r.Context(), &teacher)
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to update teacher")
+		return
+	}
+	recordAudit(r, teacherHandler.Audit, auditTeacher, objectID, models.AuditUpdate, before, &teacher)
+
+	writeJSON(w, http.StatusOK, teacher)
+}
@@ -0,0 +1,414 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+	"github.com/DanVerh/artschool-admin/backend/api/storage"
+)
+
+// Longest range of days generated by one request
+const maxGenerateDays = 366
+
+// Weekdays of the templates by name
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// Create struct (class) for TemplateHandler to handle weekly templates and generate schedules from them
+type TemplateHandler struct {
+	Templates     storage.TemplateRepository
+	Holidays      storage.HolidayRepository
+	Schedules     storage.ScheduleRepository
+	Students      storage.StudentRepository
+	Subscriptions storage.SubscriptionRepository
+	Teachers      storage.TeacherRepository
+	Rooms         storage.RoomRepository
+	Audit         storage.AuditRepository
+}
+
+// POST for template creation
+func (templateHandler *TemplateHandler) Create(w http.ResponseWriter, r *http.Request) {
+	template := &models.Template{}
+
+	jsonDecoder := json.NewDecoder(r.Body)
+	jsonDecoder.DisallowUnknownFields()
+	err := jsonDecoder.Decode(template)
+	if err != nil {
+		errorHandling.ThrowError(w, r, errorHandling.InvalidJSON, "Invalid JSON", nil)
+		return
+	}
+
+	template.StartDate = calendarDay(template.StartDate)
+	if template.EndDate != nil {
+		endDate := calendarDay(*template.EndDate)
+		template.EndDate = &endDate
+	}
+	details := validateTemplate(template)
+	if len(details) == 0 {
+		// The student, teacher and room must exist
+		class := template.Class()
+		details, err = checkReferences(r.Context(), templateHandler.Students, templateHandler.Teachers, templateHandler.Rooms, &class, "")
+		if err != nil {
+			errorHandling.ThrowDbError(w, r, "Failed to retrieve student, teacher or room", err)
+			return
+		}
+	}
+	if len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid template fields", nil, details...)
+		return
+	}
+
+	template.Id = primitive.NewObjectID()
+	err = templateHandler.Templates.Create(r.Context(), template)
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to insert the template into the database", err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, template)
+}
+
+// GET for templates list; ?studentId= returns the templates of one student
+func (templateHandler *TemplateHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := models.TemplateQuery{}
+	if studentId := r.URL.Query().Get("studentId"); studentId != "" {
+		objectID, err := primitive.ObjectIDFromHex(studentId)
+		if err != nil {
+			errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid template filters", nil, errorHandling.Detail{Field: "studentId", Message: "must be a 24 characters hex ObjectId"})
+			return
+		}
+		query.StudentId = &objectID
+	}
+
+	templates, err := templateHandler.Templates.List(r.Context(), query)
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve templates from the database", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, templates)
+}
+
+// GET for one template by ID
+func (templateHandler *TemplateHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	objectID, ok := parseObjectId(w, r, "id")
+	if !ok {
+		return
+	}
+
+	template, err := templateHandler.Templates.Get(r.Context(), objectID)
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to retrieve document")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, template)
+}
+
+// DELETE for template removal; classes already generated from it stay in the schedule
+func (templateHandler *TemplateHandler) DeleteByID(w http.ResponseWriter, r *http.Request) {
+	objectID, ok := parseObjectId(w, r, "id")
+	if !ok {
+		return
+	}
+
+	err := templateHandler.Templates.Delete(r.Context(), objectID)
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to delete template")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Body of the generate request; dates are YYYY-MM-DD, both included
+type generateRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Class of a template that was not booked
+type skippedClass struct {
+	Date       string             `json:"date"`
+	TemplateId primitive.ObjectID `json:"templateId"`
+	StudentId  primitive.ObjectID `json:"studentId"`
+	Reason     string             `json:"reason"`
+}
+
+// Response of the generate request
+type generateResponse struct {
+	// Dates of the created and changed schedules
+	Created []string `json:"created"`
+	Updated []string `json:"updated"`
+	// Number of booked classes
+	Booked   int            `json:"booked"`
+	Holidays []string       `json:"holidays"`
+	Skipped  []skippedClass `json:"skipped"`
+}
+
+// Classes left and booked of one student while generating
+type studentBookings struct {
+	found    bool
+	packs    []models.Subscription
+	unmarked int
+}
+
+// POST for generating the schedules of a date range from the templates.
+// Classes are added to the day's schedule, which is created when the day has none. A student who already has
+// a class on the day is not booked again, so running the generator twice for the same range changes nothing.
+// Holidays are skipped, and classes failing the teacher, room or conflict checks are listed as skipped
+func (templateHandler *TemplateHandler) Generate(w http.ResponseWriter, r *http.Request) {
+	request := generateRequest{}
+	jsonDecoder := json.NewDecoder(r.Body)
+	err := jsonDecoder.Decode(&request)
+	if err != nil {
+		errorHandling.ThrowError(w, r, errorHandling.InvalidJSON, "Invalid JSON", nil)
+		return
+	}
+
+	var details []errorHandling.Detail
+	from, err := time.Parse(time.DateOnly, request.From)
+	if err != nil {
+		details = append(details, errorHandling.Detail{Field: "from", Message: "from must be a YYYY-MM-DD date"})
+	}
+	to, err := time.Parse(time.DateOnly, request.To)
+	if err != nil {
+		details = append(details, errorHandling.Detail{Field: "to", Message: "to must be a YYYY-MM-DD date"})
+	}
+	if len(details) == 0 && (to.Before(from) || to.Sub(from) >= maxGenerateDays*24*time.Hour) {
+		details = append(details, errorHandling.Detail{Field: "to", Message: fmt.Sprintf("to must be from the from date to %v days after it", maxGenerateDays-1)})
+	}
+	if len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid generate range", nil, details...)
+		return
+	}
+
+	templates, err := templateHandler.Templates.List(r.Context(), models.TemplateQuery{})
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve templates from the database", err)
+		return
+	}
+	holidays, err := templateHandler.Holidays.List(r.Context(), from, to)
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve holidays from the database", err)
+		return
+	}
+	closed := map[string]bool{}
+	for _, holiday := range holidays {
+		closed[holiday.Date.Format(time.DateOnly)] = true
+	}
+
+	response := generateResponse{Created: []string{}, Updated: []string{}, Holidays: []string{}, Skipped: []skippedClass{}}
+	bookings := map[primitive.ObjectID]*studentBookings{}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if closed[day.Format(time.DateOnly)] {
+			response.Holidays = append(response.Holidays, day.Format(time.DateOnly))
+			continue
+		}
+
+		var dayTemplates []models.Template
+		for _, template := range templates {
+			if weekdays[template.Weekday] == day.Weekday() && !day.Before(template.StartDate) && (template.EndDate == nil || !day.After(*template.EndDate)) {
+				dayTemplates = append(dayTemplates, template)
+			}
+		}
+		if len(dayTemplates) == 0 {
+			continue
+		}
+
+		// A schedule created or deleted for the same day by another request is found on the second try
+		var before, after *models.Schedule
+		var booked []primitive.ObjectID
+		var skipped []skippedClass
+		for range 2 {
+			before, after, booked, skipped, err = templateHandler.generateDay(r.Context(), day, dayTemplates, from, bookings)
+			if !errors.Is(err, storage.ErrDuplicate) && !errors.Is(err, storage.ErrNotFound) {
+				break
+			}
+		}
+		if err != nil {
+			throwStorageError(w, r, err, "Failed to generate the schedule of "+day.Format(time.DateOnly))
+			return
+		}
+
+		response.Skipped = append(response.Skipped, skipped...)
+		if len(booked) == 0 {
+			continue
+		}
+		for _, studentId := range booked {
+			bookings[studentId].unmarked++
+		}
+		response.Booked += len(booked)
+		if before == nil {
+			response.Created = append(response.Created, day.Format(time.DateOnly))
+			recordAudit(r, templateHandler.Audit, auditSchedule, after.Id, models.AuditCreate, nil, after)
+		} else {
+			response.Updated = append(response.Updated, day.Format(time.DateOnly))
+			recordAudit(r, templateHandler.Audit, auditSchedule, after.Id, models.AuditUpdate, before, after)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// Book the classes of the templates on one day and save the schedule.
+// Returns the schedule before (nil when it was created) and after, and the booked students
+func (templateHandler *TemplateHandler) generateDay(ctx context.Context, day time.Time, templates []models.Template, from time.Time, bookings map[primitive.ObjectID]*studentBookings) (*models.Schedule, *models.Schedule, []primitive.ObjectID, []skippedClass, error) {
+	var before, schedule *models.Schedule
+	existing, err := templateHandler.Schedules.List(ctx, models.ScheduleQuery{From: day, To: day.AddDate(0, 0, 1)})
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if len(existing) == 0 {
+		schedule = &models.Schedule{Id: primitive.NewObjectID(), Date: primitive.NewDateTimeFromTime(day), Classes: []models.Class{}}
+	} else {
+		schedule = &existing[0]
+		copied := existing[0]
+		copied.Classes = slices.Clone(schedule.Classes)
+		before = &copied
+	}
+
+	var booked []primitive.ObjectID
+	var skipped []skippedClass
+	skip := func(template models.Template, reasons ...string) {
+		skipped = append(skipped, skippedClass{Date: day.Format(time.DateOnly), TemplateId: template.Id, StudentId: template.StudentId, Reason: strings.Join(reasons, "; ")})
+	}
+
+	for _, template := range templates {
+		if slices.ContainsFunc(schedule.Classes, func(class models.Class) bool { return class.StudentId == template.StudentId }) {
+			continue
+		}
+
+		student, err := templateHandler.bookings(ctx, template.StudentId, from, bookings)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		if !student.found {
+			skip(template, "student not found")
+			continue
+		}
+		if template.UntilSubscriptionEnds {
+			left := 0
+			for _, pack := range student.packs {
+				if pack.Usable(day) {
+					left += pack.Remaining()
+				}
+			}
+			if student.unmarked >= left {
+				skip(template, "all classes left in the subscription are booked")
+				continue
+			}
+		}
+
+		class := template.Class()
+		class.Id = primitive.NewObjectID()
+		details, err := checkReferences(ctx, templateHandler.Students, templateHandler.Teachers, templateHandler.Rooms, &class, "")
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		if len(details) == 0 {
+			schedule.Classes = append(schedule.Classes, class)
+			details, err = conflictDetails(ctx, templateHandler.Rooms, schedule.Classes, len(schedule.Classes)-1)
+			if err != nil {
+				return nil, nil, nil, nil, err
+			}
+			if len(details) > 0 {
+				schedule.Classes = schedule.Classes[:len(schedule.Classes)-1]
+			}
+		}
+		if len(details) > 0 {
+			var reasons []string
+			for _, detail := range details {
+				reasons = append(reasons, detail.Message)
+			}
+			skip(template, reasons...)
+			continue
+		}
+		booked = append(booked, template.StudentId)
+	}
+
+	if len(booked) == 0 {
+		return before, schedule, nil, skipped, nil
+	}
+	if before == nil {
+		err = templateHandler.Schedules.Create(ctx, schedule)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		return before, schedule, booked, skipped, nil
+	}
+
+	// Booked classes are added one by one, so attendance and class changes saved meanwhile on the day are kept
+	for _, class := range schedule.Classes[len(before.Classes):] {
+		err = templateHandler.Schedules.AddClass(ctx, schedule.Id, &class)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+	}
+	after, err := templateHandler.Schedules.Get(ctx, schedule.Id)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	return before, after, booked, skipped, nil
+}
+
+// Packs and unmarked classes of the student from the first generated day on, loaded once per request
+func (templateHandler *TemplateHandler) bookings(ctx context.Context, studentId primitive.ObjectID, from time.Time, bookings map[primitive.ObjectID]*studentBookings) (*studentBookings, error) {
+	if student, found := bookings[studentId]; found {
+		return student, nil
+	}
+
+	student := &studentBookings{}
+	_, err := templateHandler.Students.Get(ctx, studentId)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		student.found = true
+		student.packs, err = templateHandler.Subscriptions.ListByStudent(ctx, studentId)
+		if err != nil {
+			return nil, err
+		}
+		schedules, err := templateHandler.Schedules.ListByStudent(ctx, studentId)
+		if err != nil {
+			return nil, err
+		}
+		for _, schedule := range schedules {
+			if schedule.Date.Time().Before(from) {
+				continue
+			}
+			for _, class := range schedule.Classes {
+				if class.StudentId == studentId && class.Attendence == nil {
+					student.unmarked++
+				}
+			}
+		}
+	}
+
+	bookings[studentId] = student
+	return student, nil
+}
+
+// Midnight UTC of the day of t
+func dayStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+	"github.com/DanVerh/artschool-admin/backend/api/storage"
+)
+
+// Limits of the timeline page
+const (
+	defaultTimelineLimit = 50
+	maxTimelineLimit     = 500
+)
+
+// Create struct (class) for TimelineHandler to show the history of a student.
+// The timeline is built from the schedules, the subscription packs and the audit log on every request
+type TimelineHandler struct {
+	Students      storage.StudentRepository
+	Schedules     storage.ScheduleRepository
+	Subscriptions storage.SubscriptionRepository
+	Audit         storage.AuditRepository
+}
+
+// GET for the timeline of the student: classes, pack sales and comment changes, oldest first
+// Filters: from and to (both days included) as YYYY-MM-DD or RFC3339; page: limit and offset
+func (timelineHandler *TimelineHandler) Get(w http.ResponseWriter, r *http.Request) {
+	studentId, ok := parseObjectId(w, r, "id")
+	if !ok {
+		return
+	}
+
+	values := r.URL.Query()
+	var details []errorHandling.Detail
+	var from, to time.Time
+	for _, filter := range []struct {
+		name  string
+		value *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		text := values.Get(filter.name)
+		if text == "" {
+			continue
+		}
+		day, err := parseDay(text)
+		if err != nil {
+			details = append(details, errorHandling.Detail{Field: filter.name, Message: "must be an RFC3339 time or a YYYY-MM-DD date"})
+		}
+		*filter.value = day
+	}
+	// The last day is included
+	if !to.IsZero() {
+		to = to.AddDate(0, 0, 1)
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		details = append(details, errorHandling.Detail{Field: "to", Message: "to must not be before from"})
+	}
+	includeArchived, archivedDetails := parseIncludeArchived(r)
+	details = append(details, archivedDetails...)
+	limit, offset, pageDetails := parsePage(r, defaultTimelineLimit, maxTimelineLimit)
+	details = append(details, pageDetails...)
+	if len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid timeline query", nil, details...)
+		return
+	}
+
+	student, err := timelineHandler.Students.Get(r.Context(), studentId)
+	if err == nil && student.DeletedAt != nil && !includeArchived {
+		err = storage.ErrNotFound
+	}
+	if err != nil {
+		throwStorageError(w, r, err, "Failed to retrieve document")
+		return
+	}
+
+	// Keep the events inside the range
+	inRange := func(date time.Time) bool {
+		return (from.IsZero() || !date.Before(from)) && (to.IsZero() || date.Before(to))
+	}
+	entries := []models.TimelineEntry{}
+
+	schedules, err := timelineHandler.Schedules.ListByStudent(r.Context(), studentId)
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve schedules of the student", err)
+		return
+	}
+	for _, schedule := range schedules {
+		for _, class := range schedule.Classes {
+			date := classTime(schedule.Date.Time(), class.Time)
+			if class.StudentId != studentId || !inRange(date) {
+				continue
+			}
+			scheduleId := schedule.Id
+			entries = append(entries, models.TimelineEntry{Type: models.TimelineTypeClass, Date: date, ScheduleId: &scheduleId, Class: &class})
+		}
+	}
+
+	subscriptions, err := timelineHandler.Subscriptions.ListByStudent(r.Context(), studentId)
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve subscriptions of the student", err)
+		return
+	}
+	for _, subscription := range subscriptions {
+		if inRange(subscription.PurchaseDate) {
+			entries = append(entries, models.TimelineEntry{Type: models.TimelineTypeSubscription, Date: subscription.PurchaseDate, Subscription: &subscription})
+		}
+	}
+
+	// Comments are not versioned in the student, their history comes from the audit log
+	auditEntries, err := timelineHandler.Audit.List(r.Context(), models.AuditQuery{Entity: auditStudent, EntityId: studentId, From: from, To: to})
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve audit log of the student", err)
+		return
+	}
+	for _, auditEntry := range auditEntries {
+		for _, change := range auditEntry.Changes {
+			if change.Field != "comments" {
+				continue
+			}
+			comment := &models.TimelineComment{Actor: auditEntry.Actor}
+			if text, ok := change.After.(string); ok {
+				comment.Text = &text
+			}
+			entries = append(entries, models.TimelineEntry{Type: models.TimelineTypeComment, Date: auditEntry.Timestamp, Comment: comment})
+		}
+	}
+
+	slices.SortStableFunc(entries, func(a models.TimelineEntry, b models.TimelineEntry) int {
+		return a.Date.Compare(b.Date)
+	})
+
+	total := int64(len(entries))
+	start := min(offset, len(entries))
+	end := min(start+limit, len(entries))
+	page := models.TimelinePage{
+		Entries: entries[start:end],
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+		Next:    nextPage(r, offset, end-start, total),
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// Time of the class on the schedule date; classes start at full hours
+func classTime(date time.Time, clock string) time.Time {
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil {
+		return date.UTC()
+	}
+
+	return date.UTC().Add(time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute)
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/DanVerh/artschool-admin/backend/api/auth"
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/storage"
+)
+
+// Create struct (class) for UserHandler to manage staff accounts
+type UserHandler struct {
+	Users storage.UserRepository
+}
+
+// Body of the user creation request
+type createUserRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+// POST for user creation
+func (userHandler *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
+	request := createUserRequest{}
+	jsonDecoder := json.NewDecoder(r.Body)
+	err := jsonDecoder.Decode(&request)
+	if err != nil {
+		errorHandling.ThrowError(w, r, errorHandling.InvalidJSON, "Invalid JSON", nil)
+		return
+	}
+
+	// Check fields of the new user
+	var details []errorHandling.Detail
+	if request.Username == "" {
+		details = append(details, errorHandling.Detail{Field: "username", Message: "username is required"})
+	}
+	if len(request.Password) < auth.MinPasswordLength {
+		details = append(details, errorHandling.Detail{Field: "password", Message: fmt.Sprintf("password must have at least %v characters", auth.MinPasswordLength)})
+	} else if len(request.Password) > auth.MaxPasswordLength {
+		details = append(details, errorHandling.Detail{Field: "password", Message: fmt.Sprintf("password must have at most %v bytes", auth.MaxPasswordLength)})
+	}
+	if !auth.IsRole(request.Role) {
+		details = append(details, errorHandling.Detail{Field: "role", Message: "role must be one of " + strings.Join(auth.Roles(), ", ")})
+	}
+	if len(details) > 0 {
+		errorHandling.ThrowError(w, r, errorHandling.ValidationFailed, "Invalid user fields", nil, details...)
+		return
+	}
+
+	user, err := auth.NewUser(request.Username, request.Password, request.Role)
+	if err != nil {
+		errorHandling.ThrowError(w, r, errorHandling.Internal, "Failed to create user", &err)
+		return
+	}
+
+	err = userHandler.Users.Create(r.Context(), user)
+	if errors.Is(err, storage.ErrDuplicate) {
+		errorHandling.ThrowError(w, r, errorHandling.Duplicate, "User with this username already exists", nil, errorHandling.Detail{Field: "username", Message: "username must be unique"})
+		return
+	}
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to insert the user into the database", err)
+		return
+	}
+
+	log.Printf("Created %v user %v", user.Role, user.Username)
+
+	writeJSON(w, http.StatusCreated, user)
+}
+
+// GET for users list
+func (userHandler *UserHandler) List(w http.ResponseWriter, r *http.Request) {
+	users, err := userHandler.Users.List(r.Context())
+	if err != nil {
+		errorHandling.ThrowDbError(w, r, "Failed to retrieve users from the database", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, users)
+}
+
+// GET for the logged in user
+func (userHandler *UserHandler) Me(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, auth.UserFromContext(r.Context()))
+}
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/DanVerh/artschool-admin/backend/api/errorHandling"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+// Validation rules mirror the $jsonSchema of the collections in backend/migration/migrations,
+// so a request fails with a field-level error before the database rejects it
+var (
+	phonePattern     = regexp.MustCompile(`^\+[0-9]{12}$`)
+	emailPattern     = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	classTimePattern = regexp.MustCompile(`^(0[8-9]|1\d|20):00$`)
+	classTypes       = []string{"drawing", "painting", "both"}
+)
+
+const (
+	minSubscription = 1
+	maxSubscription = 8
+)
+
+// Check fields of a new subscription pack
+func validateSubscription(subscription *models.Subscription) []errorHandling.Detail {
+	var details []errorHandling.Detail
+
+	if subscription.Size < minSubscription || subscription.Size > maxSubscription {
+		details = append(details, errorHandling.Detail{Field: "size", Message: fmt.Sprintf("size must be an integer from %v to %v", minSubscription, maxSubscription)})
+	}
+	if subscription.Price < 0 {
+		details = append(details, errorHandling.Detail{Field: "price", Message: "price must not be negative"})
+	}
+	if !subscription.ExpiresAt.After(subscription.PurchaseDate) {
+		details = append(details, errorHandling.Detail{Field: "expiresAt", Message: "expiresAt must be after purchaseDate"})
+	}
+
+	return details
+}
+
+// Check fields of a new student
+func validateStudent(student *models.Student) []errorHandling.Detail {
+	var details []errorHandling.Detail
+
+	if student.Fullname == "" {
+		details = append(details, errorHandling.Detail{Field: "fullname", Message: "fullname is required"})
+	}
+	if student.Phone == "" {
+		details = append(details, errorHandling.Detail{Field: "phone", Message: "phone is required"})
+	} else if !phonePattern.MatchString(student.Phone) {
+		details = append(details, errorHandling.Detail{Field: "phone", Message: "phone must start with + and have 12 digits"})
+	}
+
+	return details
+}
+
+// Check fields of a teacher
+func validateTeacher(teacher *models.Teacher) []errorHandling.Detail {
+	var details []errorHandling.Detail
+
+	if teacher.Fullname == "" {
+		details = append(details, errorHandling.Detail{Field: "fullname", Message: "fullname is required"})
+	}
+	if !phonePattern.MatchString(teacher.Phone) {
+		details = append(details, errorHandling.Detail{Field: "phone", Message: "phone must start with + and have 12 digits"})
+	}
+	if teacher.Email != nil && !emailPattern.MatchString(*teacher.Email) {
+		details = append(details, errorHandling.Detail{Field: "email", Message: "email must be a valid address or null"})
+	}
+	if len(teacher.ClassTypes) == 0 {
+		details = append(details, errorHandling.Detail{Field: "classTypes", Message: "classTypes must have at least one class type"})
+	}
+	for index, classType := range teacher.ClassTypes {
+		if !slices.Contains(classTypes, classType) || slices.Index(teacher.ClassTypes, classType) != index {
+			details = append(details, errorHandling.Detail{Field: fmt.Sprintf("classTypes[%d]", index), Message: "class types must be distinct values of " + strings.Join(classTypes, ", ")})
+		}
+	}
+
+	return details
+}
+
+// Check fields of a room
+func validateRoom(room *models.Room) []errorHandling.Detail {
+	var details []errorHandling.Detail
+
+	if room.Name == "" {
+		details = append(details, errorHandling.Detail{Field: "name", Message: "name is required"})
+	}
+	if room.Capacity < 1 {
+		details = append(details, errorHandling.Detail{Field: "capacity", Message: "capacity must be at least 1"})
+	}
+
+	return details
+}
+
+// Check fields of a weekly template; dates are already moved to midnight UTC
+func validateTemplate(template *models.Template) []errorHandling.Detail {
+	class := template.Class()
+	details := validateClass(&class, "")
+
+	if _, found := weekdays[template.Weekday]; !found {
+		details = append(details, errorHandling.Detail{Field: "weekday", Message: "weekday must be a lowercase English day name like tuesday"})
+	}
+	if template.StartDate.IsZero() {
+		details = append(details, errorHandling.Detail{Field: "startDate", Message: "startDate is required"})
+	}
+	if template.EndDate != nil && template.EndDate.Before(template.StartDate) {
+		details = append(details, errorHandling.Detail{Field: "endDate", Message: "endDate must not be before startDate"})
+	}
+
+	return details
+}
+
+// Check fields of a student update and convert them to the types stored in the database
+func validateStudentUpdate(updateBody map[string]interface{}) []errorHandling.Detail {
+	var details []errorHandling.Detail
+
+	if len(updateBody) == 0 {
+		return append(details, errorHandling.Detail{Field: "", Message: "no student field is updated"})
+	}
+
+	for key, value := range updateBody {
+		switch key {
+		case "fullname":
+			fullname, ok := value.(string)
+			if !ok || fullname == "" {
+				details = append(details, errorHandling.Detail{Field: key, Message: "fullname must be a non-empty string"})
+			}
+		case "phone":
+			phone, ok := value.(string)
+			if !ok || !phonePattern.MatchString(phone) {
+				details = append(details, errorHandling.Detail{Field: key, Message: "phone must start with + and have 12 digits"})
+			}
+		case "subscription":
+			// Derived from the subscription packs
+			details = append(details, errorHandling.Detail{Field: key, Message: "subscription is changed by selling packs with POST /students/{id}/subscriptions"})
+		case "startDate", "lastDate":
+			if value == nil {
+				continue
+			}
+			dateString, ok := value.(string)
+			date, err := time.Parse(time.RFC3339, dateString)
+			if !ok || err != nil {
+				details = append(details, errorHandling.Detail{Field: key, Message: key + " must be an RFC 3339 date or null"})
+				continue
+			}
+			updateBody[key] = date
+		case "comments":
+			if _, ok := value.(string); !ok && value != nil {
+				details = append(details, errorHandling.Detail{Field: key, Message: "comments must be a string or null"})
+			}
+		default:
+			details = append(details, errorHandling.Detail{Field: key, Message: "unknown student field"})
+		}
+	}
+
+	return details
+}
+
+// Explains where attendance is changed
+const attendanceMessage = "attendance is marked with POST /schedule/{id}/classes/{classId}/attendance"
+
+// Check fields of a schedule and all its classes
+func validateSchedule(schedule *models.Schedule) []errorHandling.Detail {
+	var details []errorHandling.Detail
+
+	if schedule.Date == 0 {
+		details = append(details, errorHandling.Detail{Field: "date", Message: "date is required"})
+	}
+	if len(schedule.Classes) == 0 {
+		details = append(details, errorHandling.Detail{Field: "classes", Message: "no classes found for schedule creation"})
+	}
+	for index, class := range schedule.Classes {
+		prefix := fmt.Sprintf("classes[%d].", index)
+		details = append(details, validateClass(&class, prefix)...)
+		// Attendance changes subscriptions, so it is only marked through the attendance endpoint
+		if class.Attendence != nil {
+			details = append(details, errorHandling.Detail{Field: prefix + "attendance", Message: attendanceMessage})
+		}
+	}
+
+	return details
+}
+
+// Check fields of one class; prefix locates the class inside the request body
+func validateClass(class *models.Class, prefix string) []errorHandling.Detail {
+	var details []errorHandling.Detail
+
+	if class.StudentId.IsZero() {
+		details = append(details, errorHandling.Detail{Field: prefix + "studentId", Message: "studentId is required"})
+	}
+	if !classTimePattern.MatchString(class.Time) {
+		details = append(details, errorHandling.Detail{Field: prefix + "time", Message: "time must be a full hour from 08:00 to 20:00"})
+	}
+	if !slices.Contains(classTypes, class.Type) {
+		details = append(details, errorHandling.Detail{Field: prefix + "type", Message: "type must be one of " + strings.Join(classTypes, ", ")})
+	}
+
+	return details
+}
@@ -3,15 +3,36 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/DanVerh/artschool-admin/backend/api/application"
+	"github.com/DanVerh/artschool-admin/backend/api/config"
 )
 
 func main() {
-	app := application.New()
+	// Load settings from config file, env vars and flags
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to load config:", err)
+		os.Exit(1)
+	}
+
+	app, err := application.New(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to create app:", err)
+		os.Exit(1)
+	}
+
+	// Stop the server gracefully on Ctrl+C or when the container is stopped
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	err := app.Start(context.TODO())
+	err = app.Start(ctx)
+	stop()
 	if err != nil {
-		fmt.Println("failed to start app:", err)
+		fmt.Fprintln(os.Stderr, "failed to start app:", err)
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Audit actions
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// Create struct (class) for FieldChange; one changed field of an audited document.
+// Values are in their JSON form, nil when the field did not exist
+type FieldChange struct {
+	Field  string      `json:"field" bson:"field"`
+	Before interface{} `json:"before" bson:"before"`
+	After  interface{} `json:"after" bson:"after"`
+}
+
+// Create struct (class) for AuditEntry; one mutation of a student or a schedule
+type AuditEntry struct {
+	Id        primitive.ObjectID `json:"id" bson:"_id"`
+	Timestamp time.Time          `json:"timestamp" bson:"timestamp"`
+	ActorId   primitive.ObjectID `json:"actorId" bson:"actorId"`
+	Actor     string             `json:"actor" bson:"actor"`
+	Entity    string             `json:"entity" bson:"entity"`
+	EntityId  primitive.ObjectID `json:"entityId" bson:"entityId"`
+	Action    string             `json:"action" bson:"action"`
+	Changes   []FieldChange      `json:"changes" bson:"changes"`
+}
+
+// Create struct (class) for AuditQuery; filters of the audit log, zero values match everything
+type AuditQuery struct {
+	Entity   string
+	EntityId primitive.ObjectID
+	Actor    string
+	From     time.Time
+	To       time.Time
+	Limit    int
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Create struct (class) for Cancellation; a class removed from the schedule.
+// Calendar feeds publish it as a cancelled event, so calendars drop the event instead of keeping it
+type Cancellation struct {
+	// Id of the removed class, the same as the UID of its calendar event
+	Id          primitive.ObjectID `json:"id" bson:"_id"`
+	ScheduleId  primitive.ObjectID `json:"scheduleId" bson:"scheduleId"`
+	Date        primitive.DateTime `json:"date" bson:"date"`
+	Class       Class              `json:"class" bson:"class"`
+	CancelledAt time.Time          `json:"cancelledAt" bson:"cancelledAt"`
+}
+
+// Create struct (class) for CancellationQuery; filters of the cancellations list
+type CancellationQuery struct {
+	StudentId *primitive.ObjectID
+	TeacherId *primitive.ObjectID
+	// First day of the cancelled classes, zero value is not a limit
+	From time.Time
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Scopes of calendar feeds
+const (
+	FeedSchool  = "school"
+	FeedTeacher = "teacher"
+	FeedStudent = "student"
+)
+
+// Create struct (class) for CalendarFeed; a secret link to one calendar feed for calendar apps that can not log in.
+// Only a hash of the token is stored, the token is shown once when the feed is created
+type CalendarFeed struct {
+	Id        primitive.ObjectID `json:"id" bson:"_id"`
+	TokenHash string             `json:"-" bson:"tokenHash"`
+	Scope     string             `json:"scope" bson:"scope"`
+	// Teacher or student of the feed; null for the school feed
+	EntityId *primitive.ObjectID `json:"entityId" bson:"entityId"`
+	// User who created the feed; the feed works while the user can read the schedule
+	CreatedBy primitive.ObjectID `json:"createdBy" bson:"createdBy"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+	// Token and path of the feed, only in the response of the creation
+	Token string `json:"token,omitempty" bson:"-"`
+	Path  string `json:"path,omitempty" bson:"-"`
+}
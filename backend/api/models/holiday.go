@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Create struct (class) for Holiday; a day the school is closed and templates book no classes
+type Holiday struct {
+	Id   primitive.ObjectID `json:"id" bson:"_id"`
+	Date time.Time          `json:"date" bson:"date"`
+	Name string             `json:"name" bson:"name"`
+}
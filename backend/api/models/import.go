@@ -0,0 +1,43 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Actions of the rows of a students import
+const (
+	ImportCreate    = "create"
+	ImportUpdate    = "update"
+	ImportUnchanged = "unchanged"
+	ImportReject    = "reject"
+)
+
+// Create struct (class) for StudentImport; the report of a students import, row by row
+type StudentImport struct {
+	// Nothing is saved in a dry run; the report shows what the import would do
+	DryRun    bool               `json:"dryRun"`
+	Created   int                `json:"created"`
+	Updated   int                `json:"updated"`
+	Unchanged int                `json:"unchanged"`
+	Rejected  int                `json:"rejected"`
+	Rows      []StudentImportRow `json:"rows"`
+}
+
+// Create struct (class) for StudentImportRow; what happens to one row of the file
+type StudentImportRow struct {
+	// Row number in the file; the header is row 1
+	Row      int    `json:"row"`
+	Action   string `json:"action"`
+	Fullname string `json:"fullname"`
+	Phone    string `json:"phone"`
+	// Student created or updated by the row; new students of a dry run have no id yet
+	StudentId *primitive.ObjectID `json:"studentId,omitempty"`
+	// Fields changed on an existing student
+	Fields []string `json:"fields,omitempty"`
+	// Why the row is rejected
+	Errors []ImportError `json:"errors,omitempty"`
+}
+
+// Create struct (class) for ImportError; a problem with one column of a row
+type ImportError struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kinds of notifications
+const (
+	NotificationReminder = "reminder"
+)
+
+// Delivery statuses of notifications
+const (
+	NotificationPending = "pending"
+	NotificationSending = "sending"
+	NotificationSent    = "sent"
+	NotificationFailed  = "failed"
+)
+
+// Create struct (class) for Notification; one message to a student and its delivery status.
+// A class gets at most one notification of each kind, failed ones are tried again on the next runs
+type Notification struct {
+	Id         primitive.ObjectID `json:"id" bson:"_id"`
+	Kind       string             `json:"kind" bson:"kind"`
+	StudentId  primitive.ObjectID `json:"studentId" bson:"studentId"`
+	ScheduleId primitive.ObjectID `json:"scheduleId" bson:"scheduleId"`
+	ClassId    primitive.ObjectID `json:"classId" bson:"classId"`
+	// Channel of the sender, like sms or log
+	Channel string `json:"channel" bson:"channel"`
+	// Address on the channel; the phone of the student
+	To       string `json:"to" bson:"to"`
+	Text     string `json:"text" bson:"text"`
+	Status   string `json:"status" bson:"status"`
+	Attempts int    `json:"attempts" bson:"attempts"`
+	// Error of the last failed attempt
+	Error     string     `json:"error,omitempty" bson:"error,omitempty"`
+	CreatedAt time.Time  `json:"createdAt" bson:"createdAt"`
+	SentAt    *time.Time `json:"sentAt" bson:"sentAt"`
+}
+
+// Create struct (class) for NotificationQuery; filters of the notifications list, zero values match everything
+type NotificationQuery struct {
+	StudentId primitive.ObjectID
+	Status    string
+	Limit     int
+}
+
+// Create struct (class) for ReminderRun; what one run of the reminders did
+type ReminderRun struct {
+	// Day of the classes
+	Date    time.Time `json:"date"`
+	Sent    int       `json:"sent"`
+	Failed  int       `json:"failed"`
+	Skipped int       `json:"skipped"`
+}
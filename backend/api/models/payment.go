@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kinds of ledger entries
+const (
+	PaymentKindPayment    = "payment"
+	PaymentKindRefund     = "refund"
+	PaymentKindCorrection = "correction"
+)
+
+// Create struct (class) for Payment; one entry of the payments ledger.
+// Entries are never changed: a refund or a correction is a new entry pointing to the payment
+type Payment struct {
+	Id     primitive.ObjectID `json:"id" bson:"_id"`
+	Kind   string             `json:"kind" bson:"kind"`
+	Method string             `json:"method" bson:"method"`
+	// Amount in the smallest currency unit; positive for payments, negative for refunds, any sign for corrections
+	Amount   int64  `json:"amount" bson:"amount"`
+	Currency string `json:"currency" bson:"currency"`
+	// Student and subscription pack the money is for, both optional
+	StudentId      *primitive.ObjectID `json:"studentId" bson:"studentId"`
+	SubscriptionId *primitive.ObjectID `json:"subscriptionId" bson:"subscriptionId"`
+	// Payment refunded or corrected by this entry
+	RelatedTo  *primitive.ObjectID `json:"relatedTo" bson:"relatedTo"`
+	Note       *string             `json:"note" bson:"note"`
+	CreatedAt  time.Time           `json:"createdAt" bson:"createdAt"`
+	RecordedBy string              `json:"recordedBy" bson:"recordedBy"`
+}
+
+// Create struct (class) for PaymentQuery; filters of the ledger, zero values match everything
+type PaymentQuery struct {
+	StudentId primitive.ObjectID
+	RelatedTo primitive.ObjectID
+	Kind      string
+	Method    string
+	From      time.Time
+	To        time.Time
+	Limit     int
+}
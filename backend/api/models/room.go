@@ -0,0 +1,13 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Create struct (class) for Room
+// Capacity is the number of students that can have a class in the room at the same time
+type Room struct {
+	Id       primitive.ObjectID `json:"id" bson:"_id"`
+	Name     string             `json:"name" bson:"name"`
+	Capacity int                `json:"capacity" bson:"capacity"`
+}
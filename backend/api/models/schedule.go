@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Create struct (class) for Classes that will be added to Schedule
+// A student can have several classes on one day, so every class has its own id
+type Class struct {
+	Id         primitive.ObjectID `json:"id" bson:"_id"`
+	StudentId  primitive.ObjectID `json:"studentId" bson:"studentId"`
+	Time       string             `json:"time" bson:"time"`
+	Type       string             `json:"type" bson:"type"`
+	Attendence *bool              `json:"attendance" bson:"attendance"`
+	// Teacher running the class; null for classes planned before teachers were added
+	TeacherId *primitive.ObjectID `json:"teacherId" bson:"teacherId"`
+	// Room of the class; null for classes planned before rooms were added
+	RoomId *primitive.ObjectID `json:"roomId" bson:"roomId"`
+	// Subscription pack used by the visit, set while attendance is true
+	SubscriptionId *primitive.ObjectID `json:"subscriptionId,omitempty" bson:"subscriptionId,omitempty"`
+	// Set when the student was deleted; the class stays as history
+	StudentDeleted bool `json:"studentDeleted,omitempty" bson:"studentDeleted,omitempty"`
+}
+
+// Create struct (class) for Schedule
+type Schedule struct {
+	Id      primitive.ObjectID `json:"id" bson:"_id"`
+	Date    primitive.DateTime `bson:"date" json:"date"`
+	Classes []Class            `bson:"classes" json:"classes"`
+}
+
+// Create struct (class) for ScheduleQuery; filters of the schedules list
+type ScheduleQuery struct {
+	TeacherId *primitive.ObjectID
+	// Range of dates; From is included and To is excluded, zero values are not limits
+	From time.Time
+	To   time.Time
+}
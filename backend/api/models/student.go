@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Create struct (class) for Student
+// Subscription is the number of classes left in the active subscription pack, kept by the server
+type Student struct {
+	Id           primitive.ObjectID `json:"id" bson:"_id"`
+	Fullname     string             `json:"fullname" bson:"fullname"`
+	Phone        string             `json:"phone" bson:"phone"`
+	Subscription *int               `json:"subscription" bson:"subscription"`
+	StartDate    *time.Time         `json:"startDate" bson:"startDate"`
+	LastDate     *time.Time         `json:"lastDate" bson:"lastDate"`
+	Comments     *string            `json:"comments" bson:"comments"`
+	// Set when the student is archived; archived students are hidden from lists and can be restored
+	DeletedAt *time.Time `json:"deletedAt,omitempty" bson:"deletedAt,omitempty"`
+}
+
+// Create struct (class) for StudentQuery; filters, sorting and page of the students list
+type StudentQuery struct {
+	// Subscription filters by the subscription count; NullSubscription finds students without a subscription
+	Subscription     *int
+	NullSubscription bool
+	LastDateBefore   time.Time
+	LastDateAfter    time.Time
+	// Case-insensitive text the comments must contain
+	Comments string
+	// Archived students are listed only with IncludeArchived
+	IncludeArchived bool
+	// Field to sort by; empty keeps the order of creation
+	Sort       string
+	Descending bool
+	Limit      int
+	Offset     int
+}
+
+// Create struct (class) for StudentPage; one page of the students list
+type StudentPage struct {
+	Students []Student `json:"students"`
+	Total    int64     `json:"total"`
+	Limit    int       `json:"limit"`
+	Offset   int       `json:"offset"`
+	// Link to the next page, empty on the last page
+	Next string `json:"next,omitempty"`
+}
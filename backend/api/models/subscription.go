@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Create struct (class) for Subscription; one pack of classes bought by a student
+type Subscription struct {
+	Id        primitive.ObjectID `json:"id" bson:"_id"`
+	StudentId primitive.ObjectID `json:"studentId" bson:"studentId"`
+	// Number of classes in the pack
+	Size int `json:"size" bson:"size"`
+	// Price in the smallest currency unit
+	Price        int64     `json:"price" bson:"price"`
+	PurchaseDate time.Time `json:"purchaseDate" bson:"purchaseDate"`
+	ExpiresAt    time.Time `json:"expiresAt" bson:"expiresAt"`
+	// Number of visits that used classes of the pack
+	Consumed int `json:"consumed" bson:"consumed"`
+	// Pack this one renews, nil for a new sale
+	RenewedFrom *primitive.ObjectID `json:"renewedFrom" bson:"renewedFrom"`
+}
+
+// Remaining returns the number of classes left in the pack
+func (subscription *Subscription) Remaining() int {
+	return subscription.Size - subscription.Consumed
+}
+
+// Usable reports whether a class on date can use the pack
+func (subscription *Subscription) Usable(date time.Time) bool {
+	return subscription.Remaining() > 0 && date.Before(subscription.ExpiresAt)
+}
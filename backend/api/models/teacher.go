@@ -0,0 +1,23 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Create struct (class) for Teacher
+// ClassTypes are the types of classes the teacher can be assigned to; inactive teachers keep their past classes
+// but can not get new ones
+type Teacher struct {
+	Id         primitive.ObjectID `json:"id" bson:"_id"`
+	Fullname   string             `json:"fullname" bson:"fullname"`
+	Phone      string             `json:"phone" bson:"phone"`
+	Email      *string            `json:"email" bson:"email"`
+	ClassTypes []string           `json:"classTypes" bson:"classTypes"`
+	Active     bool               `json:"active" bson:"active"`
+}
+
+// Create struct (class) for TeacherQuery; filters of the teachers list
+type TeacherQuery struct {
+	Active    *bool
+	ClassType string
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Create struct (class) for Template; a class the student has every week on the same weekday and time.
+// The generator books the class on every matching day from StartDate to EndDate (both included, null for no end);
+// with UntilSubscriptionEnds it stops booking when the classes left in the student's packs are all booked
+type Template struct {
+	Id                    primitive.ObjectID  `json:"id" bson:"_id"`
+	StudentId             primitive.ObjectID  `json:"studentId" bson:"studentId"`
+	Weekday               string              `json:"weekday" bson:"weekday"`
+	Time                  string              `json:"time" bson:"time"`
+	Type                  string              `json:"type" bson:"type"`
+	TeacherId             *primitive.ObjectID `json:"teacherId" bson:"teacherId"`
+	RoomId                *primitive.ObjectID `json:"roomId" bson:"roomId"`
+	StartDate             time.Time           `json:"startDate" bson:"startDate"`
+	EndDate               *time.Time          `json:"endDate" bson:"endDate"`
+	UntilSubscriptionEnds bool                `json:"untilSubscriptionEnds" bson:"untilSubscriptionEnds"`
+}
+
+// Create struct (class) for TemplateQuery; filters of the templates list
+type TemplateQuery struct {
+	StudentId *primitive.ObjectID
+}
+
+// Class booked by the template
+func (template *Template) Class() Class {
+	return Class{
+		StudentId: template.StudentId,
+		Time:      template.Time,
+		Type:      template.Type,
+		TeacherId: template.TeacherId,
+		RoomId:    template.RoomId,
+	}
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Types of timeline entries
+const (
+	TimelineTypeClass        = "class"
+	TimelineTypeSubscription = "subscription"
+	TimelineTypeComment      = "comment"
+)
+
+// Create struct (class) for TimelineEntry; one event in the history of a student.
+// Only the field of the entry type is set
+type TimelineEntry struct {
+	Type string `json:"type"`
+	// Time of the event; a class happens at its time on the schedule date
+	Date time.Time `json:"date"`
+	// Schedule of the class
+	ScheduleId   *primitive.ObjectID `json:"scheduleId,omitempty"`
+	Class        *Class              `json:"class,omitempty"`
+	Subscription *Subscription       `json:"subscription,omitempty"`
+	Comment      *TimelineComment    `json:"comment,omitempty"`
+}
+
+// Create struct (class) for TimelineComment; new comments of the student, null when they were cleared
+type TimelineComment struct {
+	Text  *string `json:"text"`
+	Actor string  `json:"actor"`
+}
+
+// Create struct (class) for TimelinePage; one page of the timeline
+type TimelinePage struct {
+	Entries []TimelineEntry `json:"entries"`
+	Total   int64           `json:"total"`
+	Limit   int             `json:"limit"`
+	Offset  int             `json:"offset"`
+	// Link to the next page, empty on the last page
+	Next string `json:"next,omitempty"`
+}
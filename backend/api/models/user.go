@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Create struct (class) for User; users are the staff who log in to the admin
+type User struct {
+	Id           primitive.ObjectID `json:"id" bson:"_id"`
+	Username     string             `json:"username" bson:"username"`
+	PasswordHash string             `json:"-" bson:"passwordHash"`
+	Role         string             `json:"role" bson:"role"`
+	CreatedAt    time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+// Create struct (class) for Session of a logged in user
+// Id is the SHA-256 hash of the session token, the token itself is never stored
+type Session struct {
+	Id        string             `json:"-" bson:"_id"`
+	UserId    primitive.ObjectID `json:"userId" bson:"userId"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+	ExpiresAt time.Time          `json:"expiresAt" bson:"expiresAt"`
+}
@@ -0,0 +1,214 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/config"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+	"github.com/DanVerh/artschool-admin/backend/api/storage"
+)
+
+// How often the job looks for classes to remind of
+const checkInterval = 15 * time.Minute
+
+// Failed reminders are tried again on the next runs, up to this many attempts in total
+const maxAttempts = 3
+
+// Create struct (class) for Reminders; the job reminding students of their classes the day before.
+// Every class gets one reminder, recorded in the notifications with its delivery status, and a run
+// claims the notification before sending, so the job can run any number of times and on several
+// servers without sending twice. A server stopped in the middle of sending leaves the notification
+// in sending, and it is not tried again
+type Reminders struct {
+	Schedules     storage.ScheduleRepository
+	Students      storage.StudentRepository
+	Teachers      storage.TeacherRepository
+	Rooms         storage.RoomRepository
+	Notifications storage.NotificationRepository
+	Sender        Sender
+	Template      *template.Template
+	// Time of the day, on the clock of the server, after which the reminders of tomorrow are sent
+	SendAfter time.Duration
+	// Current time; replaced in tests
+	Now func() time.Time
+}
+
+// Start sends the reminders of tomorrow once the send time of the day has passed,
+// checking every checkInterval until ctx is cancelled
+func (reminders *Reminders) Start(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		now := reminders.Now()
+		midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		if now.Sub(midnight) >= reminders.SendAfter {
+			// Schedule dates are midnight UTC of the day
+			tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+			run, err := reminders.Run(ctx, tomorrow)
+			if err != nil {
+				log.Printf("Failed to send reminders of %v: %v", tomorrow.Format(time.DateOnly), err)
+			} else if run.Sent > 0 || run.Failed > 0 {
+				log.Printf("Sent reminders of %v: %v sent, %v failed", tomorrow.Format(time.DateOnly), run.Sent, run.Failed)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run sends the reminders of the classes on the day that were not sent yet.
+// Classes of archived and deleted students are skipped
+func (reminders *Reminders) Run(ctx context.Context, day time.Time) (models.ReminderRun, error) {
+	run := models.ReminderRun{Date: day}
+
+	schedules, err := reminders.Schedules.List(ctx, models.ScheduleQuery{From: day, To: day.AddDate(0, 0, 1)})
+	if err != nil {
+		return run, err
+	}
+
+	for _, schedule := range schedules {
+		for _, class := range schedule.Classes {
+			notification, err := reminders.Notifications.GetByClass(ctx, class.Id, models.NotificationReminder)
+			if err != nil && !errors.Is(err, storage.ErrNotFound) {
+				return run, err
+			}
+			// Already sent, being sent by another run, or given up after the last attempt
+			if notification != nil && (notification.Status == models.NotificationSent || notification.Status == models.NotificationSending || notification.Attempts >= maxAttempts) {
+				continue
+			}
+
+			if class.StudentDeleted {
+				run.Skipped++
+				continue
+			}
+			student, err := reminders.Students.Get(ctx, class.StudentId)
+			if errors.Is(err, storage.ErrNotFound) || (err == nil && student.DeletedAt != nil) {
+				run.Skipped++
+				continue
+			}
+			if err != nil {
+				return run, err
+			}
+
+			text, err := reminders.text(ctx, schedule, class, student)
+			if err != nil {
+				return run, err
+			}
+			if notification == nil {
+				// The unique index on the class claims a new notification
+				notification = &models.Notification{
+					Id:         primitive.NewObjectID(),
+					Kind:       models.NotificationReminder,
+					StudentId:  student.Id,
+					ScheduleId: schedule.Id,
+					ClassId:    class.Id,
+					Status:     models.NotificationSending,
+					Attempts:   1,
+					CreatedAt:  reminders.Now().UTC(),
+				}
+				err = reminders.Notifications.Create(ctx, notification)
+			} else {
+				err = reminders.Notifications.Claim(ctx, notification)
+			}
+			// Another run already took the class
+			if errors.Is(err, storage.ErrDuplicate) || errors.Is(err, storage.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return run, err
+			}
+			// Retries use the current phone and class
+			notification.Channel, notification.To, notification.Text = reminders.Sender.Channel(), student.Phone, text
+
+			err = reminders.Sender.Send(ctx, Message{To: notification.To, Text: notification.Text})
+			if err != nil {
+				notification.Status, notification.Error = models.NotificationFailed, err.Error()
+				run.Failed++
+			} else {
+				sentAt := reminders.Now().UTC()
+				notification.Status, notification.Error, notification.SentAt = models.NotificationSent, "", &sentAt
+				run.Sent++
+			}
+			if err := reminders.Notifications.Update(ctx, notification); err != nil {
+				return run, err
+			}
+		}
+	}
+
+	return run, nil
+}
+
+// Fill the template for the class
+func (reminders *Reminders) text(ctx context.Context, schedule models.Schedule, class models.Class, student *models.Student) (string, error) {
+	data := ReminderData{
+		Student: student.Fullname,
+		Type:    class.Type,
+		Date:    schedule.Date.Time().UTC().Format("Monday, January 2"),
+		Time:    class.Time,
+	}
+	if class.TeacherId != nil {
+		teacher, err := reminders.Teachers.Get(ctx, *class.TeacherId)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return "", err
+		}
+		if err == nil {
+			data.Teacher = teacher.Fullname
+		}
+	}
+	if class.RoomId != nil {
+		room, err := reminders.Rooms.Get(ctx, *class.RoomId)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return "", err
+		}
+		if err == nil {
+			data.Room = room.Name
+		}
+	}
+
+	var builder strings.Builder
+	if err := reminders.Template.Execute(&builder, data); err != nil {
+		return "", fmt.Errorf("failed to fill the reminder template: %w", err)
+	}
+	return builder.String(), nil
+}
+
+// NewReminders creates the reminders job of the configuration; nil when notifications are off
+func NewReminders(store *storage.Store, cfg *config.Config) (*Reminders, error) {
+	sender, err := NewSender(cfg)
+	if err != nil || sender == nil {
+		return nil, err
+	}
+	parsed, err := ParseReminderTemplate(cfg.ReminderTemplate)
+	if err != nil {
+		return nil, err
+	}
+	clock, err := time.Parse("15:04", cfg.ReminderTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reminder time %q: %w", cfg.ReminderTime, err)
+	}
+
+	return &Reminders{
+		Schedules:     store.Schedules,
+		Students:      store.Students,
+		Teachers:      store.Teachers,
+		Rooms:         store.Rooms,
+		Notifications: store.Notifications,
+		Sender:        sender,
+		Template:      parsed,
+		SendAfter:     time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute,
+		Now:           time.Now,
+	}, nil
+}
@@ -0,0 +1,91 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/DanVerh/artschool-admin/backend/api/config"
+)
+
+// Message is one text for one student
+type Message struct {
+	// Address on the channel of the sender; the phone of the student for SMS
+	To   string
+	Text string
+}
+
+// Sender delivers messages over one channel, like SMS, Telegram or email.
+// A provider is added by implementing Sender and creating it in NewSender
+type Sender interface {
+	// Channel names the channel; it is recorded with every notification
+	Channel() string
+	// Send returns an error when the message was not accepted, then it is tried again on the next run
+	Send(ctx context.Context, message Message) error
+}
+
+// NewSender creates the sender of the configuration; nil when notifications are off
+func NewSender(cfg *config.Config) (Sender, error) {
+	switch cfg.NotificationSender {
+	case config.NoNotificationSender:
+		return nil, nil
+	case config.LogNotificationSender:
+		return &localSender{}, nil
+	case config.FileNotificationSender:
+		if cfg.NotificationFile == "" {
+			return nil, fmt.Errorf("file sender needs a file path")
+		}
+		return &localSender{path: cfg.NotificationFile}, nil
+	}
+
+	return nil, fmt.Errorf("unknown notification sender %q", cfg.NotificationSender)
+}
+
+// Create struct (class) for localSender; it does not deliver anything and is meant for development and tests.
+// Messages go to the log, or are appended to the file as JSON lines when the path is set
+type localSender struct {
+	path  string
+	mutex sync.Mutex
+}
+
+func (sender *localSender) Channel() string {
+	if sender.path != "" {
+		return config.FileNotificationSender
+	}
+	return config.LogNotificationSender
+}
+
+func (sender *localSender) Send(ctx context.Context, message Message) error {
+	if sender.path == "" {
+		log.Printf("Notification to %v: %v", message.To, message.Text)
+		return nil
+	}
+
+	line, err := json.Marshal(map[string]string{
+		"time": time.Now().UTC().Format(time.RFC3339),
+		"to":   message.To,
+		"text": message.Text,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Runs of the reminders and the API may write at the same time
+	sender.mutex.Lock()
+	defer sender.mutex.Unlock()
+
+	file, err := os.OpenFile(sender.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(line, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,45 @@
+package notifications
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// Text of the reminders when the configuration does not set another one
+const DefaultReminderTemplate = "Hello, {{.Student}}! Your {{.Type}} class is tomorrow, {{.Date}} at {{.Time}}" +
+	"{{if .Room}}, {{.Room}}{{end}}. See you at the art school!"
+
+// ReminderData is what a reminder template can use
+type ReminderData struct {
+	Student string
+	// Class type, like drawing
+	Type string
+	// Day of the class, like Monday, March 4
+	Date string
+	// Start time of the class, like 16:00
+	Time string
+	// Names of the teacher and the room; empty when the class has none
+	Teacher string
+	Room    string
+}
+
+// ParseReminderTemplate parses a reminder template written with text/template, the default one for an empty text.
+// The template is tried on sample data, so unknown fields are found on start and not when reminders are sent
+func ParseReminderTemplate(text string) (*template.Template, error) {
+	if strings.TrimSpace(text) == "" {
+		text = DefaultReminderTemplate
+	}
+
+	parsed, err := template.New("reminder").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reminder template: %w", err)
+	}
+	sample := ReminderData{Student: "Alice Johnson", Type: "drawing", Date: "Monday, March 4", Time: "16:00", Teacher: "Anna Brown", Room: "Room A"}
+	if err := parsed.Execute(io.Discard, sample); err != nil {
+		return nil, fmt.Errorf("invalid reminder template: %w", err)
+	}
+
+	return parsed, nil
+}
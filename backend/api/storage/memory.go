@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"encoding/json"
+)
+
+// NewMemoryStore creates repositories that keep all data in process memory.
+// They behave like the Mongo ones, including unique indexes, and are used in tests
+// and for running the API on a machine without MongoDB
+func NewMemoryStore() *Store {
+	students := newMemoryStudentRepository()
+	schedules := newMemoryScheduleRepository()
+	subscriptions := newMemorySubscriptionRepository()
+	payments := &memoryPaymentRepository{}
+
+	return &Store{
+		Students:      students,
+		Schedules:     schedules,
+		Teachers:      newMemoryTeacherRepository(),
+		Rooms:         newMemoryRoomRepository(),
+		Templates:     newMemoryTemplateRepository(),
+		Holidays:      newMemoryHolidayRepository(),
+		Cancellations: newMemoryCancellationRepository(),
+		CalendarFeeds: &memoryCalendarFeedRepository{},
+		Notifications: &memoryNotificationRepository{},
+		Users:         newMemoryUserRepository(),
+		Sessions:      newMemorySessionRepository(),
+		Subscriptions: subscriptions,
+		Payments:      payments,
+		Audit:         &memoryAuditRepository{},
+		Transactions:  &memoryTransactor{students: students, schedules: schedules, subscriptions: subscriptions, payments: payments},
+	}
+}
+
+// Deep copy through JSON, so callers never share memory with the stored documents
+func clone[T any](document T) T {
+	var copied T
+	content, err := json.Marshal(document)
+	if err != nil {
+		panic(err)
+	}
+	err = json.Unmarshal(content, &copied)
+	if err != nil {
+		panic(err)
+	}
+
+	return copied
+}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"sync"
+
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+type memoryAuditRepository struct {
+	mutex   sync.RWMutex
+	entries []models.AuditEntry
+}
+
+func (repo *memoryAuditRepository) Create(ctx context.Context, entry *models.AuditEntry) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	repo.entries = append(repo.entries, clone(*entry))
+	return nil
+}
+
+func (repo *memoryAuditRepository) List(ctx context.Context, query models.AuditQuery) ([]models.AuditEntry, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	// Entries are appended in time order, walk backwards for newest first
+	entries := []models.AuditEntry{}
+	for index := len(repo.entries) - 1; index >= 0; index-- {
+		entry := repo.entries[index]
+		if query.Entity != "" && entry.Entity != query.Entity {
+			continue
+		}
+		if !query.EntityId.IsZero() && entry.EntityId != query.EntityId {
+			continue
+		}
+		if query.Actor != "" && entry.Actor != query.Actor {
+			continue
+		}
+		if !query.From.IsZero() && entry.Timestamp.Before(query.From) {
+			continue
+		}
+		if !query.To.IsZero() && !entry.Timestamp.Before(query.To) {
+			continue
+		}
+		entries = append(entries, clone(entry))
+		if query.Limit > 0 && len(entries) == query.Limit {
+			break
+		}
+	}
+
+	return entries, nil
+}
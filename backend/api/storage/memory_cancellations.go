@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+type memoryCancellationRepository struct {
+	mutex         sync.RWMutex
+	cancellations map[primitive.ObjectID]models.Cancellation
+}
+
+func newMemoryCancellationRepository() *memoryCancellationRepository {
+	return &memoryCancellationRepository{
+		cancellations: map[primitive.ObjectID]models.Cancellation{},
+	}
+}
+
+func (repo *memoryCancellationRepository) Create(ctx context.Context, cancellation *models.Cancellation) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, found := repo.cancellations[cancellation.Id]; found {
+		return ErrDuplicate
+	}
+
+	repo.cancellations[cancellation.Id] = clone(*cancellation)
+	return nil
+}
+
+func (repo *memoryCancellationRepository) List(ctx context.Context, query models.CancellationQuery) ([]models.Cancellation, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	cancellations := []models.Cancellation{}
+	for _, cancellation := range repo.cancellations {
+		class := cancellation.Class
+		if query.StudentId != nil && class.StudentId != *query.StudentId {
+			continue
+		}
+		if query.TeacherId != nil && (class.TeacherId == nil || *class.TeacherId != *query.TeacherId) {
+			continue
+		}
+		if !query.From.IsZero() && cancellation.Date.Time().Before(query.From) {
+			continue
+		}
+		cancellations = append(cancellations, clone(cancellation))
+	}
+	slices.SortFunc(cancellations, func(a models.Cancellation, b models.Cancellation) int {
+		return a.Date.Time().Compare(b.Date.Time())
+	})
+
+	return cancellations, nil
+}
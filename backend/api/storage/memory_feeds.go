@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+type memoryCalendarFeedRepository struct {
+	mutex sync.RWMutex
+	feeds []models.CalendarFeed
+}
+
+func (repo *memoryCalendarFeedRepository) Create(ctx context.Context, feed *models.CalendarFeed) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	// Same as the unique index on tokenHash
+	for _, stored := range repo.feeds {
+		if stored.Id == feed.Id || stored.TokenHash == feed.TokenHash {
+			return ErrDuplicate
+		}
+	}
+
+	repo.feeds = append(repo.feeds, copyFeed(*feed))
+	return nil
+}
+
+func (repo *memoryCalendarFeedRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.CalendarFeed, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	for _, feed := range repo.feeds {
+		if feed.Id == id {
+			feed = copyFeed(feed)
+			return &feed, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (repo *memoryCalendarFeedRepository) GetByToken(ctx context.Context, tokenHash string) (*models.CalendarFeed, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	for _, feed := range repo.feeds {
+		if feed.TokenHash == tokenHash {
+			feed = copyFeed(feed)
+			return &feed, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (repo *memoryCalendarFeedRepository) List(ctx context.Context, createdBy *primitive.ObjectID) ([]models.CalendarFeed, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	// Feeds are appended in time order, walk backwards for newest first
+	feeds := []models.CalendarFeed{}
+	for index := len(repo.feeds) - 1; index >= 0; index-- {
+		if createdBy == nil || repo.feeds[index].CreatedBy == *createdBy {
+			feeds = append(feeds, copyFeed(repo.feeds[index]))
+		}
+	}
+
+	return feeds, nil
+}
+
+func (repo *memoryCalendarFeedRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	index := slices.IndexFunc(repo.feeds, func(feed models.CalendarFeed) bool { return feed.Id == id })
+	if index < 0 {
+		return ErrNotFound
+	}
+
+	repo.feeds = slices.Delete(repo.feeds, index, index+1)
+	return nil
+}
+
+// Copy of a feed; clone is not used because it drops the token hash, which is hidden from JSON
+func copyFeed(feed models.CalendarFeed) models.CalendarFeed {
+	if feed.EntityId != nil {
+		entityId := *feed.EntityId
+		feed.EntityId = &entityId
+	}
+	feed.Token, feed.Path = "", ""
+	return feed
+}
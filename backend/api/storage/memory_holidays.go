@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+type memoryHolidayRepository struct {
+	mutex    sync.RWMutex
+	holidays map[primitive.ObjectID]models.Holiday
+}
+
+func newMemoryHolidayRepository() *memoryHolidayRepository {
+	return &memoryHolidayRepository{
+		holidays: map[primitive.ObjectID]models.Holiday{},
+	}
+}
+
+func (repo *memoryHolidayRepository) Create(ctx context.Context, holiday *models.Holiday) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	// Same as date_unique_index in the holidays collection
+	for id, stored := range repo.holidays {
+		if id == holiday.Id || stored.Date.Equal(holiday.Date) {
+			return ErrDuplicate
+		}
+	}
+
+	repo.holidays[holiday.Id] = clone(*holiday)
+	return nil
+}
+
+func (repo *memoryHolidayRepository) List(ctx context.Context, from time.Time, to time.Time) ([]models.Holiday, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	holidays := []models.Holiday{}
+	for _, holiday := range repo.holidays {
+		if (!from.IsZero() && holiday.Date.Before(from)) || (!to.IsZero() && holiday.Date.After(to)) {
+			continue
+		}
+		holidays = append(holidays, clone(holiday))
+	}
+	slices.SortFunc(holidays, func(a models.Holiday, b models.Holiday) int {
+		return a.Date.Compare(b.Date)
+	})
+
+	return holidays, nil
+}
+
+func (repo *memoryHolidayRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, found := repo.holidays[id]; !found {
+		return ErrNotFound
+	}
+
+	delete(repo.holidays, id)
+	return nil
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+type memoryNotificationRepository struct {
+	mutex         sync.RWMutex
+	notifications []models.Notification
+}
+
+func (repo *memoryNotificationRepository) Create(ctx context.Context, notification *models.Notification) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	// Same as the unique index on classId and kind
+	for _, stored := range repo.notifications {
+		if stored.Id == notification.Id || (stored.ClassId == notification.ClassId && stored.Kind == notification.Kind) {
+			return ErrDuplicate
+		}
+	}
+
+	repo.notifications = append(repo.notifications, clone(*notification))
+	return nil
+}
+
+func (repo *memoryNotificationRepository) GetByClass(ctx context.Context, classId primitive.ObjectID, kind string) (*models.Notification, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	for _, notification := range repo.notifications {
+		if notification.ClassId == classId && notification.Kind == kind {
+			notification = clone(notification)
+			return &notification, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (repo *memoryNotificationRepository) Update(ctx context.Context, notification *models.Notification) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	for index, stored := range repo.notifications {
+		if stored.Id == notification.Id {
+			repo.notifications[index] = clone(*notification)
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+func (repo *memoryNotificationRepository) Claim(ctx context.Context, notification *models.Notification) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	for index, stored := range repo.notifications {
+		if stored.Id == notification.Id && stored.Status == notification.Status && stored.Attempts == notification.Attempts &&
+			(stored.Status == models.NotificationPending || stored.Status == models.NotificationFailed) {
+			notification.Status, notification.Attempts = models.NotificationSending, notification.Attempts+1
+			repo.notifications[index].Status, repo.notifications[index].Attempts = notification.Status, notification.Attempts
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+func (repo *memoryNotificationRepository) List(ctx context.Context, query models.NotificationQuery) ([]models.Notification, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	// Notifications are appended in time order, walk backwards for newest first
+	notifications := []models.Notification{}
+	for index := len(repo.notifications) - 1; index >= 0; index-- {
+		notification := repo.notifications[index]
+		if !query.StudentId.IsZero() && notification.StudentId != query.StudentId {
+			continue
+		}
+		if query.Status != "" && notification.Status != query.Status {
+			continue
+		}
+		notifications = append(notifications, clone(notification))
+		if query.Limit > 0 && len(notifications) == query.Limit {
+			break
+		}
+	}
+
+	return notifications, nil
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+type memoryPaymentRepository struct {
+	mutex    sync.RWMutex
+	payments []models.Payment
+}
+
+func (repo *memoryPaymentRepository) Create(ctx context.Context, payment *models.Payment) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	for _, stored := range repo.payments {
+		if stored.Id == payment.Id {
+			return ErrDuplicate
+		}
+	}
+
+	repo.payments = append(repo.payments, clone(*payment))
+	return nil
+}
+
+func (repo *memoryPaymentRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Payment, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	for _, payment := range repo.payments {
+		if payment.Id == id {
+			payment = clone(payment)
+			return &payment, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+// Transactions already run one at a time, there is nothing to lock
+func (repo *memoryPaymentRepository) Lock(ctx context.Context, id primitive.ObjectID) error {
+	return nil
+}
+
+func (repo *memoryPaymentRepository) List(ctx context.Context, query models.PaymentQuery) ([]models.Payment, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	// Entries are appended in time order, walk backwards for newest first
+	payments := []models.Payment{}
+	for index := len(repo.payments) - 1; index >= 0; index-- {
+		payment := repo.payments[index]
+		if !query.StudentId.IsZero() && (payment.StudentId == nil || *payment.StudentId != query.StudentId) {
+			continue
+		}
+		if !query.RelatedTo.IsZero() && (payment.RelatedTo == nil || *payment.RelatedTo != query.RelatedTo) {
+			continue
+		}
+		if query.Kind != "" && payment.Kind != query.Kind {
+			continue
+		}
+		if query.Method != "" && payment.Method != query.Method {
+			continue
+		}
+		if !query.From.IsZero() && payment.CreatedAt.Before(query.From) {
+			continue
+		}
+		if !query.To.IsZero() && !payment.CreatedAt.Before(query.To) {
+			continue
+		}
+		payments = append(payments, clone(payment))
+		if query.Limit > 0 && len(payments) == query.Limit {
+			break
+		}
+	}
+
+	return payments, nil
+}
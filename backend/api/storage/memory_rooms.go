@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+type memoryRoomRepository struct {
+	mutex sync.RWMutex
+	rooms map[primitive.ObjectID]models.Room
+	order []primitive.ObjectID
+}
+
+func newMemoryRoomRepository() *memoryRoomRepository {
+	return &memoryRoomRepository{
+		rooms: map[primitive.ObjectID]models.Room{},
+	}
+}
+
+// Same as name_unique_index in the rooms collection
+func (repo *memoryRoomRepository) nameTaken(name string, except primitive.ObjectID) bool {
+	for id, room := range repo.rooms {
+		if id != except && room.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (repo *memoryRoomRepository) Create(ctx context.Context, room *models.Room) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, found := repo.rooms[room.Id]; found || repo.nameTaken(room.Name, room.Id) {
+		return ErrDuplicate
+	}
+
+	repo.rooms[room.Id] = clone(*room)
+	repo.order = append(repo.order, room.Id)
+	return nil
+}
+
+func (repo *memoryRoomRepository) List(ctx context.Context) ([]models.Room, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	rooms := []models.Room{}
+	for _, id := range repo.order {
+		rooms = append(rooms, clone(repo.rooms[id]))
+	}
+
+	return rooms, nil
+}
+
+func (repo *memoryRoomRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Room, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	room, found := repo.rooms[id]
+	if !found {
+		return nil, ErrNotFound
+	}
+
+	room = clone(room)
+	return &room, nil
+}
+
+func (repo *memoryRoomRepository) Update(ctx context.Context, room *models.Room) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, found := repo.rooms[room.Id]; !found {
+		return ErrNotFound
+	}
+	if repo.nameTaken(room.Name, room.Id) {
+		return ErrDuplicate
+	}
+
+	repo.rooms[room.Id] = clone(*room)
+	return nil
+}
@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+type memoryScheduleRepository struct {
+	mutex     sync.RWMutex
+	schedules map[primitive.ObjectID]models.Schedule
+	order     []primitive.ObjectID
+}
+
+func newMemoryScheduleRepository() *memoryScheduleRepository {
+	return &memoryScheduleRepository{
+		schedules: map[primitive.ObjectID]models.Schedule{},
+	}
+}
+
+// Same as date_unique_index in the schedule collection
+func (repo *memoryScheduleRepository) dateTaken(date primitive.DateTime, except primitive.ObjectID) bool {
+	for id, schedule := range repo.schedules {
+		if id != except && schedule.Date == date {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (repo *memoryScheduleRepository) Create(ctx context.Context, schedule *models.Schedule) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, found := repo.schedules[schedule.Id]; found || repo.dateTaken(schedule.Date, schedule.Id) {
+		return ErrDuplicate
+	}
+
+	repo.schedules[schedule.Id] = clone(*schedule)
+	repo.order = append(repo.order, schedule.Id)
+	return nil
+}
+
+func (repo *memoryScheduleRepository) List(ctx context.Context, query models.ScheduleQuery) ([]models.Schedule, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	schedules := []models.Schedule{}
+	for _, id := range repo.order {
+		schedule := repo.schedules[id]
+		if query.TeacherId != nil && !slices.ContainsFunc(schedule.Classes, func(class models.Class) bool {
+			return class.TeacherId != nil && *class.TeacherId == *query.TeacherId
+		}) {
+			continue
+		}
+		if (!query.From.IsZero() && schedule.Date.Time().Before(query.From)) || (!query.To.IsZero() && !schedule.Date.Time().Before(query.To)) {
+			continue
+		}
+		schedules = append(schedules, clone(schedule))
+	}
+	slices.SortStableFunc(schedules, func(a models.Schedule, b models.Schedule) int {
+		return cmp.Compare(a.Date, b.Date)
+	})
+
+	return schedules, nil
+}
+
+// The schedules are copied first, so fn can use the store
+func (repo *memoryScheduleRepository) Each(ctx context.Context, query models.ScheduleQuery, fn func(schedule models.Schedule) error) error {
+	schedules, err := repo.List(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	for _, schedule := range schedules {
+		if err := fn(schedule); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (repo *memoryScheduleRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Schedule, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	schedule, found := repo.schedules[id]
+	if !found {
+		return nil, ErrNotFound
+	}
+
+	schedule = clone(schedule)
+	return &schedule, nil
+}
+
+func (repo *memoryScheduleRepository) Update(ctx context.Context, schedule *models.Schedule) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, found := repo.schedules[schedule.Id]; !found {
+		return ErrNotFound
+	}
+	if repo.dateTaken(schedule.Date, schedule.Id) {
+		return ErrDuplicate
+	}
+
+	repo.schedules[schedule.Id] = clone(*schedule)
+	return nil
+}
+
+func (repo *memoryScheduleRepository) AddClass(ctx context.Context, scheduleId primitive.ObjectID, class *models.Class) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	schedule, found := repo.schedules[scheduleId]
+	if !found {
+		return ErrNotFound
+	}
+
+	schedule.Classes = append(slices.Clone(schedule.Classes), clone(*class))
+	repo.schedules[scheduleId] = schedule
+	return nil
+}
+
+func (repo *memoryScheduleRepository) UpdateClass(ctx context.Context, scheduleId primitive.ObjectID, class *models.Class, fields []string) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	schedule, found := repo.schedules[scheduleId]
+	index := slices.IndexFunc(schedule.Classes, func(stored models.Class) bool { return stored.Id == class.Id })
+	if !found || index < 0 {
+		return ErrNotFound
+	}
+
+	updated := clone(schedule.Classes[index])
+	for _, field := range fields {
+		switch field {
+		case "time":
+			updated.Time = class.Time
+		case "type":
+			updated.Type = class.Type
+		case "teacherId":
+			updated.TeacherId = clone(class.TeacherId)
+		case "roomId":
+			updated.RoomId = clone(class.RoomId)
+		default:
+			return fmt.Errorf("class field %v can not be updated", field)
+		}
+	}
+	schedule.Classes = slices.Clone(schedule.Classes)
+	schedule.Classes[index] = updated
+	repo.schedules[scheduleId] = schedule
+	return nil
+}
+
+func (repo *memoryScheduleRepository) RemoveClass(ctx context.Context, scheduleId primitive.ObjectID, classId primitive.ObjectID) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	schedule, found := repo.schedules[scheduleId]
+	index := slices.IndexFunc(schedule.Classes, func(stored models.Class) bool { return stored.Id == classId })
+	if !found || index < 0 {
+		return ErrNotFound
+	}
+	if attendance := schedule.Classes[index].Attendence; attendance != nil && *attendance {
+		return ErrAttended
+	}
+
+	schedule.Classes = slices.Delete(slices.Clone(schedule.Classes), index, index+1)
+	repo.schedules[scheduleId] = schedule
+	return nil
+}
+
+func (repo *memoryScheduleRepository) ListByStudent(ctx context.Context, studentId primitive.ObjectID) ([]models.Schedule, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	schedules := []models.Schedule{}
+	for _, id := range repo.order {
+		schedule := repo.schedules[id]
+		if slices.ContainsFunc(schedule.Classes, func(class models.Class) bool { return class.StudentId == studentId }) {
+			schedules = append(schedules, clone(schedule))
+		}
+	}
+	slices.SortStableFunc(schedules, func(a models.Schedule, b models.Schedule) int {
+		return cmp.Compare(a.Date, b.Date)
+	})
+
+	return schedules, nil
+}
+
+func (repo *memoryScheduleRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, found := repo.schedules[id]; !found {
+		return ErrNotFound
+	}
+
+	delete(repo.schedules, id)
+	repo.order = removeId(repo.order, id)
+	return nil
+}
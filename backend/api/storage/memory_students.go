@@ -0,0 +1,227 @@
+package storage
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+type memoryStudentRepository struct {
+	mutex    sync.RWMutex
+	students map[primitive.ObjectID]models.Student
+	// Keeps the order of insertion, like the natural order of a collection
+	order []primitive.ObjectID
+}
+
+func newMemoryStudentRepository() *memoryStudentRepository {
+	return &memoryStudentRepository{
+		students: map[primitive.ObjectID]models.Student{},
+	}
+}
+
+// Same as phone_unique_index in the students collection
+func (repo *memoryStudentRepository) phoneTaken(phone string, except primitive.ObjectID) bool {
+	for id, student := range repo.students {
+		if id != except && student.Phone == phone {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (repo *memoryStudentRepository) Create(ctx context.Context, student *models.Student) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, found := repo.students[student.Id]; found || repo.phoneTaken(student.Phone, student.Id) {
+		return ErrDuplicate
+	}
+
+	repo.students[student.Id] = clone(*student)
+	repo.order = append(repo.order, student.Id)
+	return nil
+}
+
+func (repo *memoryStudentRepository) List(ctx context.Context, query models.StudentQuery) ([]models.Student, int64, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	students := []models.Student{}
+	for _, id := range repo.order {
+		if student := repo.students[id]; matchStudent(student, query) {
+			students = append(students, clone(student))
+		}
+	}
+
+	// Same order as in Mongo: null values first, ties by id
+	slices.SortStableFunc(students, func(a models.Student, b models.Student) int {
+		result := compareStudentField(a, b, query.Sort)
+		if result == 0 {
+			result = bytes.Compare(a.Id[:], b.Id[:])
+		}
+		if query.Descending {
+			return -result
+		}
+		return result
+	})
+
+	total := int64(len(students))
+	start := min(query.Offset, len(students))
+	end := len(students)
+	if query.Limit > 0 {
+		end = min(start+query.Limit, end)
+	}
+
+	return students[start:end], total, nil
+}
+
+// The students are copied first, so fn can use the store
+func (repo *memoryStudentRepository) Each(ctx context.Context, query models.StudentQuery, fn func(student models.Student) error) error {
+	students, _, err := repo.List(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	for _, student := range students {
+		if err := fn(student); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Check the student against the filters of the query, the same way as studentFilter
+func matchStudent(student models.Student, query models.StudentQuery) bool {
+	if !query.IncludeArchived && student.DeletedAt != nil {
+		return false
+	}
+	if query.NullSubscription && student.Subscription != nil {
+		return false
+	}
+	if !query.NullSubscription && query.Subscription != nil && (student.Subscription == nil || *student.Subscription != *query.Subscription) {
+		return false
+	}
+	if !query.LastDateBefore.IsZero() && (student.LastDate == nil || !student.LastDate.Before(query.LastDateBefore)) {
+		return false
+	}
+	if !query.LastDateAfter.IsZero() && (student.LastDate == nil || !student.LastDate.After(query.LastDateAfter)) {
+		return false
+	}
+	if query.Comments != "" && (student.Comments == nil || !strings.Contains(strings.ToLower(*student.Comments), strings.ToLower(query.Comments))) {
+		return false
+	}
+
+	return true
+}
+
+func compareStudentField(a models.Student, b models.Student, field string) int {
+	switch field {
+	case "fullname":
+		return strings.Compare(a.Fullname, b.Fullname)
+	case "subscription":
+		return compareNullable(a.Subscription, b.Subscription, cmp.Compare[int])
+	case "startDate":
+		return compareNullable(a.StartDate, b.StartDate, time.Time.Compare)
+	case "lastDate":
+		return compareNullable(a.LastDate, b.LastDate, time.Time.Compare)
+	}
+
+	return 0
+}
+
+// Compare two optional values; nil is less than any value
+func compareNullable[T any](a *T, b *T, compare func(T, T) int) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	return compare(*a, *b)
+}
+
+func (repo *memoryStudentRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Student, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	student, found := repo.students[id]
+	if !found {
+		return nil, ErrNotFound
+	}
+
+	student = clone(student)
+	return &student, nil
+}
+
+func (repo *memoryStudentRepository) Update(ctx context.Context, id primitive.ObjectID, fields map[string]interface{}) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	student, found := repo.students[id]
+	if !found {
+		return ErrNotFound
+	}
+
+	// Apply the fields on top of the stored student through its JSON representation,
+	// keys are the same as in the Mongo $set
+	document := map[string]interface{}{}
+	content, _ := json.Marshal(student)
+	json.Unmarshal(content, &document)
+	for key, value := range fields {
+		document[key] = value
+	}
+	content, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	updated := models.Student{}
+	err = json.Unmarshal(content, &updated)
+	if err != nil {
+		return err
+	}
+
+	if repo.phoneTaken(updated.Phone, id) {
+		return ErrDuplicate
+	}
+
+	repo.students[id] = updated
+	return nil
+}
+
+func (repo *memoryStudentRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, found := repo.students[id]; !found {
+		return ErrNotFound
+	}
+
+	delete(repo.students, id)
+	repo.order = removeId(repo.order, id)
+	return nil
+}
+
+// Remove id from the insertion order
+func removeId(order []primitive.ObjectID, id primitive.ObjectID) []primitive.ObjectID {
+	for index, orderId := range order {
+		if orderId == id {
+			return append(order[:index], order[index+1:]...)
+		}
+	}
+
+	return order
+}
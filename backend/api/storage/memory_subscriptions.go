@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+type memorySubscriptionRepository struct {
+	mutex         sync.RWMutex
+	subscriptions map[primitive.ObjectID]models.Subscription
+	order         []primitive.ObjectID
+}
+
+func newMemorySubscriptionRepository() *memorySubscriptionRepository {
+	return &memorySubscriptionRepository{
+		subscriptions: map[primitive.ObjectID]models.Subscription{},
+	}
+}
+
+func (repo *memorySubscriptionRepository) Create(ctx context.Context, subscription *models.Subscription) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, found := repo.subscriptions[subscription.Id]; found {
+		return ErrDuplicate
+	}
+
+	repo.subscriptions[subscription.Id] = clone(*subscription)
+	repo.order = append(repo.order, subscription.Id)
+	return nil
+}
+
+func (repo *memorySubscriptionRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Subscription, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	subscription, found := repo.subscriptions[id]
+	if !found {
+		return nil, ErrNotFound
+	}
+
+	subscription = clone(subscription)
+	return &subscription, nil
+}
+
+func (repo *memorySubscriptionRepository) ListByStudent(ctx context.Context, studentId primitive.ObjectID) ([]models.Subscription, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	subscriptions := []models.Subscription{}
+	for _, id := range repo.order {
+		if subscription := repo.subscriptions[id]; subscription.StudentId == studentId {
+			subscriptions = append(subscriptions, clone(subscription))
+		}
+	}
+	slices.SortStableFunc(subscriptions, func(a models.Subscription, b models.Subscription) int {
+		if result := a.PurchaseDate.Compare(b.PurchaseDate); result != 0 {
+			return result
+		}
+		return bytes.Compare(a.Id[:], b.Id[:])
+	})
+
+	return subscriptions, nil
+}
+
+func (repo *memorySubscriptionRepository) ListExpiring(ctx context.Context, from time.Time, to time.Time) ([]models.Subscription, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	subscriptions := []models.Subscription{}
+	for _, id := range repo.order {
+		if subscription := repo.subscriptions[id]; !subscription.ExpiresAt.Before(from) && subscription.ExpiresAt.Before(to) {
+			subscriptions = append(subscriptions, clone(subscription))
+		}
+	}
+
+	return subscriptions, nil
+}
+
+func (repo *memorySubscriptionRepository) Update(ctx context.Context, subscription *models.Subscription) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, found := repo.subscriptions[subscription.Id]; !found {
+		return ErrNotFound
+	}
+
+	repo.subscriptions[subscription.Id] = clone(*subscription)
+	return nil
+}
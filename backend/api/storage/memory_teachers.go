@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+type memoryTeacherRepository struct {
+	mutex    sync.RWMutex
+	teachers map[primitive.ObjectID]models.Teacher
+	order    []primitive.ObjectID
+}
+
+func newMemoryTeacherRepository() *memoryTeacherRepository {
+	return &memoryTeacherRepository{
+		teachers: map[primitive.ObjectID]models.Teacher{},
+	}
+}
+
+func (repo *memoryTeacherRepository) Create(ctx context.Context, teacher *models.Teacher) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, found := repo.teachers[teacher.Id]; found {
+		return ErrDuplicate
+	}
+
+	repo.teachers[teacher.Id] = clone(*teacher)
+	repo.order = append(repo.order, teacher.Id)
+	return nil
+}
+
+func (repo *memoryTeacherRepository) List(ctx context.Context, query models.TeacherQuery) ([]models.Teacher, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	teachers := []models.Teacher{}
+	for _, id := range repo.order {
+		teacher := repo.teachers[id]
+		if query.Active != nil && teacher.Active != *query.Active {
+			continue
+		}
+		if query.ClassType != "" && !slices.Contains(teacher.ClassTypes, query.ClassType) {
+			continue
+		}
+		teachers = append(teachers, clone(teacher))
+	}
+
+	return teachers, nil
+}
+
+func (repo *memoryTeacherRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Teacher, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	teacher, found := repo.teachers[id]
+	if !found {
+		return nil, ErrNotFound
+	}
+
+	teacher = clone(teacher)
+	return &teacher, nil
+}
+
+func (repo *memoryTeacherRepository) Update(ctx context.Context, teacher *models.Teacher) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, found := repo.teachers[teacher.Id]; !found {
+		return ErrNotFound
+	}
+
+	repo.teachers[teacher.Id] = clone(*teacher)
+	return nil
+}
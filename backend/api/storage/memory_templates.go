@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+type memoryTemplateRepository struct {
+	mutex     sync.RWMutex
+	templates map[primitive.ObjectID]models.Template
+	order     []primitive.ObjectID
+}
+
+func newMemoryTemplateRepository() *memoryTemplateRepository {
+	return &memoryTemplateRepository{
+		templates: map[primitive.ObjectID]models.Template{},
+	}
+}
+
+func (repo *memoryTemplateRepository) Create(ctx context.Context, template *models.Template) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, found := repo.templates[template.Id]; found {
+		return ErrDuplicate
+	}
+
+	repo.templates[template.Id] = clone(*template)
+	repo.order = append(repo.order, template.Id)
+	return nil
+}
+
+func (repo *memoryTemplateRepository) List(ctx context.Context, query models.TemplateQuery) ([]models.Template, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	templates := []models.Template{}
+	for _, id := range repo.order {
+		template := repo.templates[id]
+		if query.StudentId != nil && template.StudentId != *query.StudentId {
+			continue
+		}
+		templates = append(templates, clone(template))
+	}
+
+	return templates, nil
+}
+
+func (repo *memoryTemplateRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Template, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	template, found := repo.templates[id]
+	if !found {
+		return nil, ErrNotFound
+	}
+
+	template = clone(template)
+	return &template, nil
+}
+
+func (repo *memoryTemplateRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, found := repo.templates[id]; !found {
+		return ErrNotFound
+	}
+
+	delete(repo.templates, id)
+	repo.order = removeId(repo.order, id)
+	return nil
+}
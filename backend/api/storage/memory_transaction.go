@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+// Runs transactions one at a time and puts the data back when a transaction fails.
+// Writes made outside of transactions while one is running are lost on rollback,
+// which is fine for tests and local runs
+type memoryTransactor struct {
+	mutex         sync.Mutex
+	students      *memoryStudentRepository
+	schedules     *memoryScheduleRepository
+	subscriptions *memorySubscriptionRepository
+	payments      *memoryPaymentRepository
+}
+
+func (transactor *memoryTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	transactor.mutex.Lock()
+	defer transactor.mutex.Unlock()
+
+	students, studentsOrder := transactor.students.snapshot()
+	schedules, schedulesOrder := transactor.schedules.snapshot()
+	subscriptions, subscriptionsOrder := transactor.subscriptions.snapshot()
+	payments := transactor.payments.snapshot()
+
+	err := fn(ctx)
+	if err != nil {
+		transactor.students.restore(students, studentsOrder)
+		transactor.schedules.restore(schedules, schedulesOrder)
+		transactor.subscriptions.restore(subscriptions, subscriptionsOrder)
+		transactor.payments.restore(payments)
+	}
+
+	return err
+}
+
+// Stored documents are never changed in place, so copies of the map and the order are enough
+func (repo *memoryStudentRepository) snapshot() (map[primitive.ObjectID]models.Student, []primitive.ObjectID) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	return maps.Clone(repo.students), slices.Clone(repo.order)
+}
+
+func (repo *memoryStudentRepository) restore(students map[primitive.ObjectID]models.Student, order []primitive.ObjectID) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	repo.students, repo.order = students, order
+}
+
+func (repo *memoryScheduleRepository) snapshot() (map[primitive.ObjectID]models.Schedule, []primitive.ObjectID) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	return maps.Clone(repo.schedules), slices.Clone(repo.order)
+}
+
+func (repo *memoryScheduleRepository) restore(schedules map[primitive.ObjectID]models.Schedule, order []primitive.ObjectID) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	repo.schedules, repo.order = schedules, order
+}
+
+func (repo *memorySubscriptionRepository) snapshot() (map[primitive.ObjectID]models.Subscription, []primitive.ObjectID) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	return maps.Clone(repo.subscriptions), slices.Clone(repo.order)
+}
+
+func (repo *memorySubscriptionRepository) restore(subscriptions map[primitive.ObjectID]models.Subscription, order []primitive.ObjectID) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	repo.subscriptions, repo.order = subscriptions, order
+}
+
+func (repo *memoryPaymentRepository) snapshot() []models.Payment {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	return slices.Clone(repo.payments)
+}
+
+func (repo *memoryPaymentRepository) restore(payments []models.Payment) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	repo.payments = payments
+}
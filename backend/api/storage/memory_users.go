@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+type memoryUserRepository struct {
+	mutex sync.RWMutex
+	users map[primitive.ObjectID]models.User
+}
+
+func newMemoryUserRepository() *memoryUserRepository {
+	return &memoryUserRepository{
+		users: map[primitive.ObjectID]models.User{},
+	}
+}
+
+func (repo *memoryUserRepository) Create(ctx context.Context, user *models.User) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	// Same as username_unique_index in the users collection
+	for id, existing := range repo.users {
+		if id == user.Id || existing.Username == user.Username {
+			return ErrDuplicate
+		}
+	}
+
+	repo.users[user.Id] = *user
+	return nil
+}
+
+func (repo *memoryUserRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	user, found := repo.users[id]
+	if !found {
+		return nil, ErrNotFound
+	}
+
+	return &user, nil
+}
+
+func (repo *memoryUserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	for _, user := range repo.users {
+		if user.Username == username {
+			return &user, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (repo *memoryUserRepository) List(ctx context.Context) ([]models.User, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	users := []models.User{}
+	for _, user := range repo.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+
+	return users, nil
+}
+
+type memorySessionRepository struct {
+	mutex    sync.Mutex
+	sessions map[string]models.Session
+}
+
+func newMemorySessionRepository() *memorySessionRepository {
+	return &memorySessionRepository{
+		sessions: map[string]models.Session{},
+	}
+}
+
+func (repo *memorySessionRepository) Create(ctx context.Context, session *models.Session) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, found := repo.sessions[session.Id]; found {
+		return ErrDuplicate
+	}
+
+	repo.sessions[session.Id] = *session
+	return nil
+}
+
+func (repo *memorySessionRepository) Get(ctx context.Context, id string) (*models.Session, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	session, found := repo.sessions[id]
+	if !found {
+		return nil, ErrNotFound
+	}
+	if !session.ExpiresAt.After(time.Now()) {
+		delete(repo.sessions, id)
+		return nil, ErrNotFound
+	}
+
+	return &session, nil
+}
+
+func (repo *memorySessionRepository) Delete(ctx context.Context, id string) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, found := repo.sessions[id]; !found {
+		return ErrNotFound
+	}
+
+	delete(repo.sessions, id)
+	return nil
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/DanVerh/artschool-admin/backend/api/db"
+)
+
+// NewMongoStore creates repositories backed by the MongoDB collections
+// created by backend/migration
+func NewMongoStore(database *db.Database) *Store {
+	return &Store{
+		Students:      &mongoStudentRepository{db: database, collection: database.Collection("students")},
+		Schedules:     &mongoScheduleRepository{db: database, collection: database.Collection("schedule")},
+		Teachers:      &mongoTeacherRepository{db: database, collection: database.Collection("teachers")},
+		Rooms:         &mongoRoomRepository{db: database, collection: database.Collection("rooms")},
+		Templates:     &mongoTemplateRepository{db: database, collection: database.Collection("templates")},
+		Holidays:      &mongoHolidayRepository{db: database, collection: database.Collection("holidays")},
+		Cancellations: &mongoCancellationRepository{db: database, collection: database.Collection("cancellations")},
+		CalendarFeeds: &mongoCalendarFeedRepository{db: database, collection: database.Collection("calendarFeeds")},
+		Notifications: &mongoNotificationRepository{db: database, collection: database.Collection("notifications")},
+		Users:         &mongoUserRepository{db: database, collection: database.Collection("users")},
+		Sessions:      &mongoSessionRepository{db: database, collection: database.Collection("sessions")},
+		Subscriptions: &mongoSubscriptionRepository{db: database, collection: database.Collection("subscriptions")},
+		Payments:      &mongoPaymentRepository{db: database, collection: database.Collection("payments"), locks: database.Collection("paymentLocks")},
+		Audit:         newMongoAuditRepository(database),
+		Transactions:  &mongoTransactor{db: database},
+	}
+}
+
+// Convert driver errors to the repository errors; other errors are wrapped
+// so db.IsUnavailable still recognises them
+func mongoError(err error, action string) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+
+	return fmt.Errorf("failed to %v: %w", action, err)
+}
+
+// Decode all documents of the cursor; never returns nil slice so empty lists are encoded as []
+func decodeAll[T any](ctx context.Context, cursor *mongo.Cursor) ([]T, error) {
+	defer cursor.Close(ctx)
+
+	documents := []T{}
+	for cursor.Next(ctx) {
+		var document T
+		if err := cursor.Decode(&document); err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+	}
+
+	return documents, cursor.Err()
+}
+
+// Decode the documents of the cursor one at a time and call fn for each; errors of fn are returned as they are.
+// Every fetch of the cursor gets its own query timeout, so long reads like exports are not cut off
+func eachDocument[T any](ctx context.Context, database *db.Database, cursor *mongo.Cursor, action string, fn func(document T) error) error {
+	defer cursor.Close(ctx)
+
+	for {
+		nextCtx, cancel := database.QueryContext(ctx)
+		found := cursor.Next(nextCtx)
+		cancel()
+		if !found {
+			break
+		}
+
+		var document T
+		if err := cursor.Decode(&document); err != nil {
+			return mongoError(err, action)
+		}
+		if err := fn(document); err != nil {
+			return err
+		}
+	}
+
+	return mongoError(cursor.Err(), action)
+}
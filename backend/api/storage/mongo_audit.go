@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/DanVerh/artschool-admin/backend/api/db"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+type mongoAuditRepository struct {
+	db         *db.Database
+	collection *mongo.Collection
+}
+
+func newMongoAuditRepository(database *db.Database) *mongoAuditRepository {
+	// Changed values are free-form; decode nested documents as maps so they are encoded back
+	// to JSON as objects and not as lists of key-value pairs
+	registry := bson.NewRegistry()
+	registry.RegisterTypeMapEntry(bsontype.EmbeddedDocument, reflect.TypeOf(bson.M{}))
+
+	return &mongoAuditRepository{
+		db:         database,
+		collection: database.Collection("audit", options.Collection().SetRegistry(registry)),
+	}
+}
+
+func (repo *mongoAuditRepository) Create(ctx context.Context, entry *models.AuditEntry) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	_, err := repo.collection.InsertOne(ctx, entry)
+	return mongoError(err, "insert audit entry")
+}
+
+func (repo *mongoAuditRepository) List(ctx context.Context, query models.AuditQuery) ([]models.AuditEntry, error) {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	filter := bson.M{}
+	if query.Entity != "" {
+		filter["entity"] = query.Entity
+	}
+	if !query.EntityId.IsZero() {
+		filter["entityId"] = query.EntityId
+	}
+	if query.Actor != "" {
+		filter["actor"] = query.Actor
+	}
+	timestamp := bson.M{}
+	if !query.From.IsZero() {
+		timestamp["$gte"] = query.From
+	}
+	if !query.To.IsZero() {
+		timestamp["$lt"] = query.To
+	}
+	if len(timestamp) > 0 {
+		filter["timestamp"] = timestamp
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}})
+	if query.Limit > 0 {
+		findOptions.SetLimit(int64(query.Limit))
+	}
+
+	cursor, err := repo.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, mongoError(err, "find audit entries")
+	}
+
+	entries, err := decodeAll[models.AuditEntry](ctx, cursor)
+	return entries, mongoError(err, "decode audit entries")
+}
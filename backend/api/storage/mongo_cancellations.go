@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/DanVerh/artschool-admin/backend/api/db"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+type mongoCancellationRepository struct {
+	db         *db.Database
+	collection *mongo.Collection
+}
+
+func (repo *mongoCancellationRepository) Create(ctx context.Context, cancellation *models.Cancellation) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	_, err := repo.collection.InsertOne(ctx, cancellation)
+	return mongoError(err, "insert cancellation")
+}
+
+func (repo *mongoCancellationRepository) List(ctx context.Context, query models.CancellationQuery) ([]models.Cancellation, error) {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	filter := bson.M{}
+	if query.StudentId != nil {
+		filter["class.studentId"] = *query.StudentId
+	}
+	if query.TeacherId != nil {
+		filter["class.teacherId"] = *query.TeacherId
+	}
+	if !query.From.IsZero() {
+		filter["date"] = bson.M{"$gte": query.From}
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "date", Value: 1}})
+	cursor, err := repo.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, mongoError(err, "find cancellations")
+	}
+
+	cancellations, err := decodeAll[models.Cancellation](ctx, cursor)
+	return cancellations, mongoError(err, "decode cancellations")
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/DanVerh/artschool-admin/backend/api/db"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+type mongoCalendarFeedRepository struct {
+	db         *db.Database
+	collection *mongo.Collection
+}
+
+func (repo *mongoCalendarFeedRepository) Create(ctx context.Context, feed *models.CalendarFeed) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	_, err := repo.collection.InsertOne(ctx, feed)
+	return mongoError(err, "insert calendar feed")
+}
+
+func (repo *mongoCalendarFeedRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.CalendarFeed, error) {
+	return repo.findOne(ctx, bson.M{"_id": id})
+}
+
+func (repo *mongoCalendarFeedRepository) GetByToken(ctx context.Context, tokenHash string) (*models.CalendarFeed, error) {
+	return repo.findOne(ctx, bson.M{"tokenHash": tokenHash})
+}
+
+func (repo *mongoCalendarFeedRepository) findOne(ctx context.Context, filter bson.M) (*models.CalendarFeed, error) {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	feed := &models.CalendarFeed{}
+	err := repo.collection.FindOne(ctx, filter).Decode(feed)
+	if err != nil {
+		return nil, mongoError(err, "find calendar feed")
+	}
+
+	return feed, nil
+}
+
+func (repo *mongoCalendarFeedRepository) List(ctx context.Context, createdBy *primitive.ObjectID) ([]models.CalendarFeed, error) {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	filter := bson.M{}
+	if createdBy != nil {
+		filter["createdBy"] = *createdBy
+	}
+
+	cursor, err := repo.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}))
+	if err != nil {
+		return nil, mongoError(err, "find calendar feeds")
+	}
+
+	feeds, err := decodeAll[models.CalendarFeed](ctx, cursor)
+	return feeds, mongoError(err, "decode calendar feeds")
+}
+
+func (repo *mongoCalendarFeedRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	deleteResult, err := repo.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return mongoError(err, "delete calendar feed")
+	}
+	if deleteResult.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
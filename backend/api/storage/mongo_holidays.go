@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/DanVerh/artschool-admin/backend/api/db"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+type mongoHolidayRepository struct {
+	db         *db.Database
+	collection *mongo.Collection
+}
+
+func (repo *mongoHolidayRepository) Create(ctx context.Context, holiday *models.Holiday) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	_, err := repo.collection.InsertOne(ctx, holiday)
+	return mongoError(err, "insert holiday")
+}
+
+func (repo *mongoHolidayRepository) List(ctx context.Context, from time.Time, to time.Time) ([]models.Holiday, error) {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	dateFilter := bson.M{}
+	if !from.IsZero() {
+		dateFilter["$gte"] = from
+	}
+	if !to.IsZero() {
+		dateFilter["$lte"] = to
+	}
+	filter := bson.M{}
+	if len(dateFilter) > 0 {
+		filter["date"] = dateFilter
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "date", Value: 1}})
+	cursor, err := repo.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, mongoError(err, "find holidays")
+	}
+
+	holidays, err := decodeAll[models.Holiday](ctx, cursor)
+	return holidays, mongoError(err, "decode holidays")
+}
+
+func (repo *mongoHolidayRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	deleteResult, err := repo.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return mongoError(err, "delete holiday")
+	}
+	if deleteResult.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/DanVerh/artschool-admin/backend/api/db"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+type mongoNotificationRepository struct {
+	db         *db.Database
+	collection *mongo.Collection
+}
+
+func (repo *mongoNotificationRepository) Create(ctx context.Context, notification *models.Notification) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	_, err := repo.collection.InsertOne(ctx, notification)
+	return mongoError(err, "insert notification")
+}
+
+func (repo *mongoNotificationRepository) GetByClass(ctx context.Context, classId primitive.ObjectID, kind string) (*models.Notification, error) {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	notification := &models.Notification{}
+	err := repo.collection.FindOne(ctx, bson.M{"classId": classId, "kind": kind}).Decode(notification)
+	if err != nil {
+		return nil, mongoError(err, "find notification")
+	}
+
+	return notification, nil
+}
+
+func (repo *mongoNotificationRepository) Update(ctx context.Context, notification *models.Notification) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	updateResult, err := repo.collection.ReplaceOne(ctx, bson.M{"_id": notification.Id}, notification)
+	if err != nil {
+		return mongoError(err, "update notification")
+	}
+	if updateResult.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (repo *mongoNotificationRepository) Claim(ctx context.Context, notification *models.Notification) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	if notification.Status != models.NotificationPending && notification.Status != models.NotificationFailed {
+		return ErrNotFound
+	}
+	filter := bson.M{"_id": notification.Id, "status": notification.Status, "attempts": notification.Attempts}
+	updateResult, err := repo.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"status": models.NotificationSending}, "$inc": bson.M{"attempts": 1}})
+	if err != nil {
+		return mongoError(err, "claim notification")
+	}
+	if updateResult.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	notification.Status, notification.Attempts = models.NotificationSending, notification.Attempts+1
+	return nil
+}
+
+func (repo *mongoNotificationRepository) List(ctx context.Context, query models.NotificationQuery) ([]models.Notification, error) {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	filter := bson.M{}
+	if !query.StudentId.IsZero() {
+		filter["studentId"] = query.StudentId
+	}
+	if query.Status != "" {
+		filter["status"] = query.Status
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}})
+	if query.Limit > 0 {
+		findOptions.SetLimit(int64(query.Limit))
+	}
+
+	cursor, err := repo.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, mongoError(err, "find notifications")
+	}
+
+	notifications, err := decodeAll[models.Notification](ctx, cursor)
+	return notifications, mongoError(err, "decode notifications")
+}
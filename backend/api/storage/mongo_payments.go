@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/DanVerh/artschool-admin/backend/api/db"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+type mongoPaymentRepository struct {
+	db         *db.Database
+	collection *mongo.Collection
+	// One document per adjusted payment, so the ledger entries themselves are never written twice
+	locks *mongo.Collection
+}
+
+func (repo *mongoPaymentRepository) Create(ctx context.Context, payment *models.Payment) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	_, err := repo.collection.InsertOne(ctx, payment)
+	return mongoError(err, "insert payment")
+}
+
+func (repo *mongoPaymentRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Payment, error) {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	payment := &models.Payment{}
+	err := repo.collection.FindOne(ctx, bson.M{"_id": id}).Decode(payment)
+	if err != nil {
+		return nil, mongoError(err, "find payment")
+	}
+
+	return payment, nil
+}
+
+func (repo *mongoPaymentRepository) Lock(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	_, err := repo.locks.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"version": 1}}, options.Update().SetUpsert(true))
+	return mongoError(err, "lock payment")
+}
+
+func (repo *mongoPaymentRepository) List(ctx context.Context, query models.PaymentQuery) ([]models.Payment, error) {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	filter := bson.M{}
+	if !query.StudentId.IsZero() {
+		filter["studentId"] = query.StudentId
+	}
+	if !query.RelatedTo.IsZero() {
+		filter["relatedTo"] = query.RelatedTo
+	}
+	if query.Kind != "" {
+		filter["kind"] = query.Kind
+	}
+	if query.Method != "" {
+		filter["method"] = query.Method
+	}
+	createdAt := bson.M{}
+	if !query.From.IsZero() {
+		createdAt["$gte"] = query.From
+	}
+	if !query.To.IsZero() {
+		createdAt["$lt"] = query.To
+	}
+	if len(createdAt) > 0 {
+		filter["createdAt"] = createdAt
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}})
+	if query.Limit > 0 {
+		findOptions.SetLimit(int64(query.Limit))
+	}
+
+	cursor, err := repo.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, mongoError(err, "find payments")
+	}
+
+	payments, err := decodeAll[models.Payment](ctx, cursor)
+	return payments, mongoError(err, "decode payments")
+}
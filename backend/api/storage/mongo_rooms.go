@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/DanVerh/artschool-admin/backend/api/db"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+type mongoRoomRepository struct {
+	db         *db.Database
+	collection *mongo.Collection
+}
+
+func (repo *mongoRoomRepository) Create(ctx context.Context, room *models.Room) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	_, err := repo.collection.InsertOne(ctx, room)
+	return mongoError(err, "insert room")
+}
+
+func (repo *mongoRoomRepository) List(ctx context.Context) ([]models.Room, error) {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	cursor, err := repo.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, mongoError(err, "find rooms")
+	}
+
+	rooms, err := decodeAll[models.Room](ctx, cursor)
+	return rooms, mongoError(err, "decode rooms")
+}
+
+func (repo *mongoRoomRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Room, error) {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	room := &models.Room{}
+	err := repo.collection.FindOne(ctx, bson.M{"_id": id}).Decode(room)
+	if err != nil {
+		return nil, mongoError(err, "find room")
+	}
+
+	return room, nil
+}
+
+func (repo *mongoRoomRepository) Update(ctx context.Context, room *models.Room) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	updateResult, err := repo.collection.ReplaceOne(ctx, bson.M{"_id": room.Id}, room)
+	if err != nil {
+		return mongoError(err, "update room")
+	}
+	if updateResult.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/DanVerh/artschool-admin/backend/api/db"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+type mongoScheduleRepository struct {
+	db         *db.Database
+	collection *mongo.Collection
+}
+
+func (repo *mongoScheduleRepository) Create(ctx context.Context, schedule *models.Schedule) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	_, err := repo.collection.InsertOne(ctx, schedule)
+	return mongoError(err, "insert schedule")
+}
+
+func (repo *mongoScheduleRepository) List(ctx context.Context, query models.ScheduleQuery) ([]models.Schedule, error) {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	// Uses date_unique_index for the range and the order
+	findOptions := options.Find().SetSort(bson.D{{Key: "date", Value: 1}})
+	cursor, err := repo.collection.Find(ctx, scheduleFilter(query), findOptions)
+	if err != nil {
+		return nil, mongoError(err, "find schedules")
+	}
+
+	schedules, err := decodeAll[models.Schedule](ctx, cursor)
+	return schedules, mongoError(err, "decode schedules")
+}
+
+func (repo *mongoScheduleRepository) Each(ctx context.Context, query models.ScheduleQuery, fn func(schedule models.Schedule) error) error {
+	findCtx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "date", Value: 1}})
+	cursor, err := repo.collection.Find(findCtx, scheduleFilter(query), findOptions)
+	if err != nil {
+		return mongoError(err, "find schedules")
+	}
+
+	return eachDocument(ctx, repo.db, cursor, "decode schedules", fn)
+}
+
+// Build the Mongo filter of the schedules query
+func scheduleFilter(query models.ScheduleQuery) bson.M {
+	filter := bson.M{}
+	if query.TeacherId != nil {
+		filter["classes.teacherId"] = *query.TeacherId
+	}
+	dateFilter := bson.M{}
+	if !query.From.IsZero() {
+		dateFilter["$gte"] = primitive.NewDateTimeFromTime(query.From)
+	}
+	if !query.To.IsZero() {
+		dateFilter["$lt"] = primitive.NewDateTimeFromTime(query.To)
+	}
+	if len(dateFilter) > 0 {
+		filter["date"] = dateFilter
+	}
+
+	return filter
+}
+
+func (repo *mongoScheduleRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Schedule, error) {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	schedule := &models.Schedule{}
+	err := repo.collection.FindOne(ctx, bson.M{"_id": id}).Decode(schedule)
+	if err != nil {
+		return nil, mongoError(err, "find schedule")
+	}
+
+	return schedule, nil
+}
+
+func (repo *mongoScheduleRepository) Update(ctx context.Context, schedule *models.Schedule) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	updateResult, err := repo.collection.ReplaceOne(ctx, bson.M{"_id": schedule.Id}, schedule)
+	if err != nil {
+		return mongoError(err, "update schedule")
+	}
+	if updateResult.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// The class methods change one element of the classes array with array operators,
+// so concurrent changes of other classes of the day are not overwritten
+
+func (repo *mongoScheduleRepository) AddClass(ctx context.Context, scheduleId primitive.ObjectID, class *models.Class) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	updateResult, err := repo.collection.UpdateOne(ctx, bson.M{"_id": scheduleId}, bson.M{"$push": bson.M{"classes": class}})
+	if err != nil {
+		return mongoError(err, "add class")
+	}
+	if updateResult.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (repo *mongoScheduleRepository) UpdateClass(ctx context.Context, scheduleId primitive.ObjectID, class *models.Class, fields []string) error {
+	values := bson.M{"time": class.Time, "type": class.Type, "teacherId": class.TeacherId, "roomId": class.RoomId}
+	set := bson.M{}
+	for _, field := range fields {
+		value, found := values[field]
+		if !found {
+			return fmt.Errorf("class field %v can not be updated", field)
+		}
+		set["classes.$."+field] = value
+	}
+
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	// Only the changed fields are set, so attendance marked meanwhile stays
+	filter := bson.M{"_id": scheduleId, "classes._id": class.Id}
+	updateResult, err := repo.collection.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return mongoError(err, "update class")
+	}
+	if updateResult.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (repo *mongoScheduleRepository) RemoveClass(ctx context.Context, scheduleId primitive.ObjectID, classId primitive.ObjectID) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	// The attendance is checked in the same update, so a visit marked meanwhile is not removed with its pack used
+	filter := bson.M{"_id": scheduleId, "classes._id": classId}
+	updateResult, err := repo.collection.UpdateOne(ctx, filter, bson.M{"$pull": bson.M{"classes": bson.M{"_id": classId, "attendance": bson.M{"$ne": true}}}})
+	if err != nil {
+		return mongoError(err, "remove class")
+	}
+	if updateResult.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	if updateResult.ModifiedCount == 0 {
+		return ErrAttended
+	}
+
+	return nil
+}
+
+func (repo *mongoScheduleRepository) ListByStudent(ctx context.Context, studentId primitive.ObjectID) ([]models.Schedule, error) {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "date", Value: 1}})
+	cursor, err := repo.collection.Find(ctx, bson.M{"classes.studentId": studentId}, findOptions)
+	if err != nil {
+		return nil, mongoError(err, "find schedules of student")
+	}
+
+	schedules, err := decodeAll[models.Schedule](ctx, cursor)
+	return schedules, mongoError(err, "decode schedules of student")
+}
+
+func (repo *mongoScheduleRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	deleteResult, err := repo.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return mongoError(err, "delete schedule")
+	}
+	if deleteResult.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
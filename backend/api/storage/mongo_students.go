@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"context"
+	"regexp"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/DanVerh/artschool-admin/backend/api/db"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+type mongoStudentRepository struct {
+	db         *db.Database
+	collection *mongo.Collection
+}
+
+func (repo *mongoStudentRepository) Create(ctx context.Context, student *models.Student) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	_, err := repo.collection.InsertOne(ctx, student)
+	return mongoError(err, "insert student")
+}
+
+func (repo *mongoStudentRepository) List(ctx context.Context, query models.StudentQuery) ([]models.Student, int64, error) {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	filter := studentFilter(query)
+	total, err := repo.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, mongoError(err, "count students")
+	}
+
+	cursor, err := repo.collection.Find(ctx, filter, studentFindOptions(query))
+	if err != nil {
+		return nil, 0, mongoError(err, "find students")
+	}
+
+	students, err := decodeAll[models.Student](ctx, cursor)
+	return students, total, mongoError(err, "decode students")
+}
+
+func (repo *mongoStudentRepository) Each(ctx context.Context, query models.StudentQuery, fn func(student models.Student) error) error {
+	findCtx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	cursor, err := repo.collection.Find(findCtx, studentFilter(query), studentFindOptions(query))
+	if err != nil {
+		return mongoError(err, "find students")
+	}
+
+	return eachDocument(ctx, repo.db, cursor, "decode students", fn)
+}
+
+// Build the sorting and the page of the students query
+func studentFindOptions(query models.StudentQuery) *options.FindOptions {
+	// Ties and unsorted lists are ordered by id, so pages do not overlap
+	order := 1
+	if query.Descending {
+		order = -1
+	}
+	sort := bson.D{}
+	if query.Sort != "" {
+		sort = append(sort, bson.E{Key: query.Sort, Value: order})
+	}
+	sort = append(sort, bson.E{Key: "_id", Value: order})
+
+	findOptions := options.Find().SetSort(sort).SetSkip(int64(query.Offset))
+	if query.Limit > 0 {
+		findOptions.SetLimit(int64(query.Limit))
+	}
+
+	return findOptions
+}
+
+// Build the Mongo filter of the students query
+func studentFilter(query models.StudentQuery) bson.M {
+	filter := bson.M{}
+	if !query.IncludeArchived {
+		// Matches both null and missing fields
+		filter["deletedAt"] = nil
+	}
+	if query.NullSubscription {
+		// Matches both null and missing fields
+		filter["subscription"] = nil
+	} else if query.Subscription != nil {
+		filter["subscription"] = *query.Subscription
+	}
+
+	lastDate := bson.M{}
+	if !query.LastDateBefore.IsZero() {
+		lastDate["$lt"] = query.LastDateBefore
+	}
+	if !query.LastDateAfter.IsZero() {
+		lastDate["$gt"] = query.LastDateAfter
+	}
+	if len(lastDate) > 0 {
+		filter["lastDate"] = lastDate
+	}
+
+	if query.Comments != "" {
+		filter["comments"] = primitive.Regex{Pattern: regexp.QuoteMeta(query.Comments), Options: "i"}
+	}
+
+	return filter
+}
+
+func (repo *mongoStudentRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Student, error) {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	student := &models.Student{}
+	err := repo.collection.FindOne(ctx, bson.M{"_id": id}).Decode(student)
+	if err != nil {
+		return nil, mongoError(err, "find student")
+	}
+
+	return student, nil
+}
+
+func (repo *mongoStudentRepository) Update(ctx context.Context, id primitive.ObjectID, fields map[string]interface{}) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	updateResult, err := repo.collection.UpdateByID(ctx, id, bson.M{"$set": fields})
+	if err != nil {
+		return mongoError(err, "update student")
+	}
+	if updateResult.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (repo *mongoStudentRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	deleteResult, err := repo.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return mongoError(err, "delete student")
+	}
+	if deleteResult.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
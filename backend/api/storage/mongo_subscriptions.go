@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/DanVerh/artschool-admin/backend/api/db"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+type mongoSubscriptionRepository struct {
+	db         *db.Database
+	collection *mongo.Collection
+}
+
+func (repo *mongoSubscriptionRepository) Create(ctx context.Context, subscription *models.Subscription) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	_, err := repo.collection.InsertOne(ctx, subscription)
+	return mongoError(err, "insert subscription")
+}
+
+func (repo *mongoSubscriptionRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Subscription, error) {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	subscription := &models.Subscription{}
+	err := repo.collection.FindOne(ctx, bson.M{"_id": id}).Decode(subscription)
+	if err != nil {
+		return nil, mongoError(err, "find subscription")
+	}
+
+	return subscription, nil
+}
+
+func (repo *mongoSubscriptionRepository) ListByStudent(ctx context.Context, studentId primitive.ObjectID) ([]models.Subscription, error) {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "purchaseDate", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := repo.collection.Find(ctx, bson.M{"studentId": studentId}, findOptions)
+	if err != nil {
+		return nil, mongoError(err, "find subscriptions")
+	}
+
+	subscriptions, err := decodeAll[models.Subscription](ctx, cursor)
+	return subscriptions, mongoError(err, "decode subscriptions")
+}
+
+func (repo *mongoSubscriptionRepository) ListExpiring(ctx context.Context, from time.Time, to time.Time) ([]models.Subscription, error) {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	cursor, err := repo.collection.Find(ctx, bson.M{"expiresAt": bson.M{"$gte": from, "$lt": to}})
+	if err != nil {
+		return nil, mongoError(err, "find subscriptions")
+	}
+
+	subscriptions, err := decodeAll[models.Subscription](ctx, cursor)
+	return subscriptions, mongoError(err, "decode subscriptions")
+}
+
+func (repo *mongoSubscriptionRepository) Update(ctx context.Context, subscription *models.Subscription) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	updateResult, err := repo.collection.ReplaceOne(ctx, bson.M{"_id": subscription.Id}, subscription)
+	if err != nil {
+		return mongoError(err, "update subscription")
+	}
+	if updateResult.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
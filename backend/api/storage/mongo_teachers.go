@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/DanVerh/artschool-admin/backend/api/db"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+type mongoTeacherRepository struct {
+	db         *db.Database
+	collection *mongo.Collection
+}
+
+func (repo *mongoTeacherRepository) Create(ctx context.Context, teacher *models.Teacher) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	_, err := repo.collection.InsertOne(ctx, teacher)
+	return mongoError(err, "insert teacher")
+}
+
+func (repo *mongoTeacherRepository) List(ctx context.Context, query models.TeacherQuery) ([]models.Teacher, error) {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	filter := bson.M{}
+	if query.Active != nil {
+		filter["active"] = *query.Active
+	}
+	if query.ClassType != "" {
+		filter["classTypes"] = query.ClassType
+	}
+
+	cursor, err := repo.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, mongoError(err, "find teachers")
+	}
+
+	teachers, err := decodeAll[models.Teacher](ctx, cursor)
+	return teachers, mongoError(err, "decode teachers")
+}
+
+func (repo *mongoTeacherRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Teacher, error) {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	teacher := &models.Teacher{}
+	err := repo.collection.FindOne(ctx, bson.M{"_id": id}).Decode(teacher)
+	if err != nil {
+		return nil, mongoError(err, "find teacher")
+	}
+
+	return teacher, nil
+}
+
+func (repo *mongoTeacherRepository) Update(ctx context.Context, teacher *models.Teacher) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	updateResult, err := repo.collection.ReplaceOne(ctx, bson.M{"_id": teacher.Id}, teacher)
+	if err != nil {
+		return mongoError(err, "update teacher")
+	}
+	if updateResult.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
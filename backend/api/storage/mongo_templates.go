@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/DanVerh/artschool-admin/backend/api/db"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+type mongoTemplateRepository struct {
+	db         *db.Database
+	collection *mongo.Collection
+}
+
+func (repo *mongoTemplateRepository) Create(ctx context.Context, template *models.Template) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	_, err := repo.collection.InsertOne(ctx, template)
+	return mongoError(err, "insert template")
+}
+
+func (repo *mongoTemplateRepository) List(ctx context.Context, query models.TemplateQuery) ([]models.Template, error) {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	filter := bson.M{}
+	if query.StudentId != nil {
+		filter["studentId"] = *query.StudentId
+	}
+
+	cursor, err := repo.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, mongoError(err, "find templates")
+	}
+
+	templates, err := decodeAll[models.Template](ctx, cursor)
+	return templates, mongoError(err, "decode templates")
+}
+
+func (repo *mongoTemplateRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Template, error) {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	template := &models.Template{}
+	err := repo.collection.FindOne(ctx, bson.M{"_id": id}).Decode(template)
+	if err != nil {
+		return nil, mongoError(err, "find template")
+	}
+
+	return template, nil
+}
+
+func (repo *mongoTemplateRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	deleteResult, err := repo.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return mongoError(err, "delete template")
+	}
+	if deleteResult.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
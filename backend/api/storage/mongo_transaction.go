@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/DanVerh/artschool-admin/backend/api/db"
+)
+
+// Transactions need MongoDB running as a replica set; a single node replica set is enough
+type mongoTransactor struct {
+	db *db.Database
+}
+
+func (transactor *mongoTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := transactor.db.Client.StartSession()
+	if err != nil {
+		return mongoError(err, "start session")
+	}
+	defer session.EndSession(ctx)
+
+	// The session context carries the transaction to every repository call made with it,
+	// WithTransaction retries fn on transient errors such as write conflicts
+	_, err = session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessionCtx)
+	})
+
+	return err
+}
@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/DanVerh/artschool-admin/backend/api/db"
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+type mongoUserRepository struct {
+	db         *db.Database
+	collection *mongo.Collection
+}
+
+func (repo *mongoUserRepository) Create(ctx context.Context, user *models.User) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	_, err := repo.collection.InsertOne(ctx, user)
+	return mongoError(err, "insert user")
+}
+
+func (repo *mongoUserRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	return repo.findOne(ctx, bson.M{"_id": id})
+}
+
+func (repo *mongoUserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	return repo.findOne(ctx, bson.M{"username": username})
+}
+
+func (repo *mongoUserRepository) List(ctx context.Context) ([]models.User, error) {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	cursor, err := repo.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"username": 1}))
+	if err != nil {
+		return nil, mongoError(err, "find users")
+	}
+
+	users, err := decodeAll[models.User](ctx, cursor)
+	return users, mongoError(err, "decode users")
+}
+
+func (repo *mongoUserRepository) findOne(ctx context.Context, filter bson.M) (*models.User, error) {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	user := &models.User{}
+	err := repo.collection.FindOne(ctx, filter).Decode(user)
+	if err != nil {
+		return nil, mongoError(err, "find user")
+	}
+
+	return user, nil
+}
+
+type mongoSessionRepository struct {
+	db         *db.Database
+	collection *mongo.Collection
+}
+
+func (repo *mongoSessionRepository) Create(ctx context.Context, session *models.Session) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	_, err := repo.collection.InsertOne(ctx, session)
+	return mongoError(err, "insert session")
+}
+
+func (repo *mongoSessionRepository) Get(ctx context.Context, id string) (*models.Session, error) {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	// The TTL index removes expired sessions only once a minute, so check expiry here too
+	session := &models.Session{}
+	err := repo.collection.FindOne(ctx, bson.M{"_id": id, "expiresAt": bson.M{"$gt": time.Now()}}).Decode(session)
+	if err != nil {
+		return nil, mongoError(err, "find session")
+	}
+
+	return session, nil
+}
+
+func (repo *mongoSessionRepository) Delete(ctx context.Context, id string) error {
+	ctx, cancel := repo.db.QueryContext(ctx)
+	defer cancel()
+
+	deleteResult, err := repo.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return mongoError(err, "delete session")
+	}
+	if deleteResult.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/DanVerh/artschool-admin/backend/api/models"
+)
+
+// Errors returned by every repository implementation,
+// so handlers do not depend on the driver errors
+var (
+	ErrNotFound  = errors.New("document not found")
+	ErrDuplicate = errors.New("document violates a unique index")
+	ErrAttended  = errors.New("class was attended")
+)
+
+// StudentRepository stores students
+type StudentRepository interface {
+	Create(ctx context.Context, student *models.Student) error
+	// List returns one page of the students matching the query and the number of all matching students
+	List(ctx context.Context, query models.StudentQuery) ([]models.Student, int64, error)
+	// Each calls fn for every student matching the query in the order of List, one at a time, and stops at the first error
+	Each(ctx context.Context, query models.StudentQuery, fn func(student models.Student) error) error
+	Get(ctx context.Context, id primitive.ObjectID) (*models.Student, error)
+	// Update sets only the given fields; keys are the JSON/BSON field names
+	Update(ctx context.Context, id primitive.ObjectID, fields map[string]interface{}) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// ScheduleRepository stores schedules of days
+type ScheduleRepository interface {
+	Create(ctx context.Context, schedule *models.Schedule) error
+	// List returns the schedules with at least one class matching the query, with all their classes, ordered by date
+	List(ctx context.Context, query models.ScheduleQuery) ([]models.Schedule, error)
+	// Each calls fn for every schedule of List, one at a time, and stops at the first error
+	Each(ctx context.Context, query models.ScheduleQuery, fn func(schedule models.Schedule) error) error
+	Get(ctx context.Context, id primitive.ObjectID) (*models.Schedule, error)
+	// Update replaces the whole schedule document
+	Update(ctx context.Context, schedule *models.Schedule) error
+	// AddClass appends the class to the classes of the schedule
+	AddClass(ctx context.Context, scheduleId primitive.ObjectID, class *models.Class) error
+	// UpdateClass sets the fields (time, type, teacherId, roomId) of the class with the same id to their values in class.
+	// Other fields, like attendance, are left as saved; ErrNotFound when the schedule has no such class
+	UpdateClass(ctx context.Context, scheduleId primitive.ObjectID, class *models.Class, fields []string) error
+	// RemoveClass removes the class unless it is marked present; ErrNotFound when the schedule has no such class,
+	// ErrAttended when the class is marked present
+	RemoveClass(ctx context.Context, scheduleId primitive.ObjectID, classId primitive.ObjectID) error
+	// ListByStudent returns the schedules with a class of the student, ordered by date
+	ListByStudent(ctx context.Context, studentId primitive.ObjectID) ([]models.Schedule, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// TeacherRepository stores teachers; they are deactivated instead of deleted, so classes keep their teacher
+type TeacherRepository interface {
+	Create(ctx context.Context, teacher *models.Teacher) error
+	List(ctx context.Context, query models.TeacherQuery) ([]models.Teacher, error)
+	Get(ctx context.Context, id primitive.ObjectID) (*models.Teacher, error)
+	// Update replaces the stored teacher
+	Update(ctx context.Context, teacher *models.Teacher) error
+}
+
+// RoomRepository stores rooms of the school
+type RoomRepository interface {
+	Create(ctx context.Context, room *models.Room) error
+	List(ctx context.Context) ([]models.Room, error)
+	Get(ctx context.Context, id primitive.ObjectID) (*models.Room, error)
+	// Update replaces the stored room
+	Update(ctx context.Context, room *models.Room) error
+}
+
+// TemplateRepository stores weekly class templates
+type TemplateRepository interface {
+	Create(ctx context.Context, template *models.Template) error
+	List(ctx context.Context, query models.TemplateQuery) ([]models.Template, error)
+	Get(ctx context.Context, id primitive.ObjectID) (*models.Template, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// HolidayRepository stores days the school is closed; one holiday per date
+type HolidayRepository interface {
+	Create(ctx context.Context, holiday *models.Holiday) error
+	// List returns the holidays from from to to, both included, ordered by date; zero times are not limits
+	List(ctx context.Context, from time.Time, to time.Time) ([]models.Holiday, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// UserRepository stores staff accounts
+type UserRepository interface {
+	Create(ctx context.Context, user *models.User) error
+	Get(ctx context.Context, id primitive.ObjectID) (*models.User, error)
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	List(ctx context.Context) ([]models.User, error)
+}
+
+// SessionRepository stores sessions of logged in users
+type SessionRepository interface {
+	Create(ctx context.Context, session *models.Session) error
+	// Get returns ErrNotFound for expired sessions too
+	Get(ctx context.Context, id string) (*models.Session, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// SubscriptionRepository stores subscription packs of students
+type SubscriptionRepository interface {
+	Create(ctx context.Context, subscription *models.Subscription) error
+	Get(ctx context.Context, id primitive.ObjectID) (*models.Subscription, error)
+	// ListByStudent returns the packs of the student, ordered by purchase date
+	ListByStudent(ctx context.Context, studentId primitive.ObjectID) ([]models.Subscription, error)
+	// ListExpiring returns the packs expiring from from, included, to to, excluded
+	ListExpiring(ctx context.Context, from time.Time, to time.Time) ([]models.Subscription, error)
+	// Update replaces the stored pack
+	Update(ctx context.Context, subscription *models.Subscription) error
+}
+
+// PaymentRepository stores the payments ledger; entries are never changed or removed
+type PaymentRepository interface {
+	Create(ctx context.Context, payment *models.Payment) error
+	Get(ctx context.Context, id primitive.ObjectID) (*models.Payment, error)
+	// List returns the newest entries first
+	List(ctx context.Context, query models.PaymentQuery) ([]models.Payment, error)
+	// Lock makes transactions adjusting the same payment conflict, so only one of them commits
+	// and the others retry with its entry in sight; the payment itself is not changed
+	Lock(ctx context.Context, id primitive.ObjectID) error
+}
+
+// CancellationRepository stores classes removed from the schedule; the id of a cancellation is the id of the class
+type CancellationRepository interface {
+	// Create saves the cancellation; ErrDuplicate when the class is already cancelled
+	Create(ctx context.Context, cancellation *models.Cancellation) error
+	// List returns the cancellations matching the query, ordered by date
+	List(ctx context.Context, query models.CancellationQuery) ([]models.Cancellation, error)
+}
+
+// CalendarFeedRepository stores the secret links of calendar feeds
+type CalendarFeedRepository interface {
+	// Create saves the feed; ErrDuplicate when the token hash is already used
+	Create(ctx context.Context, feed *models.CalendarFeed) error
+	Get(ctx context.Context, id primitive.ObjectID) (*models.CalendarFeed, error)
+	// GetByToken returns the feed with the hash of its token
+	GetByToken(ctx context.Context, tokenHash string) (*models.CalendarFeed, error)
+	// List returns the newest feeds first; only the feeds of the user when createdBy is set
+	List(ctx context.Context, createdBy *primitive.ObjectID) ([]models.CalendarFeed, error)
+	// Delete removes the feed, so its link stops working
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// NotificationRepository stores messages sent to students with their delivery status
+type NotificationRepository interface {
+	// Create saves the notification; ErrDuplicate when the class already has a notification of the kind
+	Create(ctx context.Context, notification *models.Notification) error
+	// GetByClass returns the notification of the kind for the class
+	GetByClass(ctx context.Context, classId primitive.ObjectID, kind string) (*models.Notification, error)
+	// Update replaces the stored notification
+	Update(ctx context.Context, notification *models.Notification) error
+	// Claim moves a pending or failed notification to sending and counts the attempt, but only while
+	// it still has the status and attempts it was read with; ErrNotFound when another run took it first
+	Claim(ctx context.Context, notification *models.Notification) error
+	// List returns the newest notifications first
+	List(ctx context.Context, query models.NotificationQuery) ([]models.Notification, error)
+}
+
+// AuditRepository stores the audit log; it is append-only, entries are never changed or removed
+type AuditRepository interface {
+	Create(ctx context.Context, entry *models.AuditEntry) error
+	// List returns the newest entries first
+	List(ctx context.Context, query models.AuditQuery) ([]models.AuditEntry, error)
+}
+
+// Transactor runs several repository calls as one operation
+type Transactor interface {
+	// WithTransaction calls fn with a context that makes the repository calls of fn one transaction.
+	// Nothing is saved when fn returns an error; fn may be called again when the transaction conflicts with another one
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Store groups all repositories of one backend
+type Store struct {
+	Students      StudentRepository
+	Schedules     ScheduleRepository
+	Teachers      TeacherRepository
+	Rooms         RoomRepository
+	Templates     TemplateRepository
+	Holidays      HolidayRepository
+	Cancellations CancellationRepository
+	CalendarFeeds CalendarFeedRepository
+	Notifications NotificationRepository
+	Users         UserRepository
+	Sessions      SessionRepository
+	Subscriptions SubscriptionRepository
+	Payments      PaymentRepository
+	Audit         AuditRepository
+	// Transactions wraps calls to several repositories
+	Transactions Transactor
+}